@@ -1,9 +1,16 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"nodemgr/internal/adapter/execute"
+	"nodemgr/internal/adapter/lifecycle"
+	"nodemgr/internal/adapter/metrics"
 	"nodemgr/internal/adapter/provision"
 	"nodemgr/internal/core/domain"
 	"nodemgr/internal/core/port"
@@ -12,11 +19,48 @@ import (
 )
 
 func main() {
+	logger, err := util.NewLogger(os.Stderr, os.Getenv("NODEMGR_LOG_FORMAT"), os.Getenv("NODEMGR_LOG_LEVEL"))
+	if err != nil {
+		slog.Error("failed to configure logging", "err", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	ctx := context.Background()
+	shutdownTracing, err := util.SetupTracing(ctx)
+	if err != nil {
+		fatal("failed to configure tracing", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := runAudit(ctx, os.Args[2:]); err != nil {
+			fatal("failed to query audit events", err)
+		}
+		return
+	}
+
+	// setting up the demo is done by nodemgr itself
+	adminCtx := util.WithIdentity(ctx, domain.SystemIdentity)
+
+	auditSink, closeAuditSink, err := openAuditSink(ctx)
+	if err != nil {
+		fatal("failed to open audit log", err)
+	}
+	auditService := service.NewAuditService(auditSink)
+
+	tokenRepo := util.NewRepository[domain.APITokenID, domain.APIToken]()
+	authService := service.NewAuthService(tokenRepo, strings.FieldsFunc(os.Getenv("NODEMGR_TRUSTED_ISSUERS"), func(r rune) bool { return r == ',' }))
+
 	templateRepo := util.NewRepository[domain.TemplateID, domain.NodeTemplate]()
 	templateRepo.Create(domain.NodeTemplate{
 		TemplateID: "ubuntu-worker-small",
 		Image:      "ubuntu",
 		User:       "ubuntu",
+		Lease: domain.LeasePolicy{
+			TTL:         8 * time.Hour,
+			IdleTimeout: 30 * time.Minute,
+			OnExpire:    domain.ExpireActionStop,
+		},
 		ProviderOverrides: map[domain.ProviderID]map[string]any{
 			"libvirt": {
 				"memory_mb": 512,
@@ -26,12 +70,6 @@ func main() {
 		},
 	})
 
-	templateService := service.NewTemplateService(templateRepo)
-	spec, err := templateService.RenderTemplate("ubuntu-worker-small", "docker")
-	if err != nil {
-		log.Fatalf("failed to render template: %v", err)
-	}
-
 	mappingRepo := util.NewRepository[domain.MappingID, domain.NodeSpecMapping]()
 	mappingRepo.Create(domain.NodeSpecMapping{
 		MappingID: "ubuntu",
@@ -47,6 +85,10 @@ func main() {
 				"image":      "ubuntu:24.04",
 				"image_type": "docker",
 			},
+			"docker-native": {
+				"image":      "ubuntu:24.04",
+				"image_type": "docker",
+			},
 			"libvirt": {
 				"image":      "ubuntu-24.04.3-live-server-amd64.iso",
 				"image_type": "iso",
@@ -54,49 +96,172 @@ func main() {
 		},
 	})
 
+	providerRepo := util.NewRepository[domain.ProviderID, port.NodeProvider]()
+	dockerImages := provision.NewDockerImages("unix:///var/run/docker.sock", "", nil)
+	providerRepo.Create(provision.NewDockerProvider("unix:///var/run/docker.sock", dockerImages))
+	providerRepo.Create(provision.NewDockerNativeProvider("unix:///var/run/docker.sock", dockerImages))
+	providerRepo.Create(provision.NewLocalProvider(""))
+	providerRepo.Create(provision.NewLibvirtProvider("qemu:///system", os.Getenv("NODEMGR_LIBVIRT_KEY_DIR")))
+
+	profileRepo := util.NewRepository[domain.ProviderID, domain.ProviderProfile]()
+	profileRepo.Create(domain.ProviderProfile{
+		ProviderID:  "docker",
+		Caps:        []domain.Cap{"exec:docker", "lifecycle:docker"},
+		Arch:        []string{"amd64"},
+		Local:       true,
+		StartupTime: 5 * time.Second,
+		Capacity:    domain.Resources{Nodes: 8, CPUs: 8, MemoryMB: 16384},
+	})
+	profileRepo.Create(domain.ProviderProfile{
+		ProviderID:  "libvirt",
+		Caps:        []domain.Cap{"exec:ssh"},
+		Arch:        []string{"amd64"},
+		Local:       true,
+		StartupTime: time.Minute,
+	})
+
+	templateService := service.NewTemplateService(templateRepo)
 	mappingService := service.NewMappingService(mappingRepo)
-	spec, err = mappingService.ResolveSpecAliases(spec)
-	if err != nil {
-		log.Fatalf("failed to resolve spec aliases: %v", err)
+	operationRepo := util.NewRepository[domain.OperationID, domain.Operation]()
+	operationService := service.NewOperationService(operationRepo)
+	nodeRepo := util.NewRepository[domain.NodeID, domain.Node]()
+	quotaRepo := util.NewRepository[domain.TenantID, domain.Quota]()
+	quotaService := service.NewQuotaService(nodeRepo, quotaRepo, profileRepo)
+	quotaService.SetQuota(adminCtx, domain.Quota{TenantID: "demo", Limits: domain.Resources{Nodes: 4}})
+	retryRepo := util.NewRepository[domain.ProviderID, domain.RetryPolicies]()
+	retryRepo.Create(domain.RetryPolicies{
+		ProviderID: "libvirt",
+		Provision:  domain.RetryPolicy{MaxAttempts: 2, InitialBackoff: 10 * time.Second},
+		ExecOpen:   domain.RetryPolicy{MaxAttempts: 10, InitialBackoff: 2 * time.Second, MaxBackoff: 15 * time.Second, Multiplier: 1.5, Jitter: 0.2},
+	})
+	poolRepo := util.NewRepository[domain.PoolID, domain.NodePool]()
+	queueRepo := util.NewRepository[domain.QueueRequestID, domain.QueueRequest]()
+	metricsRecorder := metrics.NewPrometheusMetrics(nodeRepo, poolRepo, queueRepo)
+	basicProvisionService := service.NewProvisionService(nodeRepo, providerRepo, templateService, mappingService, operationService, quotaService, retryRepo, metricsRecorder, auditService)
+
+	execHandleRepo := util.NewRepository[domain.ExecHandleID, port.ExecHandle]()
+	execProviderRepo := util.NewRepository[domain.ExecProviderID, port.NodeExecProvider]()
+	execProviderRepo.Create(execute.NewDockerExecProvider(execHandleRepo))
+	execProviderRepo.Create(execute.NewLocalExecProvider(execHandleRepo))
+	execProviderRepo.Create(execute.NewSSHExecProvider(execHandleRepo, ""))
+	executeService := service.NewExecuteService(nodeRepo, execProviderRepo, retryRepo, metricsRecorder, auditService, os.Getenv("NODEMGR_STAGING_DIR"))
+
+	provisionService := service.NewPoolService(basicProvisionService, templateService, poolRepo, nodeRepo, operationService, executeService, quotaService, auditService)
+
+	lifecycleRepo := util.NewRepository[domain.LifecycleProviderID, port.NodeLifecycle]()
+	lifecycleRepo.Create(lifecycle.NewDockerLifecycle())
+	lifecycleService := service.NewLifecycleService(nodeRepo, lifecycleRepo, provisionService, operationService, auditService)
+
+	leaseService := service.NewLeaseService(nodeRepo, lifecycleService, provisionService)
+
+	schedulerService := service.NewSchedulerService(templateService, mappingService, providerRepo, profileRepo, nodeRepo, provisionService)
+
+	jobRepo := util.NewRepository[domain.JobID, domain.Job]()
+	orchestratorService := service.NewOrchestratorService(jobRepo, provisionService, executeService, lifecycleService, operationService)
+
+	queueService := service.NewQueueService(queueRepo, nodeRepo, provisionService, quotaService)
+
+	if err := provisionService.PrefetchTemplateImages(adminCtx); err != nil {
+		slog.Warn("failed to prefetch template images", "err", err)
 	}
 
-	log.Printf("Rendered Spec: %+v", spec)
+	go operationService.Run(ctx)
+	go provisionService.Run(ctx)
+	go leaseService.Run(ctx)
+	go queueService.Run(ctx)
 
-	var provider port.NodeProvider = provision.NewDockerProvider("unix:///var/run/docker.sock")
+	metricsAddr := os.Getenv("NODEMGR_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9464"
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsRecorder.Handler())
+	go func() {
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			slog.Error("serving metrics", "addr", metricsAddr, "err", err)
+		}
+	}()
 
-	node, err := provider.Provision(spec)
-	if err != nil {
-		log.Fatalf("failed to provision node: %v", err)
+	if _, err := provisionService.CreatePool(adminCtx, domain.NodePool{
+		TemplateID: "ubuntu-worker-small",
+		ProviderID: "docker",
+		Size:       1,
+		MaxIdle:    30 * time.Minute,
+	}); err != nil {
+		fatal("failed to create pool", err)
 	}
 
-	log.Println("Provisioned Node:")
-	log.Printf("  ID: %s\n", node.ID())
-	log.Printf("  ProviderID (container ID): %s\n", node.ProviderID)
-	log.Printf("  State: %s\n", node.State)
-	log.Printf("  Meta: %+v\n", node.Meta)
+	// the demo job runs as a user of the demo tenant, authenticated like an
+	// API client would be
+	actor := os.Getenv("NODEMGR_ACTOR")
+	if actor == "" {
+		actor = os.Getenv("USER")
+	}
+	token, _, err := authService.CreateToken(adminCtx, domain.Identity{Subject: actor, TenantID: "demo", Role: domain.RoleUser}, time.Hour)
+	if err != nil {
+		fatal("failed to create token", err)
+	}
+	identity, err := authService.AuthenticateToken(ctx, token)
+	if err != nil {
+		fatal("failed to authenticate", err)
+	}
+	ctx = util.WithIdentity(ctx, *identity)
 
-	execHandleRepo := util.NewRepository[domain.ExecHandleID, port.ExecHandle]()
-	var executor port.NodeExecProvider = execute.NewDockerExecProvider(execHandleRepo)
+	decision, err := schedulerService.Schedule(ctx, domain.Requirement{
+		TenantID: "demo",
+		Caps:     []domain.Cap{"exec:docker", "lifecycle:docker"},
+		Policy:   domain.SchedulingPolicyFastestStart,
+	})
+	if err != nil {
+		// the decision is only returned when no candidate was viable
+		if decision != nil {
+			for _, c := range decision.Candidates {
+				slog.Info("candidate rejected", "template_id", c.TemplateID, "provider_id", c.ProviderID, "rejections", c.Rejections)
+			}
+		}
+		fatal("failed to schedule node", err)
+	}
+	slog.Info("scheduled", "template_id", decision.TemplateID, "provider_id", decision.ProviderID)
 
-	execHandle, err := executor.OpenExecHandle(node)
+	job, err := orchestratorService.SubmitJob(ctx, domain.Job{
+		TemplateID: decision.TemplateID,
+		ProviderID: decision.ProviderID,
+		TenantID:   "demo",
+		Commands: []domain.JobCommand{
+			{Command: []string{"sh", "-c", "time uname -a"}},
+		},
+		Teardown: domain.TeardownPolicyDestroy,
+	})
 	if err != nil {
-		log.Fatalf("failed to open exec handle: %v", err)
+		fatal("failed to submit job", err)
 	}
 
-	execReq := domain.ExecRequest{
-		Command: []string{"sh", "-c", "time uname -a"},
+	if _, err := operationService.WaitOperation(ctx, job.OperationID); err != nil {
+		fatal("failed to wait for job", err)
 	}
-	execResp, err := execHandle.Exec(execReq)
+
+	job, err = orchestratorService.GetJob(ctx, job.ID())
 	if err != nil {
-		log.Fatalf("failed to execute command: %v", err)
+		fatal("failed to load job", err)
 	}
 
-	log.Printf("Command executed with exit code %d", execResp.ExitCode)
-	log.Printf("STDOUT:\n%s\n", execResp.Stdout)
-	log.Printf("STDERR:\n%s\n", execResp.Stderr)
+	slog.Info("job finished", "job_id", job.ID(), "state", job.State, "node_id", job.NodeID)
+	for _, step := range job.Steps {
+		slog.Info("job step", "job_id", job.ID(), "step", step.Name, "state", step.State, "err", step.Error,
+			"exit_code", step.ExitCode, "stdout", string(step.Stdout), "stderr", string(step.Stderr))
+	}
 
-	if err := provider.Destroy(node.ID()); err != nil {
-		log.Fatalf("failed to destroy node: %v", err)
+	closeAuditSink()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("failed to flush traces", "err", err)
 	}
-	log.Println("Node destroyed successfully")
+	if job.State != domain.JobStateSucceeded {
+		slog.Error("job failed", "job_id", job.ID(), "err", job.Error)
+		os.Exit(1)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"os"
+	"sync"
+)
+
+// FileAuditSink appends events as JSON lines to a file which is only ever
+// opened for appending.
+type FileAuditSink struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &FileAuditSink{path: path, f: f}, nil
+}
+
+func (s *FileAuditSink) Append(ctx context.Context, event domain.AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.f.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	return nil
+}
+
+func (s *FileAuditSink) Query(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var events []*domain.AuditEvent
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event domain.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode audit event on line %d: %w", line, err)
+		}
+		if !filter.Match(event) {
+			continue
+		}
+
+		events = append(events, &event)
+		// only the latest events are returned, so older ones need not be kept
+		if filter.Limit > 0 && len(events) > filter.Limit {
+			events = events[len(events)-filter.Limit:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return events, nil
+}
+
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.f.Close()
+}
+
+var _ port.AuditSink = (*FileAuditSink)(nil)
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+)
+
+func newTestFileAuditSink(t *testing.T) (*FileAuditSink, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("opening sink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink, path
+}
+
+func eventIDs(events []*domain.AuditEvent) []domain.AuditEventID {
+	ids := make([]domain.AuditEventID, len(events))
+	for i, event := range events {
+		ids[i] = event.EventID
+	}
+	return ids
+}
+
+func TestFileAuditSinkQuery(t *testing.T) {
+	sink, _ := newTestFileAuditSink(t)
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []domain.AuditEvent{
+		{EventID: "e1", Time: start, Actor: "alice", Action: "node.provision", TenantID: "a", NodeID: "n1", Outcome: domain.AuditOutcomeSucceeded},
+		{EventID: "e2", Time: start.Add(time.Minute), Actor: "bob", Action: "node.exec", TenantID: "b", NodeID: "n2", Outcome: domain.AuditOutcomeFailed},
+		{EventID: "e3", Time: start.Add(2 * time.Minute), Actor: "alice", Action: "node.exec", TenantID: "a", NodeID: "n1", Outcome: domain.AuditOutcomeSucceeded},
+		{EventID: "e4", Time: start.Add(3 * time.Minute), Actor: "alice", Action: "node.destroy", TenantID: "a", NodeID: "n1", Outcome: domain.AuditOutcomeSucceeded},
+	}
+	for _, event := range events {
+		if err := sink.Append(ctx, event); err != nil {
+			t.Fatalf("appending %s: %v", event.EventID, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter domain.AuditFilter
+		want   []domain.AuditEventID
+	}{
+		{"all", domain.AuditFilter{}, []domain.AuditEventID{"e1", "e2", "e3", "e4"}},
+		{"actor", domain.AuditFilter{Actor: "alice"}, []domain.AuditEventID{"e1", "e3", "e4"}},
+		{"action", domain.AuditFilter{Action: "node.exec"}, []domain.AuditEventID{"e2", "e3"}},
+		{"tenant", domain.AuditFilter{TenantID: "b"}, []domain.AuditEventID{"e2"}},
+		{"outcome", domain.AuditFilter{Outcome: domain.AuditOutcomeFailed}, []domain.AuditEventID{"e2"}},
+		{"time range", domain.AuditFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, []domain.AuditEventID{"e2", "e3"}},
+		{"limit keeps the latest", domain.AuditFilter{NodeID: "n1", Limit: 2}, []domain.AuditEventID{"e3", "e4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sink.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			if ids := eventIDs(got); !slices.Equal(ids, tt.want) {
+				t.Errorf("events = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileAuditSinkAppendsToExistingLog(t *testing.T) {
+	sink, path := newTestFileAuditSink(t)
+	ctx := context.Background()
+
+	if err := sink.Append(ctx, domain.AuditEvent{EventID: "e1", Action: "node.provision"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	sink.Close()
+
+	reopened, err := NewFileAuditSink(path)
+	if err != nil {
+		t.Fatalf("reopening sink: %v", err)
+	}
+	defer reopened.Close()
+	if err := reopened.Append(ctx, domain.AuditEvent{EventID: "e2", Action: "node.destroy"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	got, err := reopened.Query(ctx, domain.AuditFilter{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if ids := eventIDs(got); !slices.Equal(ids, []domain.AuditEventID{"e1", "e2"}) {
+		t.Errorf("events = %v, want [e1 e2]", ids)
+	}
+}
+
+func TestFileAuditSinkQueryMissingLog(t *testing.T) {
+	sink, path := newTestFileAuditSink(t)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sink.Query(context.Background(), domain.AuditFilter{})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("events = %v, want none", eventIDs(got))
+	}
+}
+
+func TestFileAuditSinkQueryCorruptLine(t *testing.T) {
+	sink, path := newTestFileAuditSink(t)
+	ctx := context.Background()
+
+	if err := sink.Append(ctx, domain.AuditEvent{EventID: "e1"}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{not json\n")
+	f.Close()
+
+	_, err = sink.Query(ctx, domain.AuditFilter{})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("query error = %v, want a decode error on line 2", err)
+	}
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const auditSubjectPrefix = "nodemgr.audit."
+
+// auditPublishGrace bounds the time between stamping and storing an event.
+const auditPublishGrace = time.Minute
+
+// JetStreamAuditSink publishes events to `nodemgr.audit.<action>` subjects of
+// a stream which denies deleting and purging messages.
+type JetStreamAuditSink struct {
+	js     jetstream.JetStream
+	stream string
+}
+
+func NewJetStreamAuditSink(ctx context.Context, nc *nats.Conn, stream string) (*JetStreamAuditSink, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        stream,
+		Description: "nodemgr audit log",
+		Subjects:    []string{auditSubjectPrefix + ">"},
+		Storage:     jetstream.FileStorage,
+		DenyDelete:  true,
+		DenyPurge:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit stream %q: %w", stream, err)
+	}
+
+	return &JetStreamAuditSink{js: js, stream: stream}, nil
+}
+
+func (s *JetStreamAuditSink) Append(ctx context.Context, event domain.AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	msg := nats.NewMsg(auditSubjectPrefix + string(event.Action))
+	msg.Data = data
+	// the event ID deduplicates events published again after a lost ack
+	msg.Header.Set(jetstream.MsgIDHeader, string(event.EventID))
+	util.InjectTraceHeaders(ctx, msg.Header)
+
+	if _, err := s.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish audit event: %w", err)
+	}
+	return nil
+}
+
+// auditFetchBatch is the number of events fetched from the stream at once.
+const auditFetchBatch = 256
+
+// Query reads the stream from filter.Since on and stops at filter.Until, the
+// action is filtered on the server and the other fields while reading.
+func (s *JetStreamAuditSink) Query(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	subject := auditSubjectPrefix + ">"
+	if filter.Action != "" {
+		subject = auditSubjectPrefix + string(filter.Action)
+	}
+
+	stream, err := s.js.Stream(ctx, s.stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit stream %q: %w", s.stream, err)
+	}
+
+	config := jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{subject},
+		DeliverPolicy:  jetstream.DeliverAllPolicy,
+	}
+	// events are stored after they happened, so nothing stored before Since
+	// can match
+	if !filter.Since.IsZero() {
+		config.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		config.OptStartTime = &filter.Since
+	}
+	consumer, err := stream.OrderedConsumer(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit consumer: %w", err)
+	}
+
+	var events []*domain.AuditEvent
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// unlike Messages, FetchNoWait returns once the stored events are read
+		batch, err := consumer.FetchNoWait(auditFetchBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit events: %w", err)
+		}
+
+		var read int
+		for msg := range batch.Messages() {
+			read++
+
+			var event domain.AuditEvent
+			if err := json.Unmarshal(msg.Data(), &event); err != nil {
+				return nil, fmt.Errorf("failed to decode audit event: %w", err)
+			}
+			if !filter.Until.IsZero() && storedAfter(msg, filter.Until) {
+				return events, nil
+			}
+			if !filter.Match(event) {
+				continue
+			}
+
+			events = append(events, &event)
+			// only the latest events are returned, so older ones need not be kept
+			if filter.Limit > 0 && len(events) > filter.Limit {
+				events = events[len(events)-filter.Limit:]
+			}
+		}
+		if err := batch.Error(); err != nil {
+			return nil, fmt.Errorf("failed to read audit events: %w", err)
+		}
+		if read < auditFetchBatch {
+			return events, nil
+		}
+	}
+}
+
+// storedAfter reports whether msg was stored so long after t that no later
+// event can have happened before t. Events are stamped before they are
+// published, the grace covers the time publishing takes.
+func storedAfter(msg jetstream.Msg, t time.Time) bool {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return false
+	}
+	return meta.Timestamp.After(t.Add(auditPublishGrace))
+}
+
+var _ port.AuditSink = (*JetStreamAuditSink)(nil)
@@ -11,6 +11,7 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/google/uuid"
 )
@@ -31,47 +32,66 @@ func (p *DockerExecProvider) ID() domain.ExecProviderID {
 
 func (p *DockerExecProvider) OpenExecHandle(node *domain.Node) (port.ExecHandle, error) {
 	if !node.HasCap("exec:docker") {
-		return nil, fmt.Errorf("node does not have exec:docker capability")
+		return nil, &domain.CapabilityMissingError{NodeID: node.NodeID, Cap: "exec:docker"}
 	}
 
-	containerID := node.Meta["container_id"].(string)
-	dockerHost := node.Meta["docker_host"].(string)
+	containerID, ok := node.Meta["container_id"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing container_id")
+	}
+	dockerHost, ok := node.Meta["docker_host"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing docker_host")
+	}
 
 	client, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(), client.WithHost(dockerHost))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
-	execHandle := NewDockerExecHandle(client, containerID)
-	p.execHandleRepository.Create(execHandle)
-
-	return execHandle, nil
+	execHandle, err := NewDockerExecHandle(client, containerID)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return register(p.execHandleRepository, execHandle), nil
 }
 
 var _ port.NodeExecProvider = (*DockerExecProvider)(nil)
 
+// DockerExecHandle runs execs in a container, closing it aborts requests
+// still in flight.
 type DockerExecHandle struct {
 	id          string
 	cli         *client.Client
 	containerID string
 	user        string
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewDockerExecHandle(cli *client.Client, containerID string) *DockerExecHandle {
+func NewDockerExecHandle(cli *client.Client, containerID string) (*DockerExecHandle, error) {
 	ctx := context.Background()
 
 	inspectResp, err := cli.ContainerInspect(ctx, containerID)
 	if err != nil {
-		return nil
+		if client.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err) {
+			err = &domain.ProviderUnavailableError{Err: err}
+		}
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 	user := inspectResp.Config.User
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &DockerExecHandle{
 		id:          uuid.New().String(),
 		cli:         cli,
 		containerID: containerID,
 		user:        user,
-	}
+		ctx:         ctx,
+		cancel:      cancel,
+	}, nil
 }
 
 func (d DockerExecHandle) ID() domain.ExecHandleID {
@@ -79,6 +99,9 @@ func (d DockerExecHandle) ID() domain.ExecHandleID {
 }
 
 func (d *DockerExecHandle) Close() error {
+	if d.cancel != nil {
+		d.cancel()
+	}
 	if d.cli != nil {
 		return d.cli.Close()
 	}
@@ -90,7 +113,7 @@ func (d *DockerExecHandle) Attach(req domain.AttachRequest) (*domain.AttachResul
 }
 
 func (d *DockerExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
-	ctx := context.Background()
+	ctx := d.ctx
 	var env []string
 	for k, v := range req.Env {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
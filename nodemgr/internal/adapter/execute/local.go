@@ -0,0 +1,314 @@
+package execute
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+
+	"github.com/creack/pty"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/google/uuid"
+)
+
+type LocalExecProvider struct {
+	execHandleRepository port.ExecHandleRepository
+}
+
+func NewLocalExecProvider(execHandleRepository port.ExecHandleRepository) *LocalExecProvider {
+	return &LocalExecProvider{
+		execHandleRepository: execHandleRepository,
+	}
+}
+
+func (p *LocalExecProvider) ID() domain.ExecProviderID {
+	return domain.ExecProviderID("local")
+}
+
+func (p *LocalExecProvider) OpenExecHandle(node *domain.Node) (port.ExecHandle, error) {
+	if !node.HasCap(domain.LocalExecCap) {
+		return nil, &domain.CapabilityMissingError{NodeID: node.NodeID, Cap: domain.LocalExecCap}
+	}
+
+	workdir, ok := node.Meta["workdir"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing workdir")
+	}
+
+	return register(p.execHandleRepository, NewLocalExecHandle(workdir)), nil
+}
+
+var _ port.NodeExecProvider = (*LocalExecProvider)(nil)
+
+// LocalExecHandle runs processes on the host. Every path it receives is
+// resolved inside the node workdir, which is also the default working directory.
+// Closing the handle kills the processes it started.
+type LocalExecHandle struct {
+	id      string
+	workdir string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewLocalExecHandle(workdir string) *LocalExecHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LocalExecHandle{
+		id:      uuid.New().String(),
+		workdir: workdir,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (l LocalExecHandle) ID() domain.ExecHandleID {
+	return domain.ExecHandleID(l.id)
+}
+
+func (l *LocalExecHandle) Close() error {
+	l.cancel()
+	return nil
+}
+
+func (l *LocalExecHandle) Attach(req domain.AttachRequest) (*domain.AttachResult, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	cmd := exec.CommandContext(l.ctx, shell)
+	cmd.Dir = l.workdir
+	cmd.Env = os.Environ()
+
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start shell with pty: %w", err)
+	}
+
+	exited := make(chan struct{})
+	if req.Stdin != nil {
+		go copyInput(ptmx, req.Stdin, exited)
+	}
+
+	// the pty has to be drained even without a reader, a full pty buffer
+	// blocks the shell
+	stdout := req.Stdout
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	var output sync.WaitGroup
+	output.Add(1)
+	go func() {
+		defer output.Done()
+		// reading the pty master fails with EIO once the shell exits
+		io.Copy(stdout, ptmx)
+	}()
+
+	return l.watch(cmd, func() {
+		close(exited)
+		output.Wait()
+		ptmx.Close()
+	}), nil
+}
+
+// copyInput copies src to the input of a process until it exits. A read of src
+// still pending then is interrupted when src supports read deadlines,
+// otherwise its data is dropped once it returns.
+func copyInput(dst io.Writer, src io.Reader, exited <-chan struct{}) {
+	if d, ok := src.(interface{ SetReadDeadline(t time.Time) error }); ok {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-exited:
+				_ = d.SetReadDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		select {
+		case <-exited:
+			return
+		default:
+		}
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (l *LocalExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+	cmd, err := l.command(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+
+	var execResult domain.ExecResult
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	execResult.ExitCode = cmd.ProcessState.ExitCode()
+	execResult.Stdout = outBuf.Bytes()
+	execResult.Stderr = errBuf.Bytes()
+
+	return &execResult, nil
+}
+
+func (l *LocalExecHandle) ExecStream(req domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
+	cmd, err := l.command(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdout = attach.Stdout
+	cmd.Stderr = attach.Stderr
+
+	// with a plain reader as stdin Wait would also wait for it to reach EOF,
+	// which an interactive stream never does, so it is copied by hand
+	var stdin io.WriteCloser
+	if attach.Stdin != nil {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return nil, fmt.Errorf("failed to open stdin: %w", err)
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	exited := make(chan struct{})
+	if stdin != nil {
+		go func() {
+			copyInput(stdin, attach.Stdin, exited)
+			stdin.Close()
+		}()
+	}
+
+	return l.watch(cmd, func() { close(exited) }), nil
+}
+
+func (l *LocalExecHandle) CopyTo(src io.Reader, dst string) error {
+	path, err := l.resolve(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, src); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return f.Close()
+}
+
+func (l *LocalExecHandle) CopyFrom(src string) (io.ReadCloser, error) {
+	path, err := l.resolve(src)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return f, nil
+}
+
+func (l *LocalExecHandle) command(req domain.ExecRequest) (*exec.Cmd, error) {
+	if len(req.Command) == 0 {
+		return nil, domain.InvalidSpec("empty command")
+	}
+
+	dir, err := l.resolve(req.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(l.ctx, req.Command[0], req.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range req.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	return cmd, nil
+}
+
+func (l *LocalExecHandle) resolve(path string) (string, error) {
+	resolved, err := securejoin.SecureJoin(l.workdir, path)
+	if err != nil {
+		return "", &domain.InvalidSpecError{Err: fmt.Errorf("failed to resolve %q inside node workdir: %w", path, err)}
+	}
+	return resolved, nil
+}
+
+// watch reports the exit code of an already started command, cleanup runs
+// after the process exits and before the exit code is published.
+func (l *LocalExecHandle) watch(cmd *exec.Cmd, cleanup func()) *domain.AttachResult {
+	exitCode := make(chan int, 1)
+	done := make(chan struct{})
+	var waitErr error
+
+	go func() {
+		waitErr = cmd.Wait()
+		cleanup()
+
+		var exitErr *exec.ExitError
+		if waitErr != nil && errors.As(waitErr, &exitErr) {
+			waitErr = nil
+		}
+		exitCode <- cmd.ProcessState.ExitCode()
+		close(exitCode)
+		close(done)
+	}()
+
+	return &domain.AttachResult{
+		ExitCode: exitCode,
+		Close: func() error {
+			if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+				return err
+			}
+			return nil
+		},
+		Wait: func() error {
+			<-done
+			return waitErr
+		},
+	}
+}
+
+var _ port.ExecHandle = (*LocalExecHandle)(nil)
@@ -0,0 +1,258 @@
+package execute
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+)
+
+func newTestLocalHandle(t *testing.T) (*LocalExecHandle, string) {
+	t.Helper()
+
+	workdir := t.TempDir()
+	handle := NewLocalExecHandle(workdir)
+	t.Cleanup(func() { handle.Close() })
+	return handle, workdir
+}
+
+// waitExit returns the exit code of res or fails when the command does not
+// finish in time.
+func waitExit(t *testing.T, res *domain.AttachResult) int {
+	t.Helper()
+
+	select {
+	case code := <-res.ExitCode:
+		done := make(chan error, 1)
+		go func() { done <- res.Wait() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("wait: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("wait did not return after the command exited")
+		}
+		return code
+	case <-time.After(5 * time.Second):
+		t.Fatal("command did not finish")
+		return 0
+	}
+}
+
+func TestLocalExec(t *testing.T) {
+	handle, workdir := newTestLocalHandle(t)
+	if err := os.Mkdir(filepath.Join(workdir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := handle.Exec(domain.ExecRequest{
+		Command:    []string{"sh", "-c", `echo "$GREETING from $(pwd)"; echo oops >&2; exit 3`},
+		Env:        map[string]string{"GREETING": "hello"},
+		WorkingDir: "sub",
+	})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	if res.ExitCode != 3 {
+		t.Errorf("exit code = %d, want 3", res.ExitCode)
+	}
+	if got, want := string(res.Stdout), "hello from "+filepath.Join(workdir, "sub")+"\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if got := string(res.Stderr); got != "oops\n" {
+		t.Errorf("stderr = %q, want %q", got, "oops\n")
+	}
+}
+
+func TestLocalExecMissingCommand(t *testing.T) {
+	handle, _ := newTestLocalHandle(t)
+
+	if _, err := handle.Exec(domain.ExecRequest{}); domain.Code(err) != domain.ErrorCodeInvalidSpec {
+		t.Errorf("code = %q, want invalid_spec", domain.Code(err))
+	}
+	if _, err := handle.Exec(domain.ExecRequest{Command: []string{"nodemgr-no-such-command"}}); err == nil {
+		t.Error("exec of a missing binary succeeded")
+	}
+}
+
+func TestLocalExecStreamWithOpenStdin(t *testing.T) {
+	handle, _ := newTestLocalHandle(t)
+
+	stdin, input := io.Pipe()
+	defer input.Close()
+	var stdout bytes.Buffer
+
+	res, err := handle.ExecStream(
+		domain.ExecRequest{Command: []string{"sh", "-c", "read line; echo got $line; exit 4"}},
+		domain.AttachRequest{Stdin: stdin, Stdout: &stdout},
+	)
+	if err != nil {
+		t.Fatalf("exec stream: %v", err)
+	}
+
+	// the pipe stays open after the line, like an interactive client
+	if _, err := input.Write([]byte("x\n")); err != nil {
+		t.Fatalf("writing stdin: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 4 {
+		t.Errorf("exit code = %d, want 4", code)
+	}
+	if got := stdout.String(); got != "got x\n" {
+		t.Errorf("stdout = %q, want %q", got, "got x\n")
+	}
+}
+
+func TestLocalExecStreamIgnoringStdin(t *testing.T) {
+	handle, _ := newTestLocalHandle(t)
+
+	stdin, input := io.Pipe()
+	defer input.Close()
+
+	res, err := handle.ExecStream(
+		domain.ExecRequest{Command: []string{"sh", "-c", "exit 2"}},
+		domain.AttachRequest{Stdin: stdin, Stdout: io.Discard},
+	)
+	if err != nil {
+		t.Fatalf("exec stream: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+}
+
+func TestLocalExecStreamStdinEOF(t *testing.T) {
+	handle, _ := newTestLocalHandle(t)
+	var stdout bytes.Buffer
+
+	res, err := handle.ExecStream(
+		domain.ExecRequest{Command: []string{"cat"}},
+		domain.AttachRequest{Stdin: strings.NewReader("streamed"), Stdout: &stdout},
+	)
+	if err != nil {
+		t.Fatalf("exec stream: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	if got := stdout.String(); got != "streamed" {
+		t.Errorf("stdout = %q, want %q", got, "streamed")
+	}
+}
+
+// syncBuffer is written by the pty reader while the test reads it.
+type syncBuffer struct {
+	ch chan []byte
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.ch <- bytes.Clone(p)
+	return len(p), nil
+}
+
+func TestLocalAttach(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	handle, _ := newTestLocalHandle(t)
+
+	stdin, input := io.Pipe()
+	defer input.Close()
+	stdout := &syncBuffer{ch: make(chan []byte, 64)}
+
+	res, err := handle.Attach(domain.AttachRequest{Stdin: stdin, Stdout: stdout})
+	if err != nil {
+		t.Skipf("no pty available: %v", err)
+	}
+
+	// only the evaluated expression prints 42, the echoed input does not
+	if _, err := input.Write([]byte("echo $((40 + 2)); exit 5\n")); err != nil {
+		t.Fatalf("writing stdin: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 5 {
+		t.Errorf("exit code = %d, want 5", code)
+	}
+
+	var output strings.Builder
+	for len(stdout.ch) > 0 {
+		output.Write(<-stdout.ch)
+	}
+	if !strings.Contains(output.String(), "42") {
+		t.Errorf("output = %q, want the evaluated 42", output.String())
+	}
+}
+
+func TestLocalCopy(t *testing.T) {
+	handle, workdir := newTestLocalHandle(t)
+
+	if err := handle.CopyTo(strings.NewReader("payload"), "nested/file.txt"); err != nil {
+		t.Fatalf("copy to: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(workdir, "nested/file.txt"))
+	if err != nil {
+		t.Fatalf("reading copied file: %v", err)
+	}
+	if string(b) != "payload" {
+		t.Errorf("copied file = %q, want %q", b, "payload")
+	}
+
+	src, err := handle.CopyFrom("nested/file.txt")
+	if err != nil {
+		t.Fatalf("copy from: %v", err)
+	}
+	defer src.Close()
+	if b, _ := io.ReadAll(src); string(b) != "payload" {
+		t.Errorf("copied back = %q, want %q", b, "payload")
+	}
+}
+
+func TestLocalPathsStayInWorkdir(t *testing.T) {
+	handle, workdir := newTestLocalHandle(t)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("host file"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(workdir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"absolute path":      filepath.Join(outside, "secret"),
+		"parent directories": "../../../../../../../.." + filepath.Join(outside, "secret"),
+		"symlink":            "link/secret",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resolved, err := handle.resolve(path)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if !strings.HasPrefix(resolved, workdir+string(filepath.Separator)) {
+				t.Errorf("%q resolved to %q outside of the workdir", path, resolved)
+			}
+
+			// an earlier case may have created the file inside the workdir
+			if src, err := handle.CopyFrom(path); err == nil {
+				b, _ := io.ReadAll(src)
+				src.Close()
+				if string(b) == "host file" {
+					t.Error("copied the file from outside the workdir")
+				}
+			}
+			if err := handle.CopyTo(strings.NewReader("overwritten"), path); err != nil {
+				t.Fatalf("copy to: %v", err)
+			}
+		})
+	}
+
+	if b, _ := os.ReadFile(filepath.Join(outside, "secret")); string(b) != "host file" {
+		t.Errorf("file outside the workdir = %q, want it untouched", b)
+	}
+}
@@ -0,0 +1,25 @@
+package execute
+
+import (
+	"sync"
+
+	"nodemgr/internal/core/port"
+)
+
+// register stores the handle in repository until it is closed.
+func register(repository port.ExecHandleRepository, handle port.ExecHandle) port.ExecHandle {
+	repository.Create(handle)
+	return &registeredHandle{ExecHandle: handle, repository: repository}
+}
+
+type registeredHandle struct {
+	port.ExecHandle
+
+	repository port.ExecHandleRepository
+	once       sync.Once
+}
+
+func (h *registeredHandle) Close() error {
+	h.once.Do(func() { _ = h.repository.Delete(h.ID()) })
+	return h.ExecHandle.Close()
+}
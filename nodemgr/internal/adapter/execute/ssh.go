@@ -0,0 +1,433 @@
+package execute
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshDialTimeout bounds connecting and the ssh handshake, an unreachable
+// node must not block the request forever
+const sshDialTimeout = 30 * time.Second
+
+type SSHExecProvider struct {
+	execHandleRepository port.ExecHandleRepository
+	knownHostsPath       string
+}
+
+// NewSSHExecProvider creates ssh executor. Host keys are verified against
+// the node "ssh_host_key" meta and, when it is missing, against knownHostsPath.
+func NewSSHExecProvider(execHandleRepository port.ExecHandleRepository, knownHostsPath string) *SSHExecProvider {
+	return &SSHExecProvider{
+		execHandleRepository: execHandleRepository,
+		knownHostsPath:       knownHostsPath,
+	}
+}
+
+func (p *SSHExecProvider) ID() domain.ExecProviderID {
+	return domain.ExecProviderID("ssh")
+}
+
+func (p *SSHExecProvider) OpenExecHandle(node *domain.Node) (port.ExecHandle, error) {
+	if !node.HasCap("exec:ssh") {
+		return nil, &domain.CapabilityMissingError{NodeID: node.NodeID, Cap: "exec:ssh"}
+	}
+
+	host, ok := node.Meta["ssh_host"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing ssh_host")
+	}
+	user, ok := node.Meta["ssh_user"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing ssh_user")
+	}
+	sshPort := 22
+	if v, ok := node.Meta["ssh_port"]; ok {
+		var err error
+		if sshPort, err = strconv.Atoi(fmt.Sprint(v)); err != nil {
+			return nil, &domain.InvalidSpecError{Err: fmt.Errorf("invalid ssh_port: %w", err)}
+		}
+	}
+
+	hostKeyCallback, err := p.hostKeyCallback(node)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, agentConn, err := p.authMethods(node)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshDialTimeout,
+	}
+
+	client, err := ssh.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(sshPort)), config)
+	if err != nil {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+		return nil, fmt.Errorf("failed to dial ssh: %w", err)
+	}
+
+	workdir, _ := node.Meta["workdir"].(string)
+	execHandle := NewSSHExecHandle(client, workdir)
+	execHandle.agentConn = agentConn
+
+	return register(p.execHandleRepository, execHandle), nil
+}
+
+// authMethods returns the key of the node and the keys of the ssh agent, the
+// agent connection is returned too so the handle can close it.
+func (p *SSHExecProvider) authMethods(node *domain.Node) ([]ssh.AuthMethod, net.Conn, error) {
+	var methods []ssh.AuthMethod
+
+	key, _ := node.Meta["ssh_private_key"].(string)
+	if keyPath, ok := node.Meta["ssh_key_path"].(string); ok && key == "" {
+		b, err := os.ReadFile(keyPath)
+		if err != nil {
+			return nil, nil, &domain.InvalidSpecError{Err: fmt.Errorf("failed to read ssh key: %w", err)}
+		}
+		key = string(b)
+	}
+	if key != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(key))
+		if err != nil {
+			return nil, nil, &domain.InvalidSpecError{Err: fmt.Errorf("failed to parse ssh key: %w", err)}
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err == nil {
+			agentConn = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, domain.InvalidSpec("no ssh key in node meta and no ssh agent available")
+	}
+	return methods, agentConn, nil
+}
+
+func (p *SSHExecProvider) hostKeyCallback(node *domain.Node) (ssh.HostKeyCallback, error) {
+	if hostKey, ok := node.Meta["ssh_host_key"].(string); ok && hostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+		if err != nil {
+			return nil, &domain.InvalidSpecError{Err: fmt.Errorf("failed to parse ssh_host_key: %w", err)}
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+
+	if p.knownHostsPath == "" {
+		return nil, domain.InvalidSpec("node meta is missing ssh_host_key and no known_hosts file is configured")
+	}
+
+	callback, err := knownhosts.New(p.knownHostsPath)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("failed to load known_hosts: %w", err)}
+	}
+	return callback, nil
+}
+
+var _ port.NodeExecProvider = (*SSHExecProvider)(nil)
+
+type SSHExecHandle struct {
+	id      string
+	client  *ssh.Client
+	workdir string
+	// agentConn is the connection to the ssh agent the client authenticated with
+	agentConn net.Conn
+
+	mu   sync.Mutex
+	sftp *sftp.Client
+}
+
+func NewSSHExecHandle(client *ssh.Client, workdir string) *SSHExecHandle {
+	return &SSHExecHandle{
+		id:      uuid.New().String(),
+		client:  client,
+		workdir: workdir,
+	}
+}
+
+func (s *SSHExecHandle) ID() domain.ExecHandleID {
+	return domain.ExecHandleID(s.id)
+}
+
+func (s *SSHExecHandle) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sftp != nil {
+		s.sftp.Close()
+		s.sftp = nil
+	}
+	if s.agentConn != nil {
+		s.agentConn.Close()
+		s.agentConn = nil
+	}
+	return s.client.Close()
+}
+
+func (s *SSHExecHandle) Attach(req domain.AttachRequest) (*domain.AttachResult, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm", 40, 80, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to request pty: %w", err)
+	}
+
+	session.Stdout = req.Stdout
+	session.Stderr = req.Stderr
+	stdin, err := stdinPipe(session, req.Stdin)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+
+	return s.watch(session, forwardInput(stdin, req.Stdin)), nil
+}
+
+func (s *SSHExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+	defer session.Close()
+
+	var outBuf, errBuf bytes.Buffer
+	session.Stdout = &outBuf
+	session.Stderr = &errBuf
+
+	var execResult domain.ExecResult
+	exitCode, err := exitStatus(session.Run(s.command(req)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	execResult.ExitCode = exitCode
+	execResult.Stdout = outBuf.Bytes()
+	execResult.Stderr = errBuf.Bytes()
+
+	return &execResult, nil
+}
+
+func (s *SSHExecHandle) ExecStream(req domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+
+	session.Stdout = attach.Stdout
+	session.Stderr = attach.Stderr
+	stdin, err := stdinPipe(session, attach.Stdin)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	if err := session.Start(s.command(req)); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+
+	return s.watch(session, forwardInput(stdin, attach.Stdin)), nil
+}
+
+// stdinPipe opens the session input when there is something to send. The
+// input is copied by forwardInput instead of the session, which would keep
+// reading a stdin that never reaches EOF after the command exits.
+func stdinPipe(session *ssh.Session, src io.Reader) (io.WriteCloser, error) {
+	if src == nil {
+		return nil, nil
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	return stdin, nil
+}
+
+// forwardInput copies src to the started session until the returned function
+// reports that the command exited.
+func forwardInput(stdin io.WriteCloser, src io.Reader) func() {
+	exited := make(chan struct{})
+	if stdin != nil {
+		go func() {
+			copyInput(stdin, src, exited)
+			stdin.Close()
+		}()
+	}
+	return func() { close(exited) }
+}
+
+func (s *SSHExecHandle) CopyTo(src io.Reader, dst string) error {
+	client, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	dst = s.resolve(dst)
+	if err := client.MkdirAll(path.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	f, err := client.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.ReadFrom(src); err != nil {
+		return fmt.Errorf("failed to write remote file: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *SSHExecHandle) CopyFrom(src string) (io.ReadCloser, error) {
+	client, err := s.sftpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := client.Open(s.resolve(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open remote file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *SSHExecHandle) sftpClient() (*sftp.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sftp != nil {
+		return s.sftp, nil
+	}
+
+	client, err := sftp.NewClient(s.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp: %w", err)
+	}
+	s.sftp = client
+	return client, nil
+}
+
+func (s *SSHExecHandle) resolve(p string) string {
+	if path.IsAbs(p) || s.workdir == "" {
+		return p
+	}
+	return path.Join(s.workdir, p)
+}
+
+// command renders the request into a single shell line, sshd often refuses
+// Setenv so both env and working directory are handled by the remote shell.
+func (s *SSHExecHandle) command(req domain.ExecRequest) string {
+	var sb strings.Builder
+
+	if dir := s.resolve(req.WorkingDir); dir != "" {
+		fmt.Fprintf(&sb, "cd %s && ", util.ShellQuote(dir))
+	}
+	if len(req.Env) > 0 {
+		sb.WriteString("env ")
+		for k, v := range req.Env {
+			sb.WriteString(util.ShellQuote(k + "=" + v))
+			sb.WriteString(" ")
+		}
+	}
+
+	quoted := make([]string, len(req.Command))
+	for i, arg := range req.Command {
+		quoted[i] = util.ShellQuote(arg)
+	}
+	sb.WriteString(strings.Join(quoted, " "))
+
+	return sb.String()
+}
+
+// watch reports the exit code of a started session, exited runs once the
+// command is done and before the exit code is published.
+func (s *SSHExecHandle) watch(session *ssh.Session, exited func()) *domain.AttachResult {
+	exitCode := make(chan int, 1)
+	done := make(chan struct{})
+	var waitErr error
+
+	go func() {
+		var code int
+		code, waitErr = exitStatus(session.Wait())
+		exited()
+		session.Close()
+
+		exitCode <- code
+		close(exitCode)
+		close(done)
+	}()
+
+	return &domain.AttachResult{
+		ExitCode: exitCode,
+		Close: func() error {
+			err := session.Close()
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			return nil
+		},
+		Wait: func() error {
+			<-done
+			return waitErr
+		},
+	}
+}
+
+func exitStatus(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	return -1, err
+}
+
+var _ port.ExecHandle = (*SSHExecHandle)(nil)
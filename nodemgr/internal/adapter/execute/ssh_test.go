@@ -0,0 +1,190 @@
+package execute
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"nodemgr/internal/adapter/execute/sshtest"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+)
+
+func openTestSSHHandle(t *testing.T) (port.ExecHandle, port.ExecHandleRepository, string) {
+	t.Helper()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+	repository := util.NewRepository[domain.ExecHandleID, port.ExecHandle]()
+	workdir := t.TempDir()
+
+	handle, err := NewSSHExecProvider(repository, "").OpenExecHandle(server.Node(workdir))
+	if err != nil {
+		t.Fatalf("opening handle: %v", err)
+	}
+	return handle, repository, workdir
+}
+
+func TestSSHExec(t *testing.T) {
+	handle, _, workdir := openTestSSHHandle(t)
+	defer handle.Close()
+
+	res, err := handle.Exec(domain.ExecRequest{
+		Command: []string{"sh", "-c", `echo "$GREETING from $(pwd)"; echo oops >&2; exit 3`},
+		Env:     map[string]string{"GREETING": "it's me"},
+	})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	if res.ExitCode != 3 {
+		t.Errorf("exit code = %d, want 3", res.ExitCode)
+	}
+	if got, want := string(res.Stdout), "it's me from "+workdir+"\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if got := string(res.Stderr); got != "oops\n" {
+		t.Errorf("stderr = %q, want %q", got, "oops\n")
+	}
+}
+
+func TestSSHExecStream(t *testing.T) {
+	handle, _, _ := openTestSSHHandle(t)
+	defer handle.Close()
+
+	var stdout bytes.Buffer
+	res, err := handle.ExecStream(
+		domain.ExecRequest{Command: []string{"cat"}},
+		domain.AttachRequest{Stdin: bytes.NewBufferString("streamed"), Stdout: &stdout},
+	)
+	if err != nil {
+		t.Fatalf("exec stream: %v", err)
+	}
+
+	if code := <-res.ExitCode; code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+	if err := res.Wait(); err != nil {
+		t.Errorf("wait: %v", err)
+	}
+	if got := stdout.String(); got != "streamed" {
+		t.Errorf("stdout = %q, want %q", got, "streamed")
+	}
+}
+
+func TestSSHExecStreamWithOpenStdin(t *testing.T) {
+	handle, _, _ := openTestSSHHandle(t)
+	defer handle.Close()
+
+	stdin, input := io.Pipe()
+	defer input.Close()
+	var stdout bytes.Buffer
+
+	res, err := handle.ExecStream(
+		domain.ExecRequest{Command: []string{"sh", "-c", "read line; echo got $line; exit 4"}},
+		domain.AttachRequest{Stdin: stdin, Stdout: &stdout},
+	)
+	if err != nil {
+		t.Fatalf("exec stream: %v", err)
+	}
+
+	// the pipe is never closed, like the input of an interactive client
+	if _, err := input.Write([]byte("x\n")); err != nil {
+		t.Fatalf("writing stdin: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 4 {
+		t.Errorf("exit code = %d, want 4", code)
+	}
+	if got := stdout.String(); got != "got x\n" {
+		t.Errorf("stdout = %q, want %q", got, "got x\n")
+	}
+}
+
+func TestSSHAttachWithOpenStdin(t *testing.T) {
+	handle, _, _ := openTestSSHHandle(t)
+	defer handle.Close()
+
+	stdin, input := io.Pipe()
+	defer input.Close()
+	var stdout bytes.Buffer
+
+	res, err := handle.Attach(domain.AttachRequest{Stdin: stdin, Stdout: &stdout})
+	if err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+
+	if _, err := input.Write([]byte("echo $((40 + 2)); exit 5\n")); err != nil {
+		t.Fatalf("writing stdin: %v", err)
+	}
+
+	if code := waitExit(t, res); code != 5 {
+		t.Errorf("exit code = %d, want 5", code)
+	}
+	if got := stdout.String(); got != "42\n" {
+		t.Errorf("stdout = %q, want %q", got, "42\n")
+	}
+}
+
+func TestSSHCopy(t *testing.T) {
+	handle, _, workdir := openTestSSHHandle(t)
+	defer handle.Close()
+
+	if err := handle.CopyTo(bytes.NewBufferString("payload"), "nested/file.txt"); err != nil {
+		t.Fatalf("copy to: %v", err)
+	}
+
+	res, err := handle.Exec(domain.ExecRequest{Command: []string{"cat", filepath.Join(workdir, "nested/file.txt")}})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if got := string(res.Stdout); got != "payload" {
+		t.Errorf("copied file = %q, want %q", got, "payload")
+	}
+
+	src, err := handle.CopyFrom("nested/file.txt")
+	if err != nil {
+		t.Fatalf("copy from: %v", err)
+	}
+	defer src.Close()
+
+	b, err := io.ReadAll(src)
+	if err != nil {
+		t.Fatalf("reading copied file: %v", err)
+	}
+	if got := string(b); got != "payload" {
+		t.Errorf("copied back = %q, want %q", got, "payload")
+	}
+}
+
+func TestSSHHostKeyMismatch(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+	other := sshtest.NewServer(t)
+
+	node := server.Node(t.TempDir())
+	node.Meta["ssh_host_key"] = other.AuthorizedHostKey()
+
+	repository := util.NewRepository[domain.ExecHandleID, port.ExecHandle]()
+	if _, err := NewSSHExecProvider(repository, "").OpenExecHandle(node); err == nil {
+		t.Fatal("opening handle with the wrong host key succeeded")
+	}
+}
+
+func TestSSHHandleUnregisteredOnClose(t *testing.T) {
+	handle, repository, _ := openTestSSHHandle(t)
+
+	if _, err := repository.Get(handle.ID()); err != nil {
+		t.Fatalf("open handle is not registered: %v", err)
+	}
+	if err := handle.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := repository.Get(handle.ID()); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("closed handle is still registered, err = %v", err)
+	}
+}
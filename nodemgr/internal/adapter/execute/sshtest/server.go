@@ -0,0 +1,186 @@
+// Package sshtest runs an in-process ssh server for tests of code which
+// reaches nodes over ssh.
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"nodemgr/internal/core/domain"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Server runs commands of exec requests with the local shell and serves
+// sftp, which is all the ssh executor needs.
+type Server struct {
+	Addr    *net.TCPAddr
+	HostKey ssh.PublicKey
+	// ClientKey is the PEM encoded private key the server accepts
+	ClientKey string
+}
+
+// NewServer starts a server on a random local port until the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, errors.New("unknown key")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSHConn(conn, config)
+		}
+	}()
+
+	return &Server{
+		Addr:      listener.Addr().(*net.TCPAddr),
+		HostKey:   hostSigner.PublicKey(),
+		ClientKey: string(pem.EncodeToMemory(block)),
+	}
+}
+
+func serveSSHConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go serveSSHSession(channel, requests)
+	}
+}
+
+func serveSSHSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			req.Reply(true, nil)
+		case "exec", "shell":
+			var payload struct{ Command string }
+			if req.Type == "exec" {
+				ssh.Unmarshal(req.Payload, &payload)
+			}
+			req.Reply(true, nil)
+
+			cmd := exec.Command("/bin/sh")
+			if payload.Command != "" {
+				cmd.Args = append(cmd.Args, "-c", payload.Command)
+			}
+			// like sshd the input is not waited for once the command exits
+			cmd.Stdout, cmd.Stderr = channel, channel.Stderr()
+			stdin, _ := cmd.StdinPipe()
+			if err := cmd.Start(); err != nil {
+				return
+			}
+			go func() {
+				io.Copy(stdin, channel)
+				stdin.Close()
+			}()
+			cmd.Wait()
+
+			status := struct{ Status uint32 }{uint32(cmd.ProcessState.ExitCode())}
+			channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			ssh.Unmarshal(req.Payload, &payload)
+			if payload.Name != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				return
+			}
+			server.Serve()
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// AuthorizedHostKey is the host key in authorized_keys format, as ssh
+// executors expect it in "ssh_host_key" node meta.
+func (s *Server) AuthorizedHostKey() string {
+	return string(ssh.MarshalAuthorizedKey(s.HostKey))
+}
+
+// Node is a running node reaching the server over "exec:ssh".
+func (s *Server) Node(workdir string) *domain.Node {
+	return &domain.Node{
+		NodeID: "ssh-node",
+		State:  domain.NodeStateRunning,
+		Meta: map[string]any{
+			"ssh_host":        s.Addr.IP.String(),
+			"ssh_port":        strconv.Itoa(s.Addr.Port),
+			"ssh_user":        "test",
+			"ssh_private_key": s.ClientKey,
+			"ssh_host_key":    s.AuthorizedHostKey(),
+			"workdir":         workdir,
+		},
+		Cap: map[domain.Cap]bool{"exec:ssh": true},
+	}
+}
@@ -0,0 +1,88 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+type DockerLifecycle struct{}
+
+func NewDockerLifecycle() *DockerLifecycle {
+	return &DockerLifecycle{}
+}
+
+func (l *DockerLifecycle) ID() domain.LifecycleProviderID {
+	return domain.LifecycleProviderID("docker")
+}
+
+func (l *DockerLifecycle) OpenLifecycleHandle(node *domain.Node) (port.NodeLifecycleHandle, error) {
+	if !node.HasCap("lifecycle:docker") {
+		return nil, &domain.CapabilityMissingError{NodeID: node.NodeID, Cap: "lifecycle:docker"}
+	}
+
+	containerID, ok := node.Meta["container_id"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing container_id")
+	}
+	dockerHost, ok := node.Meta["docker_host"].(string)
+	if !ok {
+		return nil, domain.InvalidSpec("node meta is missing docker_host")
+	}
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(), client.WithHost(dockerHost))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+
+	return NewDockerLifecycleHandle(cli, containerID), nil
+}
+
+var _ port.NodeLifecycle = (*DockerLifecycle)(nil)
+
+type DockerLifecycleHandle struct {
+	cli         *client.Client
+	containerID string
+}
+
+func NewDockerLifecycleHandle(cli *client.Client, containerID string) *DockerLifecycleHandle {
+	return &DockerLifecycleHandle{
+		cli:         cli,
+		containerID: containerID,
+	}
+}
+
+func (h *DockerLifecycleHandle) Close() error {
+	if h.cli != nil {
+		return h.cli.Close()
+	}
+	return nil
+}
+
+func (h *DockerLifecycleHandle) Start(ctx context.Context) error {
+	if err := h.cli.ContainerStart(ctx, h.containerID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	return nil
+}
+
+func (h *DockerLifecycleHandle) Stop(ctx context.Context) error {
+	if err := h.cli.ContainerStop(ctx, h.containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
+}
+
+func (h *DockerLifecycleHandle) Reboot(ctx context.Context) error {
+	if err := h.cli.ContainerRestart(ctx, h.containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	return nil
+}
+
+var _ port.NodeLifecycleHandle = (*DockerLifecycleHandle)(nil)
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// resultOK labels successful calls, failures are labelled with their
+// domain error code.
+const resultOK = "ok"
+
+// PrometheusMetrics records provider calls and exposes them together with node,
+// pool and queue gauges which are read from the repositories on every scrape.
+type PrometheusMetrics struct {
+	registry *prometheus.Registry
+
+	provisionSeconds *prometheus.HistogramVec
+	destroySeconds   *prometheus.HistogramVec
+	execSeconds      *prometheus.HistogramVec
+	execExitCodes    *prometheus.CounterVec
+	execErrors       *prometheus.CounterVec
+
+	nodeRepository  port.NodeRepository
+	poolRepository  port.NodePoolRepository
+	queueRepository port.QueueRepository
+
+	nodesDesc      *prometheus.Desc
+	poolSizeDesc   *prometheus.Desc
+	poolIdleDesc   *prometheus.Desc
+	queueDepthDesc *prometheus.Desc
+}
+
+func NewPrometheusMetrics(nodeRepository port.NodeRepository, poolRepository port.NodePoolRepository, queueRepository port.QueueRepository) *PrometheusMetrics {
+	// provisioning ranges from a second for containers to minutes for vms
+	providerBuckets := []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300, 600}
+
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		provisionSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nodemgr_provision_duration_seconds",
+			Help:    "Time spent provisioning nodes including retries.",
+			Buckets: providerBuckets,
+		}, []string{"provider", "result"}),
+		destroySeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nodemgr_destroy_duration_seconds",
+			Help:    "Time spent destroying nodes including retries.",
+			Buckets: providerBuckets,
+		}, []string{"provider", "result"}),
+		execSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nodemgr_exec_duration_seconds",
+			Help:    "Time spent running commands and attached sessions on nodes.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 3, 10),
+		}, []string{"provider"}),
+		execExitCodes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nodemgr_exec_exit_codes_total",
+			Help: "Commands and attached sessions that ran to completion by exit code.",
+		}, []string{"provider", "exit_code"}),
+		execErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nodemgr_exec_errors_total",
+			Help: "Commands that could not be run by error code.",
+		}, []string{"provider", "code"}),
+
+		nodeRepository:  nodeRepository,
+		poolRepository:  poolRepository,
+		queueRepository: queueRepository,
+
+		nodesDesc: prometheus.NewDesc("nodemgr_nodes",
+			"Known nodes by provider and state.", []string{"provider", "state"}, nil),
+		poolSizeDesc: prometheus.NewDesc("nodemgr_pool_size",
+			"Configured number of idle nodes of a warm pool.", []string{"pool", "template", "provider"}, nil),
+		poolIdleDesc: prometheus.NewDesc("nodemgr_pool_idle_nodes",
+			"Idle nodes currently waiting in a warm pool.", []string{"pool", "template", "provider"}, nil),
+		queueDepthDesc: prometheus.NewDesc("nodemgr_queue_depth",
+			"Provisioning requests waiting in the queue by priority.", []string{"priority"}, nil),
+	}
+
+	m.registry.MustRegister(
+		m.provisionSeconds,
+		m.destroySeconds,
+		m.execSeconds,
+		m.execExitCodes,
+		m.execErrors,
+		m,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the prometheus exposition format.
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *PrometheusMetrics) ObserveProvision(providerID domain.ProviderID, duration time.Duration, err error) {
+	m.provisionSeconds.WithLabelValues(string(providerID), result(err)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveDestroy(providerID domain.ProviderID, duration time.Duration, err error) {
+	m.destroySeconds.WithLabelValues(string(providerID), result(err)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveExec(providerID domain.ProviderID, duration time.Duration, exitCode int, err error) {
+	m.execSeconds.WithLabelValues(string(providerID)).Observe(duration.Seconds())
+	if err != nil {
+		m.execErrors.WithLabelValues(string(providerID), string(domain.Code(err))).Inc()
+		return
+	}
+	m.execExitCodes.WithLabelValues(string(providerID), strconv.Itoa(exitCode)).Inc()
+}
+
+func (m *PrometheusMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.nodesDesc
+	ch <- m.poolSizeDesc
+	ch <- m.poolIdleDesc
+	ch <- m.queueDepthDesc
+}
+
+func (m *PrometheusMetrics) Collect(ch chan<- prometheus.Metric) {
+	nodes, err := m.nodeRepository.List()
+	if err != nil {
+		slog.Error("listing nodes for metrics", "err", err)
+		return
+	}
+
+	type providerState struct {
+		provider domain.ProviderID
+		state    domain.NodeState
+	}
+	counts := make(map[providerState]int)
+	idle := make(map[domain.PoolID]int)
+	for _, node := range nodes {
+		if node.State == domain.NodeStateTerminated {
+			continue
+		}
+		counts[providerState{node.ProviderID, node.State}]++
+		if node.PoolID != "" && node.State == domain.NodeStateRunning {
+			idle[node.PoolID]++
+		}
+	}
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(m.nodesDesc, prometheus.GaugeValue, float64(n), string(k.provider), string(k.state))
+	}
+
+	pools, err := m.poolRepository.List()
+	if err != nil {
+		slog.Error("listing pools for metrics", "err", err)
+		return
+	}
+	for _, pool := range pools {
+		labels := []string{string(pool.PoolID), string(pool.TemplateID), string(pool.ProviderID)}
+		ch <- prometheus.MustNewConstMetric(m.poolSizeDesc, prometheus.GaugeValue, float64(pool.Size), labels...)
+		ch <- prometheus.MustNewConstMetric(m.poolIdleDesc, prometheus.GaugeValue, float64(idle[pool.PoolID]), labels...)
+	}
+
+	reqs, err := m.queueRepository.List()
+	if err != nil {
+		slog.Error("listing queue requests for metrics", "err", err)
+		return
+	}
+	depth := map[domain.QueuePriority]int{
+		domain.QueuePriorityInteractive: 0,
+		domain.QueuePriorityNormal:      0,
+		domain.QueuePriorityBatch:       0,
+	}
+	for _, req := range reqs {
+		if req.State == domain.QueueRequestStateQueued {
+			depth[req.Priority]++
+		}
+	}
+	for priority, n := range depth {
+		ch <- prometheus.MustNewConstMetric(m.queueDepthDesc, prometheus.GaugeValue, float64(n), string(priority))
+	}
+}
+
+func result(err error) string {
+	if err == nil {
+		return resultOK
+	}
+	return string(domain.Code(err))
+}
+
+var (
+	_ port.MetricsRecorder = (*PrometheusMetrics)(nil)
+	_ prometheus.Collector = (*PrometheusMetrics)(nil)
+)
@@ -1,20 +1,31 @@
 package provision
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"math"
 	"nodemgr/internal/core/domain"
 	"nodemgr/internal/core/port"
 	"nodemgr/internal/core/util"
 	"strings"
 	"sync"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/pulumi/pulumi-docker/sdk/v4/go/docker"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type DockerArgs struct {
@@ -22,78 +33,128 @@ type DockerArgs struct {
 	User      string           `mapstructure:"user,omitempty"`
 	Image     string           `mapstructure:"image" validate:"required"`
 	ImageType domain.ImageType `mapstructure:"image_type" validate:"required"`
-	CPUs      int              `mapstructure:"cpus,omitempty"`
+	Platform  string           `mapstructure:"platform,omitempty"`
+	CPUs      float64          `mapstructure:"cpus,omitempty" validate:"gte=0"`
 	MemoryMB  int              `mapstructure:"memory_mb,omitempty"`
-	Command   []string         `mapstructure:"command,omitempty"`
+	ShmSizeMB int              `mapstructure:"shm_size_mb,omitempty"`
 	StdinOpen bool             `mapstructure:"stdin_open,omitempty"`
 	Tty       bool             `mapstructure:"tty,omitempty"`
+
+	// PullPolicy defaults to if-not-present
+	PullPolicy PullPolicy `mapstructure:"pull_policy,omitempty" validate:"omitempty,oneof=always if-not-present never"`
+
+	// Command and Entrypoint accept a list or a shell-quoted string
+	Command    util.ShellWords `mapstructure:"command,omitempty"`
+	Entrypoint util.ShellWords `mapstructure:"entrypoint,omitempty"`
+
+	// Env entries use the KEY=VALUE form of docker run -e
+	Env        []string          `mapstructure:"env,omitempty"`
+	WorkingDir string            `mapstructure:"working_dir,omitempty"`
+	Labels     map[string]string `mapstructure:"labels,omitempty"`
+	Mounts     []DockerMount     `mapstructure:"mounts,omitempty" validate:"dive"`
+	Networks   []string          `mapstructure:"networks,omitempty"`
+	Ports      []DockerPort      `mapstructure:"ports,omitempty" validate:"dive"`
+	Privileged bool              `mapstructure:"privileged,omitempty"`
+	CapAdd     []string          `mapstructure:"cap_add,omitempty"`
+	CapDrop    []string          `mapstructure:"cap_drop,omitempty"`
+	Ulimits    []DockerUlimit    `mapstructure:"ulimits,omitempty" validate:"dive"`
+
+	Bootstrap *domain.NodeBootstrap `mapstructure:"bootstrap,omitempty"`
+}
+
+// DockerMount is a bind mount when Source is an absolute path and a named
+// volume otherwise, unless Type says differently.
+type DockerMount struct {
+	Type     string `mapstructure:"type,omitempty" validate:"omitempty,oneof=bind volume tmpfs"`
+	Source   string `mapstructure:"source,omitempty"`
+	Target   string `mapstructure:"target" validate:"required"`
+	ReadOnly bool   `mapstructure:"read_only,omitempty"`
+}
+
+func (m DockerMount) kind() string {
+	switch {
+	case m.Type != "":
+		return m.Type
+	case strings.HasPrefix(m.Source, "/"):
+		return "bind"
+	default:
+		return "volume"
+	}
+}
+
+type DockerPort struct {
+	Internal int    `mapstructure:"internal" validate:"required,gt=0,lte=65535"`
+	External int    `mapstructure:"external,omitempty" validate:"gte=0,lte=65535"`
+	IP       string `mapstructure:"ip,omitempty" validate:"omitempty,ip"`
+	Protocol string `mapstructure:"protocol,omitempty" validate:"omitempty,oneof=tcp udp sctp"`
+}
+
+type DockerUlimit struct {
+	Name string `mapstructure:"name" validate:"required"`
+	Soft int    `mapstructure:"soft"`
+	Hard int    `mapstructure:"hard"`
+}
+
+// labels adds the node id to the user labels so containers can be traced back
+// to the node owning them.
+func (a DockerArgs) labels(nodeID domain.NodeID) map[string]string {
+	labels := map[string]string{dockerNodeLabel: string(nodeID)}
+	maps.Copy(labels, a.Labels)
+	return labels
 }
 
+const (
+	dockerNodeLabel = "nodemgr.node_id"
+	// cpu limits are expressed as quota per period, same as docker run --cpus
+	dockerCPUPeriod = 100000
+)
+
 type DockerProvider struct {
-	mu       sync.Mutex
-	stacks   map[string]auto.Stack
-	validate *validator.Validate
+	mu         sync.Mutex
+	dockerHost string
+	images     *DockerImages
+	stacks     map[string]auto.Stack
+	validate   *validator.Validate
 }
 
-func NewDockerProvider(dockerHost string) *DockerProvider {
+func NewDockerProvider(dockerHost string, images *DockerImages) *DockerProvider {
 	return &DockerProvider{
-		stacks:   make(map[string]auto.Stack),
-		validate: validator.New(validator.WithRequiredStructEnabled()),
+		dockerHost: dockerHost,
+		images:     images,
+		stacks:     make(map[string]auto.Stack),
+		validate:   validator.New(validator.WithRequiredStructEnabled()),
 	}
 }
 
 func (p *DockerProvider) ID() domain.ProviderID {
-	return domain.ProviderID("docker-pulumi")
+	return domain.ProviderID("docker")
 }
 
-func (p *DockerProvider) Provision(spec domain.NodeSpec) (*domain.Node, error) {
-	ctx := context.Background()
-
+func (p *DockerProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error) {
 	args, err := util.DecodeExtraTo[DockerArgs](spec.Extra)
 	if err != nil {
-		return nil, fmt.Errorf("decode extra: %w", err)
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
 	}
 
 	err = p.validate.Struct(args)
 	if err != nil {
-		return nil, fmt.Errorf("validate args: %w", err)
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate args: %w", err)}
 	}
 
-	if args.Name == "" {
-		args.Name = fmt.Sprintf("node-%s", uuid.New().String()[:8])
+	if err := args.defaults(); err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate args: %w", err)}
 	}
 
-	if args.Command != nil {
-		args.Command = strings.Split(args.Command[0], " ")
-	} else {
-		args.Command = []string{"sleep", "infinity"}
+	// pulumi only manages the container, images are handled by the pull policy
+	img, err := p.images.Ensure(ctx, args.Image, args.Platform, args.PullPolicy)
+	if err != nil {
+		return nil, err
 	}
 
-	nodeUUID := uuid.New().String()
-	nodeID := domain.NodeID(nodeUUID)
-	stackName := fmt.Sprintf("%s-node-%s", p.ID(), nodeUUID)
+	stackName := fmt.Sprintf("%s-node-%s", p.ID(), nodeID)
 
 	pulumiProgram := func(ctx *pulumi.Context) error {
-		img, err := docker.NewRemoteImage(ctx, "image", &docker.RemoteImageArgs{
-			Name: pulumi.String(args.Image),
-		})
-		if err != nil {
-			return err
-		}
-
-		containerArgs := &docker.ContainerArgs{
-			Image: img.ImageId,
-			User:  pulumi.StringPtrFromPtr(&args.User),
-			Name:  pulumi.String(args.Name),
-			// TODO: Cpu flag is currently broken in pulumi bindings as underlying terraform provider does not expose it
-			// Cpus:    pulumi.StringPtrFromPtr(cpus),
-			Memory:    pulumi.IntPtrFromPtr(&args.MemoryMB),
-			Command:   pulumi.ToStringArray(args.Command),
-			StdinOpen: pulumi.BoolPtr(args.StdinOpen),
-			Tty:       pulumi.BoolPtr(args.Tty),
-		}
-
-		container, err := docker.NewContainer(ctx, args.Name, containerArgs)
+		container, err := docker.NewContainer(ctx, args.Name, pulumiContainerArgs(args, nodeID, pulumi.String(img.ImageID)))
 		if err != nil {
 			return err
 		}
@@ -112,50 +173,79 @@ func (p *DockerProvider) Provision(spec domain.NodeSpec) (*domain.Node, error) {
 		return nil, fmt.Errorf("installing docker pulumi plugin: %w", err)
 	}
 
-	upRes, err := stack.Up(ctx)
+	engineLog := pulumiLog(ctx, stackName)
+	defer engineLog.Close()
+
+	rollback := func() {
+		// ctx may already be cancelled, cleanup must not depend on it
+		cleanupCtx := context.WithoutCancel(ctx)
+		_, _ = stack.Destroy(cleanupCtx, optdestroy.ProgressStreams(engineLog), optdestroy.ErrorProgressStreams(engineLog))
+		_ = stack.Workspace().RemoveStack(cleanupCtx, stackName)
+	}
+
+	upCtx, span := util.StartSpan(ctx, "pulumi up", attribute.String("pulumi_stack", stackName))
+	upRes, err := stack.Up(upCtx, optup.ProgressStreams(engineLog), optup.ErrorProgressStreams(engineLog))
+	util.EndSpan(span, &err)
 	if err != nil {
-		return nil, fmt.Errorf("pulumi up failed: %w", err)
+		rollback()
+		return nil, fmt.Errorf("pulumi up failed: %w", transient(err))
 	}
 
 	containerId, ok := upRes.Outputs["container_id"].Value.(string)
 	if !ok {
-		return nil, fmt.Errorf("failed to get container_id output from pulumi stack")
+		rollback()
+		return nil, domain.ProviderUnavailable(errors.New("failed to get container_id output from pulumi stack"))
+	}
+
+	if args.Bootstrap != nil {
+		script := util.RenderBootstrapScript(args.Bootstrap, args.User)
+		if err := runBootstrap(ctx, p.dockerHost, containerId, script); err != nil {
+			rollback()
+			return nil, err
+		}
 	}
 
 	p.mu.Lock()
 	p.stacks[string(nodeID)] = stack
 	p.mu.Unlock()
 
+	meta := imageMeta(args, img)
+	meta["pulumi_stack"] = stackName
+	meta["pulumi_stack_name"] = stackName
+	meta["docker_host"] = p.dockerHost
+	meta["container_id"] = containerId
+
 	node := domain.Node{
 		NodeID:     nodeID,
 		ProviderID: p.ID(),
 		State:      domain.NodeStateRunning,
-		Meta: map[string]any{
-			"pulumi_stack":      stackName,
-			"pulumi_stack_name": stackName,
-			"docker_host":       "unix:///var/run/docker.sock",
-			"container_id":      containerId,
-		},
+		Meta:       meta,
 		Cap: map[domain.Cap]bool{
-			"exec:docker": true,
+			"exec:docker":      true,
+			"lifecycle:docker": true,
 		},
 	}
 
 	return &node, nil
 }
 
-func (p *DockerProvider) Destroy(nodeID domain.NodeID) error {
+func (p *DockerProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
 	p.mu.Lock()
 	stack, ok := p.stacks[string(nodeID)]
 	p.mu.Unlock()
 
 	if !ok {
-		return errors.New("stack for node not found")
+		return &domain.NotFoundError{Kind: "pulumi stack", ID: string(nodeID)}
 	}
 
-	ctx := context.Background()
-	if _, err := stack.Destroy(ctx); err != nil {
-		return fmt.Errorf("pulumi destroy failed: %w", err)
+	engineLog := pulumiLog(ctx, stack.Name())
+	defer engineLog.Close()
+
+	destroyCtx, span := util.StartSpan(ctx, "pulumi destroy", attribute.String("pulumi_stack", stack.Name()))
+	_, err := stack.Destroy(destroyCtx, optdestroy.ProgressStreams(engineLog), optdestroy.ErrorProgressStreams(engineLog))
+	util.EndSpan(span, &err)
+	if err != nil {
+		return fmt.Errorf("pulumi destroy failed: %w", transient(err))
 	}
 
 	if err := stack.Workspace().RemoveStack(ctx, stack.Name()); err != nil {
@@ -169,4 +259,234 @@ func (p *DockerProvider) Destroy(nodeID domain.NodeID) error {
 	return nil
 }
 
-var _ port.NodeProvider = (*DockerProvider)(nil)
+func (p *DockerProvider) PrefetchImage(ctx context.Context, spec domain.NodeSpec) error {
+	return prefetchImage(ctx, p.images, spec)
+}
+
+func (p *DockerProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	return dockerSpecResources(spec)
+}
+
+// dockerSpecResources charges the limits of the container, containers without
+// limits only count as a node.
+func dockerSpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	args, err := util.DecodeExtraTo[DockerArgs](spec.Extra)
+	if err != nil {
+		return domain.Resources{}, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+	return domain.Resources{Nodes: 1, CPUs: int(math.Ceil(args.CPUs)), MemoryMB: args.MemoryMB}, nil
+}
+
+func prefetchImage(ctx context.Context, images *DockerImages, spec domain.NodeSpec) error {
+	args, err := util.DecodeExtraTo[DockerArgs](spec.Extra)
+	if err != nil {
+		return &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+	if args.Image == "" || args.PullPolicy == PullPolicyNever {
+		return nil
+	}
+
+	// always only applies to provisioning, warming the cache needs the image once
+	_, err = images.Ensure(ctx, args.Image, args.Platform, PullPolicyIfNotPresent)
+	return err
+}
+
+func imageMeta(args DockerArgs, img DockerImageStatus) map[string]any {
+	policy := args.PullPolicy
+	if policy == "" {
+		policy = PullPolicyIfNotPresent
+	}
+
+	meta := map[string]any{
+		"image":             args.Image,
+		"image_id":          img.ImageID,
+		"image_pull_policy": string(policy),
+		"image_pulled":      img.Pulled,
+	}
+	if img.Digest != "" {
+		meta["image_digest"] = img.Digest
+	}
+	if img.Pulled {
+		meta["image_pulled_at"] = img.PulledAt
+	}
+	return meta
+}
+
+func (a *DockerArgs) defaults() error {
+	if a.Name == "" {
+		a.Name = fmt.Sprintf("node-%s", uuid.New().String()[:8])
+	}
+
+	var err error
+	if a.Command != nil {
+		if a.Command, err = a.Command.Normalize(); err != nil {
+			return fmt.Errorf("command: %w", err)
+		}
+	}
+	if a.Entrypoint != nil {
+		if a.Entrypoint, err = a.Entrypoint.Normalize(); err != nil {
+			return fmt.Errorf("entrypoint: %w", err)
+		}
+	}
+
+	// keep the container alive unless the caller decided what it runs
+	if a.Command == nil && a.Entrypoint == nil {
+		a.Command = util.ShellWords{"sleep", "infinity"}
+	}
+
+	return nil
+}
+
+func pulumiContainerArgs(args DockerArgs, nodeID domain.NodeID, image pulumi.StringInput) *docker.ContainerArgs {
+	containerArgs := &docker.ContainerArgs{
+		Image:       image,
+		User:        pulumi.StringPtrFromPtr(optional(args.User)),
+		Name:        pulumi.String(args.Name),
+		Command:     pulumi.ToStringArray(args.Command),
+		Entrypoints: pulumi.ToStringArray(args.Entrypoint),
+		StdinOpen:   pulumi.BoolPtr(args.StdinOpen),
+		Tty:         pulumi.BoolPtr(args.Tty),
+		Envs:        pulumi.ToStringArray(args.Env),
+		WorkingDir:  pulumi.StringPtrFromPtr(optional(args.WorkingDir)),
+		Privileged:  pulumi.BoolPtr(args.Privileged),
+	}
+
+	if args.MemoryMB > 0 {
+		containerArgs.Memory = pulumi.IntPtr(args.MemoryMB)
+	}
+	if args.ShmSizeMB > 0 {
+		containerArgs.ShmSize = pulumi.IntPtr(args.ShmSizeMB)
+	}
+	if args.CPUs > 0 {
+		// the cpus argument is not supported by the docker plugin, quota is
+		containerArgs.CpuPeriod = pulumi.IntPtr(dockerCPUPeriod)
+		containerArgs.CpuQuota = pulumi.IntPtr(int(args.CPUs * dockerCPUPeriod))
+	}
+	if len(args.CapAdd) > 0 || len(args.CapDrop) > 0 {
+		containerArgs.Capabilities = docker.ContainerCapabilitiesArgs{
+			Adds:  pulumi.ToStringArray(args.CapAdd),
+			Drops: pulumi.ToStringArray(args.CapDrop),
+		}
+	}
+
+	var labels docker.ContainerLabelArray
+	for k, v := range args.labels(nodeID) {
+		labels = append(labels, docker.ContainerLabelArgs{Label: pulumi.String(k), Value: pulumi.String(v)})
+	}
+	containerArgs.Labels = labels
+
+	var mounts docker.ContainerMountArray
+	for _, m := range args.Mounts {
+		mounts = append(mounts, docker.ContainerMountArgs{
+			Type:     pulumi.String(m.kind()),
+			Source:   pulumi.StringPtrFromPtr(optional(m.Source)),
+			Target:   pulumi.String(m.Target),
+			ReadOnly: pulumi.BoolPtr(m.ReadOnly),
+		})
+	}
+	containerArgs.Mounts = mounts
+
+	var networks docker.ContainerNetworksAdvancedArray
+	for _, n := range args.Networks {
+		networks = append(networks, docker.ContainerNetworksAdvancedArgs{Name: pulumi.String(n)})
+	}
+	containerArgs.NetworksAdvanced = networks
+
+	var ports docker.ContainerPortArray
+	for _, port := range args.Ports {
+		portArgs := docker.ContainerPortArgs{
+			Internal: pulumi.Int(port.Internal),
+			Ip:       pulumi.StringPtrFromPtr(optional(port.IP)),
+			Protocol: pulumi.StringPtrFromPtr(optional(port.Protocol)),
+		}
+		if port.External > 0 {
+			portArgs.External = pulumi.IntPtr(port.External)
+		}
+		ports = append(ports, portArgs)
+	}
+	containerArgs.Ports = ports
+
+	var ulimits docker.ContainerUlimitArray
+	for _, u := range args.Ulimits {
+		ulimits = append(ulimits, docker.ContainerUlimitArgs{
+			Name: pulumi.String(u.Name),
+			Soft: pulumi.Int(u.Soft),
+			Hard: pulumi.Int(u.Hard),
+		})
+	}
+	containerArgs.Ulimits = ulimits
+
+	return containerArgs
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func runBootstrap(ctx context.Context, dockerHost string, containerID string, script string) (err error) {
+	ctx, span := util.StartSpan(ctx, "docker bootstrap", attribute.String("container_id", containerID))
+	defer util.EndSpan(span, &err)
+
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(), client.WithHost(dockerHost))
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	opts := container.ExecOptions{
+		User:         "root",
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          []string{"sh", "-c", script},
+	}
+
+	execID, err := cli.ContainerExecCreate(ctx, containerID, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap exec: %w", err)
+	}
+
+	hijack, err := cli.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to attach to bootstrap exec: %w", err)
+	}
+	defer hijack.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, hijack.Reader); err != nil {
+		return fmt.Errorf("failed to read bootstrap output: %w", err)
+	}
+
+	res, err := cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect bootstrap exec: %w", err)
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("bootstrap exited with %d: %s", res.ExitCode, output.String())
+	}
+
+	return nil
+}
+
+var (
+	_ port.NodeProvider          = (*DockerProvider)(nil)
+	_ port.NodeImagePrefetcher   = (*DockerProvider)(nil)
+	_ port.NodeResourceEstimator = (*DockerProvider)(nil)
+)
+
+// pulumiLog captures the pulumi engine output at debug level.
+func pulumiLog(ctx context.Context, stackName string) *util.LogWriter {
+	ctx = util.WithLogAttrs(ctx, slog.String("pulumi_stack", stackName))
+	return util.NewLogWriter(ctx, slog.Default(), slog.LevelDebug)
+}
+
+// transient classifies errors of an unreachable docker daemon and of stacks
+// locked by another update so they are retried.
+func transient(err error) error {
+	if client.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err) || auto.IsConcurrentUpdateError(err) {
+		return domain.ProviderUnavailable(err)
+	}
+	return err
+}
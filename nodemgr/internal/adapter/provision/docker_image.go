@@ -0,0 +1,374 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type PullPolicy string
+
+const (
+	PullPolicyAlways       PullPolicy = "always"
+	PullPolicyIfNotPresent PullPolicy = "if-not-present"
+	PullPolicyNever        PullPolicy = "never"
+)
+
+// DockerRegistryAuth are credentials configured in nodemgr itself, they take
+// precedence over the docker config of the host.
+type DockerRegistryAuth struct {
+	Registry      string `mapstructure:"registry" validate:"required"`
+	Username      string `mapstructure:"username,omitempty"`
+	Password      string `mapstructure:"password,omitempty"`
+	IdentityToken string `mapstructure:"identity_token,omitempty"`
+}
+
+type DockerImageStatus struct {
+	Ref      string
+	ImageID  string
+	Digest   string
+	Pulled   bool
+	PulledAt time.Time
+}
+
+// DockerImages pulls images for both docker providers. It applies the pull
+// policy, resolves registry credentials and makes sure concurrent requests
+// for the same image share a single pull.
+type DockerImages struct {
+	dockerHost string
+	configPath string
+	auths      []DockerRegistryAuth
+	// newClient connects to the docker host, tests replace it
+	newClient func() (imageClient, error)
+
+	mu       sync.Mutex
+	inflight map[string]*imagePull
+}
+
+// imageClient is the part of the docker client used to pull images.
+type imageClient interface {
+	ImageInspect(ctx context.Context, imageID string, inspectOpts ...client.ImageInspectOption) (image.InspectResponse, error)
+	ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error)
+	Close() error
+}
+
+type imagePull struct {
+	done   chan struct{}
+	status DockerImageStatus
+	err    error
+
+	// waiters counts the callers waiting for the pull, it is cancelled once
+	// the last of them gives up
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewDockerImages creates the image puller, an empty dockerConfigPath falls
+// back to $DOCKER_CONFIG/config.json and ~/.docker/config.json.
+func NewDockerImages(dockerHost string, dockerConfigPath string, auths []DockerRegistryAuth) *DockerImages {
+	if dockerConfigPath == "" {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			if home, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(home, ".docker")
+			}
+		}
+		if dir != "" {
+			dockerConfigPath = filepath.Join(dir, "config.json")
+		}
+	}
+
+	d := &DockerImages{
+		dockerHost: dockerHost,
+		configPath: dockerConfigPath,
+		auths:      auths,
+		inflight:   make(map[string]*imagePull),
+	}
+	d.newClient = d.dockerClient
+	return d
+}
+
+func (d *DockerImages) dockerClient() (imageClient, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(), client.WithHost(d.dockerHost))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return cli, nil
+}
+
+func (d *DockerImages) Ensure(ctx context.Context, ref string, platform string, policy PullPolicy) (_ DockerImageStatus, err error) {
+	if policy == "" {
+		policy = PullPolicyIfNotPresent
+	}
+
+	ctx, span := util.StartSpan(ctx, "DockerImages.Ensure",
+		attribute.String("image", ref),
+		attribute.String("pull_policy", string(policy)))
+	defer util.EndSpan(span, &err)
+
+	cli, err := d.newClient()
+	if err != nil {
+		return DockerImageStatus{}, err
+	}
+	defer cli.Close()
+
+	if policy != PullPolicyAlways {
+		status, err := d.inspect(ctx, cli, ref)
+		if err == nil {
+			return status, nil
+		}
+		if !errdefs.IsNotFound(err) {
+			return DockerImageStatus{}, fmt.Errorf("failed to inspect image: %w", transient(err))
+		}
+		if policy == PullPolicyNever {
+			return DockerImageStatus{}, fmt.Errorf("pull policy is %s: %w", policy, &domain.NotFoundError{Kind: "image", ID: ref})
+		}
+	}
+
+	return d.pull(ctx, ref, platform)
+}
+
+// pull joins a pull of the same image already in flight, its span covers the
+// wait as well. The pull does not belong to any single caller, a caller
+// giving up only cancels it when nobody else is waiting for it.
+func (d *DockerImages) pull(ctx context.Context, ref string, platform string) (_ DockerImageStatus, err error) {
+	ctx, span := util.StartSpan(ctx, "docker image pull", attribute.String("image", ref), attribute.String("platform", platform))
+	defer util.EndSpan(span, &err)
+
+	key := ref + "|" + platform
+
+	d.mu.Lock()
+	call, ok := d.inflight[key]
+	if !ok {
+		pullCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &imagePull{done: make(chan struct{}), cancel: cancel}
+		d.inflight[key] = call
+
+		go func() {
+			call.status, call.err = d.doPull(pullCtx, ref, platform)
+			cancel()
+
+			d.mu.Lock()
+			if d.inflight[key] == call {
+				delete(d.inflight, key)
+			}
+			d.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	d.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.status, call.err
+	case <-ctx.Done():
+		d.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			// later callers start a new pull instead of joining the cancelled one
+			if d.inflight[key] == call {
+				delete(d.inflight, key)
+			}
+		}
+		d.mu.Unlock()
+		return DockerImageStatus{}, ctx.Err()
+	}
+}
+
+func (d *DockerImages) doPull(ctx context.Context, ref string, platform string) (DockerImageStatus, error) {
+	cli, err := d.newClient()
+	if err != nil {
+		return DockerImageStatus{}, err
+	}
+	defer cli.Close()
+
+	auth, err := d.registryAuth(ref)
+	if err != nil {
+		return DockerImageStatus{}, err
+	}
+
+	progress, err := cli.ImagePull(ctx, ref, image.PullOptions{Platform: platform, RegistryAuth: auth})
+	if err != nil {
+		return DockerImageStatus{}, fmt.Errorf("failed to pull image: %w", transient(err))
+	}
+	defer progress.Close()
+
+	// errors like denied access are reported inside the progress stream
+	dec := json.NewDecoder(progress)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return DockerImageStatus{}, fmt.Errorf("failed to read pull progress: %w", err)
+		}
+		if msg.Error != "" {
+			return DockerImageStatus{}, fmt.Errorf("failed to pull image: %s", msg.Error)
+		}
+	}
+
+	status, err := d.inspect(ctx, cli, ref)
+	if err != nil {
+		return DockerImageStatus{}, fmt.Errorf("failed to inspect pulled image: %w", err)
+	}
+	status.Pulled = true
+	status.PulledAt = time.Now()
+	return status, nil
+}
+
+func (d *DockerImages) inspect(ctx context.Context, cli imageClient, ref string) (DockerImageStatus, error) {
+	res, err := cli.ImageInspect(ctx, ref)
+	if err != nil {
+		return DockerImageStatus{}, err
+	}
+
+	status := DockerImageStatus{Ref: ref, ImageID: res.ID}
+	if len(res.RepoDigests) > 0 {
+		status.Digest = res.RepoDigests[0]
+	}
+	return status, nil
+}
+
+// registryAuth returns the encoded credentials for the registry of ref or an
+// empty string for anonymous pulls.
+func (d *DockerImages) registryAuth(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", domain.InvalidSpec("image reference %q: %w", ref, err)
+	}
+	host := reference.Domain(named)
+
+	auth, found, err := d.lookupAuth(host)
+	if err != nil || !found {
+		return "", err
+	}
+
+	encoded, err := registry.EncodeAuthConfig(auth)
+	if err != nil {
+		return "", fmt.Errorf("encoding registry auth: %w", err)
+	}
+	return encoded, nil
+}
+
+func (d *DockerImages) lookupAuth(host string) (registry.AuthConfig, bool, error) {
+	for _, a := range d.auths {
+		if registryHost(a.Registry) == host {
+			return registry.AuthConfig{
+				Username:      a.Username,
+				Password:      a.Password,
+				IdentityToken: a.IdentityToken,
+				ServerAddress: a.Registry,
+			}, true, nil
+		}
+	}
+
+	if d.configPath == "" {
+		return registry.AuthConfig{}, false, nil
+	}
+	b, err := os.ReadFile(d.configPath)
+	if os.IsNotExist(err) {
+		return registry.AuthConfig{}, false, nil
+	}
+	if err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("reading docker config: %w", err)
+	}
+
+	var config struct {
+		Auths       map[string]registry.AuthConfig `json:"auths"`
+		CredsStore  string                         `json:"credsStore"`
+		CredHelpers map[string]string              `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("parsing docker config: %w", err)
+	}
+
+	for server, helper := range config.CredHelpers {
+		if registryHost(server) == host {
+			return credentialHelper(helper, server)
+		}
+	}
+
+	for server, auth := range config.Auths {
+		if registryHost(server) != host {
+			continue
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return registry.AuthConfig{}, false, fmt.Errorf("decoding auth for %s: %w", server, err)
+			}
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+			auth.Auth = ""
+		}
+		if auth.Username == "" && auth.IdentityToken == "" && config.CredsStore != "" {
+			return credentialHelper(config.CredsStore, server)
+		}
+		auth.ServerAddress = server
+		return auth, true, nil
+	}
+
+	return registry.AuthConfig{}, false, nil
+}
+
+// credentialHelper runs docker-credential-<helper> the same way the docker
+// cli does.
+func credentialHelper(helper string, server string) (registry.AuthConfig, bool, error) {
+	var out bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("running credential helper %s: %w", helper, err)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &creds); err != nil {
+		return registry.AuthConfig{}, false, fmt.Errorf("parsing credential helper output: %w", err)
+	}
+
+	auth := registry.AuthConfig{ServerAddress: server}
+	if creds.Username == "<token>" {
+		auth.IdentityToken = creds.Secret
+	} else {
+		auth.Username, auth.Password = creds.Username, creds.Secret
+	}
+	return auth, true, nil
+}
+
+// registryHost normalizes docker config keys like https://index.docker.io/v1/
+// to the registry domain used in image references.
+func registryHost(server string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return host
+}
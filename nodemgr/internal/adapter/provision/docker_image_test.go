@@ -0,0 +1,309 @@
+package provision
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+// fakeImageClient serves images from memory. Pulls block until release is
+// closed when it is set.
+type fakeImageClient struct {
+	mu      sync.Mutex
+	present map[string]bool
+	pulls   int
+	auths   []string
+	// pullErr is reported inside the progress stream like the daemon does
+	pullErr string
+	release chan struct{}
+	started chan struct{}
+}
+
+func newFakeImageClient(present ...string) *fakeImageClient {
+	c := &fakeImageClient{present: map[string]bool{}, started: make(chan struct{}, 16)}
+	for _, ref := range present {
+		c.present[ref] = true
+	}
+	return c
+}
+
+func (c *fakeImageClient) ImageInspect(ctx context.Context, ref string, _ ...client.ImageInspectOption) (image.InspectResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.present[ref] {
+		return image.InspectResponse{}, errdefs.NotFound(fmt.Errorf("no such image: %s", ref))
+	}
+	return image.InspectResponse{ID: "sha256:" + ref, RepoDigests: []string{ref + "@sha256:abc"}}, nil
+}
+
+func (c *fakeImageClient) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	c.mu.Lock()
+	c.pulls++
+	c.auths = append(c.auths, options.RegistryAuth)
+	release := c.release
+	c.mu.Unlock()
+	c.started <- struct{}{}
+
+	if release != nil {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if c.pullErr != "" {
+		return io.NopCloser(strings.NewReader(`{"status":"Pulling"}` + "\n" + `{"error":"` + c.pullErr + `"}` + "\n")), nil
+	}
+	c.mu.Lock()
+	c.present[ref] = true
+	c.mu.Unlock()
+	return io.NopCloser(strings.NewReader(`{"status":"Pulling"}` + "\n" + `{"status":"Downloaded"}` + "\n")), nil
+}
+
+func (c *fakeImageClient) Close() error {
+	return nil
+}
+
+func (c *fakeImageClient) pullCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pulls
+}
+
+func newTestDockerImages(t *testing.T, cli *fakeImageClient, auths []DockerRegistryAuth) *DockerImages {
+	t.Helper()
+
+	d := NewDockerImages("unix:///nonexistent.sock", filepath.Join(t.TempDir(), "config.json"), auths)
+	d.newClient = func() (imageClient, error) { return cli, nil }
+	return d
+}
+
+func TestDockerImagesPullPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    PullPolicy
+		present   bool
+		wantPulls int
+		wantErr   domain.ErrorCode
+	}{
+		{"always pulls a present image", PullPolicyAlways, true, 1, ""},
+		{"always pulls a missing image", PullPolicyAlways, false, 1, ""},
+		{"if-not-present keeps a present image", PullPolicyIfNotPresent, true, 0, ""},
+		{"if-not-present pulls a missing image", PullPolicyIfNotPresent, false, 1, ""},
+		{"if-not-present by default", "", true, 0, ""},
+		{"never keeps a present image", PullPolicyNever, true, 0, ""},
+		{"never fails on a missing image", PullPolicyNever, false, 0, domain.ErrorCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := newFakeImageClient()
+			if tt.present {
+				cli.present["alpine:3"] = true
+			}
+			d := newTestDockerImages(t, cli, nil)
+
+			status, err := d.Ensure(context.Background(), "alpine:3", "", tt.policy)
+			if domain.Code(err) != tt.wantErr && !(tt.wantErr == "" && err == nil) {
+				t.Fatalf("err = %v, want code %q", err, tt.wantErr)
+			}
+			if got := cli.pullCount(); got != tt.wantPulls {
+				t.Errorf("pulls = %d, want %d", got, tt.wantPulls)
+			}
+			if err != nil {
+				return
+			}
+
+			if status.Pulled != (tt.wantPulls == 1) || status.PulledAt.IsZero() == status.Pulled {
+				t.Errorf("status = %+v, want pulled %v", status, tt.wantPulls == 1)
+			}
+			if status.ImageID != "sha256:alpine:3" || status.Digest != "alpine:3@sha256:abc" {
+				t.Errorf("status = %+v, want the inspected image", status)
+			}
+		})
+	}
+}
+
+func TestDockerImagesPullErrorInProgress(t *testing.T) {
+	cli := newFakeImageClient()
+	cli.pullErr = "pull access denied"
+	d := newTestDockerImages(t, cli, nil)
+
+	_, err := d.Ensure(context.Background(), "private/app:1", "", PullPolicyAlways)
+	if err == nil || !strings.Contains(err.Error(), "pull access denied") {
+		t.Errorf("err = %v, want the error of the progress stream", err)
+	}
+}
+
+// waitPullStarted fails unless n pulls start in time.
+func waitPullStarted(t *testing.T, cli *fakeImageClient, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-cli.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("pull did not start")
+		}
+	}
+}
+
+// waitWaiters waits until the pull of ref has n waiters.
+func waitWaiters(t *testing.T, d *DockerImages, ref string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		d.mu.Lock()
+		call, ok := d.inflight[ref+"|"]
+		waiters := 0
+		if ok {
+			waiters = call.waiters
+		}
+		d.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("pull of %s did not get %d waiters", ref, n)
+}
+
+func TestDockerImagesSharedPull(t *testing.T) {
+	cli := newFakeImageClient()
+	cli.release = make(chan struct{})
+	d := newTestDockerImages(t, cli, nil)
+
+	type result struct {
+		status DockerImageStatus
+		err    error
+	}
+	results := make(chan result, 3)
+	for range 3 {
+		go func() {
+			status, err := d.Ensure(context.Background(), "alpine:3", "", PullPolicyAlways)
+			results <- result{status, err}
+		}()
+	}
+	waitPullStarted(t, cli, 1)
+	waitWaiters(t, d, "alpine:3", 3)
+	close(cli.release)
+
+	var first DockerImageStatus
+	for i := range 3 {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("ensure: %v", r.err)
+		}
+		if i == 0 {
+			first = r.status
+		} else if r.status != first {
+			t.Errorf("status = %+v, want the shared %+v", r.status, first)
+		}
+	}
+	if got := cli.pullCount(); got != 1 {
+		t.Errorf("pulls = %d, want one shared pull", got)
+	}
+
+	// a finished pull is not joined, the next one starts afresh
+	cli.release = nil
+	if _, err := d.Ensure(context.Background(), "alpine:3", "", PullPolicyAlways); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if got := cli.pullCount(); got != 2 {
+		t.Errorf("pulls = %d, want a second pull", got)
+	}
+}
+
+func TestDockerImagesPullCancelledWithLastWaiter(t *testing.T) {
+	cli := newFakeImageClient()
+	cli.release = make(chan struct{})
+	d := newTestDockerImages(t, cli, nil)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { _, err := d.Ensure(ctx1, "alpine:3", "", PullPolicyAlways); errs <- err }()
+	waitPullStarted(t, cli, 1)
+	go func() { _, err := d.Ensure(ctx2, "alpine:3", "", PullPolicyAlways); errs <- err }()
+	waitWaiters(t, d, "alpine:3", 2)
+
+	// one waiter giving up leaves the pull running for the other
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want cancelled", err)
+	}
+	waitWaiters(t, d, "alpine:3", 1)
+
+	cancel2()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want cancelled", err)
+	}
+	d.mu.Lock()
+	_, inflight := d.inflight["alpine:3|"]
+	d.mu.Unlock()
+	if inflight {
+		t.Error("cancelled pull is still joinable")
+	}
+
+	// the abandoned pull was cancelled, so a new caller pulls again
+	close(cli.release)
+	if _, err := d.Ensure(context.Background(), "alpine:3", "", PullPolicyAlways); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if got := cli.pullCount(); got != 2 {
+		t.Errorf("pulls = %d, want a new pull after cancellation", got)
+	}
+}
+
+func TestDockerImagesRegistryAuth(t *testing.T) {
+	cli := newFakeImageClient()
+	d := newTestDockerImages(t, cli, []DockerRegistryAuth{{Registry: "registry.example.com", Username: "ci", Password: "secret"}})
+
+	config := `{"auths": {"https://index.docker.io/v1/": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("hub:token")) + `"}}}`
+	if err := os.WriteFile(d.configPath, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"registry.example.com/team/app:1", "library/alpine:3", "ghcr.io/org/tool:2"} {
+		if _, err := d.Ensure(context.Background(), ref, "", PullPolicyAlways); err != nil {
+			t.Fatalf("ensure %s: %v", ref, err)
+		}
+	}
+
+	want := []registry.AuthConfig{
+		{Username: "ci", Password: "secret", ServerAddress: "registry.example.com"},
+		{Username: "hub", Password: "token", ServerAddress: "https://index.docker.io/v1/"},
+		{},
+	}
+	for i, encoded := range cli.auths {
+		if encoded == "" {
+			if want[i] != (registry.AuthConfig{}) {
+				t.Errorf("pull %d was anonymous, want %+v", i, want[i])
+			}
+			continue
+		}
+		got, err := registry.DecodeAuthConfig(encoded)
+		if err != nil {
+			t.Fatalf("decoding auth of pull %d: %v", i, err)
+		}
+		if *got != want[i] {
+			t.Errorf("auth of pull %d = %+v, want %+v", i, *got, want[i])
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/go-connections/nat"
+	"github.com/go-playground/validator/v10"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// DockerNativeProvider creates containers through the Docker Engine API
+// directly, without pulumi stacks. It accepts the same DockerArgs as
+// DockerProvider and produces nodes usable by the same exec and lifecycle
+// adapters.
+type DockerNativeProvider struct {
+	mu         sync.Mutex
+	dockerHost string
+	images     *DockerImages
+	containers map[domain.NodeID]string
+	validate   *validator.Validate
+}
+
+func NewDockerNativeProvider(dockerHost string, images *DockerImages) *DockerNativeProvider {
+	return &DockerNativeProvider{
+		dockerHost: dockerHost,
+		images:     images,
+		containers: make(map[domain.NodeID]string),
+		validate:   validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+func (p *DockerNativeProvider) ID() domain.ProviderID {
+	return domain.ProviderID("docker-native")
+}
+
+func (p *DockerNativeProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error) {
+	args, err := util.DecodeExtraTo[DockerArgs](spec.Extra)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+
+	err = p.validate.Struct(args)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate args: %w", err)}
+	}
+
+	if err := args.defaults(); err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate args: %w", err)}
+	}
+
+	config, hostConfig, err := nativeContainerConfig(args, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	platform, err := parsePlatform(args.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	img, err := p.images.Ensure(ctx, args.Image, args.Platform, args.PullPolicy)
+	if err != nil {
+		return nil, err
+	}
+	config.Image = img.ImageID
+
+	var networking *network.NetworkingConfig
+	if len(args.Networks) > 0 {
+		hostConfig.NetworkMode = container.NetworkMode(args.Networks[0])
+		networking = &network.NetworkingConfig{
+			EndpointsConfig: map[string]*network.EndpointSettings{
+				args.Networks[0]: {},
+			},
+		}
+	}
+
+	created, err := cli.ContainerCreate(ctx, config, hostConfig, networking, platform, args.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", transient(err))
+	}
+
+	rollback := func() {
+		// ctx may already be cancelled, cleanup must not depend on it
+		_ = cli.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	}
+
+	// older engines accept a single network on create, the rest are connected
+	for _, name := range args.Networks[min(1, len(args.Networks)):] {
+		if err := cli.NetworkConnect(ctx, name, created.ID, nil); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to connect network %q: %w", name, transient(err))
+		}
+	}
+
+	if err := cli.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		rollback()
+		return nil, fmt.Errorf("failed to start container: %w", transient(err))
+	}
+
+	if args.Bootstrap != nil {
+		script := util.RenderBootstrapScript(args.Bootstrap, args.User)
+		if err := runBootstrap(ctx, p.dockerHost, created.ID, script); err != nil {
+			rollback()
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	p.containers[nodeID] = created.ID
+	p.mu.Unlock()
+
+	meta := imageMeta(args, img)
+	meta["docker_host"] = p.dockerHost
+	meta["container_id"] = created.ID
+
+	node := domain.Node{
+		NodeID:     nodeID,
+		ProviderID: p.ID(),
+		State:      domain.NodeStateRunning,
+		Meta:       meta,
+		Cap: map[domain.Cap]bool{
+			"exec:docker":      true,
+			"lifecycle:docker": true,
+		},
+	}
+
+	return &node, nil
+}
+
+func (p *DockerNativeProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
+	p.mu.Lock()
+	containerID, ok := p.containers[nodeID]
+	p.mu.Unlock()
+
+	if !ok {
+		return &domain.NotFoundError{Kind: "container", ID: string(nodeID)}
+	}
+
+	cli, err := p.client()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	err = cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+	if err != nil && !errdefs.IsNotFound(err) {
+		return fmt.Errorf("failed to remove container: %w", transient(err))
+	}
+
+	p.mu.Lock()
+	delete(p.containers, nodeID)
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *DockerNativeProvider) PrefetchImage(ctx context.Context, spec domain.NodeSpec) error {
+	return prefetchImage(ctx, p.images, spec)
+}
+
+func (p *DockerNativeProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	return dockerSpecResources(spec)
+}
+
+func (p *DockerNativeProvider) client() (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(), client.WithHost(p.dockerHost))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return cli, nil
+}
+
+func nativeContainerConfig(args DockerArgs, nodeID domain.NodeID) (*container.Config, *container.HostConfig, error) {
+	config := &container.Config{
+		Image:      args.Image,
+		User:       args.User,
+		Cmd:        []string(args.Command),
+		Entrypoint: []string(args.Entrypoint),
+		Env:        args.Env,
+		WorkingDir: args.WorkingDir,
+		Labels:     args.labels(nodeID),
+		OpenStdin:  args.StdinOpen,
+		Tty:        args.Tty,
+	}
+
+	hostConfig := &container.HostConfig{
+		Privileged: args.Privileged,
+		CapAdd:     args.CapAdd,
+		CapDrop:    args.CapDrop,
+		ShmSize:    int64(args.ShmSizeMB) << 20,
+		Resources: container.Resources{
+			Memory:   int64(args.MemoryMB) << 20,
+			NanoCPUs: int64(args.CPUs * 1e9),
+		},
+	}
+
+	for _, m := range args.Mounts {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.Type(m.kind()),
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+
+	for _, u := range args.Ulimits {
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &container.Ulimit{
+			Name: u.Name,
+			Soft: int64(u.Soft),
+			Hard: int64(u.Hard),
+		})
+	}
+
+	if len(args.Ports) > 0 {
+		config.ExposedPorts = nat.PortSet{}
+		hostConfig.PortBindings = nat.PortMap{}
+	}
+	for _, p := range args.Ports {
+		proto := p.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+
+		port, err := nat.NewPort(proto, strconv.Itoa(p.Internal))
+		if err != nil {
+			return nil, nil, domain.InvalidSpec("port %d/%s: %w", p.Internal, proto, err)
+		}
+
+		binding := nat.PortBinding{HostIP: p.IP}
+		if p.External > 0 {
+			binding.HostPort = strconv.Itoa(p.External)
+		}
+
+		config.ExposedPorts[port] = struct{}{}
+		hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], binding)
+	}
+
+	return config, hostConfig, nil
+}
+
+// parsePlatform accepts os/arch[/variant] as used by docker --platform.
+func parsePlatform(platform string) (*ocispec.Platform, error) {
+	if platform == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, domain.InvalidSpec("platform %q, expected os/arch[/variant]", platform)
+	}
+
+	p := &ocispec.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+var (
+	_ port.NodeProvider          = (*DockerNativeProvider)(nil)
+	_ port.NodeImagePrefetcher   = (*DockerNativeProvider)(nil)
+	_ port.NodeResourceEstimator = (*DockerNativeProvider)(nil)
+)
@@ -0,0 +1,719 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/ssh"
+)
+
+type LibvirtArgs struct {
+	Name       string           `mapstructure:"name"`
+	User       string           `mapstructure:"user" validate:"required"`
+	Image      string           `mapstructure:"image" validate:"required"`
+	ImageType  domain.ImageType `mapstructure:"image_type" validate:"required,oneof=qcow2 iso"`
+	CPUs       int              `mapstructure:"cpus,omitempty" validate:"gte=0"`
+	MemoryMB   int              `mapstructure:"memory_mb,omitempty" validate:"gte=0"`
+	DiskMB     int              `mapstructure:"disk_mb,omitempty" validate:"gte=0"`
+	DiskGB     int              `mapstructure:"disk_gb,omitempty" validate:"gte=0"`
+	Network    string           `mapstructure:"network,omitempty"`
+	Pool       string           `mapstructure:"pool,omitempty"`
+	DomainType string           `mapstructure:"domain_type,omitempty"`
+
+	Bootstrap *domain.NodeBootstrap `mapstructure:"bootstrap,omitempty"`
+}
+
+type libvirtNode struct {
+	domain  string
+	volumes []string
+	pool    string
+	keyPath string
+}
+
+// libvirtAddressTimeout bounds waiting for the DHCP lease of a new domain.
+const libvirtAddressTimeout = 5 * time.Minute
+
+type LibvirtProvider struct {
+	uri string
+	// keyDir holds the private login keys of the nodes, only their paths
+	// end up in the node meta
+	keyDir   string
+	validate *validator.Validate
+	// addressTimeout bounds waiting for the DHCP lease of a new domain
+	addressTimeout time.Duration
+
+	mu    sync.Mutex
+	conn  *libvirt.Libvirt
+	nodes map[domain.NodeID]libvirtNode
+}
+
+func NewLibvirtProvider(uri string, keyDir string) *LibvirtProvider {
+	if keyDir == "" {
+		keyDir = filepath.Join(os.TempDir(), "nodemgr-libvirt-keys")
+	}
+
+	return &LibvirtProvider{
+		uri:            uri,
+		keyDir:         keyDir,
+		validate:       validator.New(validator.WithRequiredStructEnabled()),
+		addressTimeout: libvirtAddressTimeout,
+		nodes:          make(map[domain.NodeID]libvirtNode),
+	}
+}
+
+func (p *LibvirtProvider) ID() domain.ProviderID {
+	return domain.ProviderID("libvirt")
+}
+
+func (a *LibvirtArgs) defaults() {
+	if a.Name == "" {
+		a.Name = fmt.Sprintf("node-%s", uuid.New().String()[:8])
+	}
+	if a.CPUs == 0 {
+		a.CPUs = 1
+	}
+	if a.MemoryMB == 0 {
+		a.MemoryMB = 512
+	}
+	if a.Network == "" {
+		a.Network = "default"
+	}
+	if a.Pool == "" {
+		a.Pool = "default"
+	}
+	if a.DomainType == "" {
+		a.DomainType = "kvm"
+	}
+}
+
+// SpecResources charges the size of the domain, including the defaults.
+func (p *LibvirtProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	args, err := util.DecodeExtraTo[LibvirtArgs](spec.Extra)
+	if err != nil {
+		return domain.Resources{}, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+	args.defaults()
+	return domain.Resources{Nodes: 1, CPUs: args.CPUs, MemoryMB: args.MemoryMB}, nil
+}
+
+func (p *LibvirtProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (_ *domain.Node, err error) {
+	args, err := util.DecodeExtraTo[LibvirtArgs](spec.Extra)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+
+	err = p.validate.Struct(args)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate args: %w", err)}
+	}
+
+	args.defaults()
+
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := newLibvirtKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := conn.StoragePoolLookupByName(args.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("looking up storage pool %q: %w", args.Pool, err)
+	}
+
+	image, err := conn.StorageVolLookupByName(pool, args.Image)
+	if err != nil {
+		return nil, fmt.Errorf("looking up image volume %q: %w", args.Image, err)
+	}
+	imagePath, err := conn.StorageVolGetPath(image)
+	if err != nil {
+		return nil, fmt.Errorf("reading image path: %w", err)
+	}
+
+	// only what this call created is rolled back, a domain or volume which
+	// already had the name belongs to someone else
+	var rb libvirtRollback
+	defer func() {
+		if err != nil {
+			rb.run(conn)
+		}
+	}()
+
+	keyPath, err := p.storeKey(nodeID, keys.clientPrivate)
+	if err != nil {
+		return nil, err
+	}
+	rb.keyPath = keyPath
+
+	diskPath, err := p.createRootDisk(ctx, conn, pool, image, imagePath, args, &rb)
+	if err != nil {
+		return nil, err
+	}
+
+	seedPath, err := p.createSeed(conn, pool, nodeID, args, keys, &rb)
+	if err != nil {
+		return nil, err
+	}
+
+	domainXML, err := libvirtDomainXML(args, diskPath, seedPath, imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dom, err := conn.DomainDefineXML(domainXML)
+	if err != nil {
+		return nil, fmt.Errorf("defining domain: %w", err)
+	}
+	rb.dom = &dom
+
+	if err := conn.DomainCreate(dom); err != nil {
+		return nil, fmt.Errorf("starting domain: %w", err)
+	}
+
+	addr, err := p.waitForAddress(ctx, conn, dom)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.nodes[nodeID] = libvirtNode{domain: args.Name, volumes: rb.volumeNames(), pool: args.Pool, keyPath: keyPath}
+	p.mu.Unlock()
+
+	node := domain.Node{
+		NodeID:     nodeID,
+		ProviderID: p.ID(),
+		State:      domain.NodeStateRunning,
+		Meta: map[string]any{
+			"libvirt_uri":    p.uri,
+			"libvirt_domain": args.Name,
+			"ssh_host":       addr,
+			"ssh_user":       args.User,
+			"ssh_key_path":   keyPath,
+			"ssh_host_key":   keys.hostPublic,
+		},
+		Cap: map[domain.Cap]bool{
+			"exec:ssh": true,
+		},
+	}
+
+	return &node, nil
+}
+
+func (p *LibvirtProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
+	p.mu.Lock()
+	state, ok := p.nodes[nodeID]
+	p.mu.Unlock()
+
+	if !ok {
+		return &domain.NotFoundError{Kind: "libvirt domain", ID: string(nodeID)}
+	}
+
+	conn, err := p.connect()
+	if err != nil {
+		return err
+	}
+
+	if err := p.cleanup(conn, state); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	delete(p.nodes, nodeID)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// storeKey writes the private login key of the node to a file only nodemgr
+// can read, node meta is shown to every viewer of the tenant.
+func (p *LibvirtProvider) storeKey(nodeID domain.NodeID, key string) (string, error) {
+	if err := os.MkdirAll(p.keyDir, 0o700); err != nil {
+		return "", fmt.Errorf("creating key directory: %w", err)
+	}
+	path := filepath.Join(p.keyDir, string(nodeID))
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		return "", fmt.Errorf("writing ssh key: %w", err)
+	}
+	return path, nil
+}
+
+func (p *LibvirtProvider) connect() (*libvirt.Libvirt, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && p.conn.IsConnected() {
+		return p.conn, nil
+	}
+
+	uri, err := url.Parse(p.uri)
+	if err != nil {
+		return nil, fmt.Errorf("parsing libvirt uri: %w", err)
+	}
+
+	conn, err := libvirt.ConnectToURI(uri)
+	if err != nil {
+		return nil, &domain.ProviderUnavailableError{Err: fmt.Errorf("connecting to libvirt: %w", err)}
+	}
+	p.conn = conn
+
+	return conn, nil
+}
+
+func (p *LibvirtProvider) createRootDisk(ctx context.Context, conn *libvirt.Libvirt, pool libvirt.StoragePool, image libvirt.StorageVol, imagePath string, args LibvirtArgs, rb *libvirtRollback) (_ string, err error) {
+	_, span := util.StartSpan(ctx, "libvirt create root disk", attribute.String("image", args.Image))
+	defer util.EndSpan(span, &err)
+
+	capacity := uint64(args.DiskGB) << 30
+	if capacity == 0 {
+		capacity = uint64(args.DiskMB) << 20
+	}
+
+	vol := libvirtVolume{
+		Name:     args.Name + "-root.qcow2",
+		Capacity: libvirtCapacity{Unit: "bytes"},
+		Format:   libvirtFormat{Type: "qcow2"},
+	}
+
+	switch args.ImageType {
+	case domain.ImageTypeQCOW2:
+		_, imageCapacity, _, err := conn.StorageVolGetInfo(image)
+		if err != nil {
+			return "", fmt.Errorf("reading image info: %w", err)
+		}
+		// a disk backed by the image can not be smaller than the image itself
+		vol.Capacity.Value = max(capacity, imageCapacity)
+		vol.BackingStore = &libvirtBackingStore{Path: imagePath, Format: libvirtFormat{Type: "qcow2"}}
+	case domain.ImageTypeISO:
+		if capacity == 0 {
+			capacity = 10 << 30
+		}
+		vol.Capacity.Value = capacity
+	}
+
+	volXML, err := xml.Marshal(vol)
+	if err != nil {
+		return "", fmt.Errorf("rendering volume xml: %w", err)
+	}
+
+	disk, err := conn.StorageVolCreateXML(pool, string(volXML), 0)
+	if err != nil {
+		return "", fmt.Errorf("creating root disk: %w", err)
+	}
+	rb.volumes = append(rb.volumes, disk)
+
+	return conn.StorageVolGetPath(disk)
+}
+
+func (p *LibvirtProvider) createSeed(conn *libvirt.Libvirt, pool libvirt.StoragePool, nodeID domain.NodeID, args LibvirtArgs, keys libvirtKeys, rb *libvirtRollback) (string, error) {
+	userData, err := libvirtUserData(args, keys)
+	if err != nil {
+		return "", err
+	}
+	metaData := fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", nodeID, args.Name)
+
+	seed, err := buildSeedISO(userData, metaData)
+	if err != nil {
+		return "", err
+	}
+
+	vol := libvirtVolume{
+		Name:     args.Name + "-cidata.iso",
+		Capacity: libvirtCapacity{Unit: "bytes", Value: uint64(len(seed))},
+		Format:   libvirtFormat{Type: "raw"},
+	}
+	volXML, err := xml.Marshal(vol)
+	if err != nil {
+		return "", fmt.Errorf("rendering volume xml: %w", err)
+	}
+
+	seedVol, err := conn.StorageVolCreateXML(pool, string(volXML), 0)
+	if err != nil {
+		return "", fmt.Errorf("creating cloud-init volume: %w", err)
+	}
+	rb.volumes = append(rb.volumes, seedVol)
+
+	if err := conn.StorageVolUpload(seedVol, bytes.NewReader(seed), 0, uint64(len(seed)), 0); err != nil {
+		return "", fmt.Errorf("uploading cloud-init volume: %w", err)
+	}
+
+	return conn.StorageVolGetPath(seedVol)
+}
+
+func (p *LibvirtProvider) waitForAddress(ctx context.Context, conn *libvirt.Libvirt, dom libvirt.Domain) (_ string, err error) {
+	ctx, span := util.StartSpan(ctx, "libvirt wait for address", attribute.String("libvirt_domain", dom.Name))
+	defer util.EndSpan(span, &err)
+
+	// a guest that never gets a lease would otherwise hang the operation
+	ctx, cancel := context.WithTimeout(ctx, p.addressTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		ifaces, err := conn.DomainInterfaceAddresses(dom, uint32(libvirt.DomainInterfaceAddressesSrcLease), 0)
+		if err != nil {
+			return "", fmt.Errorf("reading domain addresses: %w", err)
+		}
+		for _, iface := range ifaces {
+			for _, addr := range iface.Addrs {
+				if addr.Type == int32(libvirt.IPAddrTypeIpv4) {
+					return addr.Addr, nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return "", &domain.TimeoutError{Op: "waiting for domain address", Err: ctx.Err()}
+			}
+			return "", fmt.Errorf("waiting for domain address: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// libvirtRollback holds what a Provision call created so far.
+type libvirtRollback struct {
+	dom     *libvirt.Domain
+	volumes []libvirt.StorageVol
+	keyPath string
+}
+
+func (rb *libvirtRollback) volumeNames() []string {
+	names := make([]string, len(rb.volumes))
+	for i, vol := range rb.volumes {
+		names[i] = vol.Name
+	}
+	return names
+}
+
+// run removes the created domain and volumes, failures are only logged as the
+// provision error is what the caller gets.
+func (rb *libvirtRollback) run(conn *libvirt.Libvirt) {
+	if rb.dom != nil {
+		// destroying an already stopped domain fails, undefine still has to run
+		_ = conn.DomainDestroy(*rb.dom)
+		if err := conn.DomainUndefineFlags(*rb.dom, libvirt.DomainUndefineNvram); err != nil {
+			slog.Error("rolling back libvirt domain", "libvirt_domain", rb.dom.Name, "err", err)
+		}
+	}
+	for _, vol := range rb.volumes {
+		if err := conn.StorageVolDelete(vol, libvirt.StorageVolDeleteNormal); err != nil {
+			slog.Error("rolling back libvirt volume", "volume", vol.Name, "err", err)
+		}
+	}
+	if rb.keyPath != "" {
+		if err := os.Remove(rb.keyPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Error("rolling back ssh key", "path", rb.keyPath, "err", err)
+		}
+	}
+}
+
+// cleanup removes everything that belongs to a provisioned node, missing
+// pieces are skipped.
+func (p *LibvirtProvider) cleanup(conn *libvirt.Libvirt, state libvirtNode) error {
+	var errs []error
+
+	if dom, err := conn.DomainLookupByName(state.domain); err == nil {
+		// destroying an already stopped domain fails, undefine still has to run
+		_ = conn.DomainDestroy(dom)
+		if err := conn.DomainUndefineFlags(dom, libvirt.DomainUndefineNvram); err != nil {
+			errs = append(errs, fmt.Errorf("undefining domain: %w", err))
+		}
+	}
+
+	pool, err := conn.StoragePoolLookupByName(state.pool)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("looking up storage pool: %w", err))...)
+	}
+	for _, name := range state.volumes {
+		vol, err := conn.StorageVolLookupByName(pool, name)
+		if err != nil {
+			continue
+		}
+		if err := conn.StorageVolDelete(vol, libvirt.StorageVolDeleteNormal); err != nil {
+			errs = append(errs, fmt.Errorf("deleting volume %q: %w", name, err))
+		}
+	}
+
+	if err := os.Remove(state.keyPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("deleting ssh key: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+type libvirtKeys struct {
+	clientPrivate string
+	clientPublic  string
+	hostPrivate   string
+	hostPublic    string
+}
+
+// newLibvirtKeys generates per node login key and host key, the host key is
+// injected through cloud-init so it can be pinned without trust on first use.
+func newLibvirtKeys() (libvirtKeys, error) {
+	var keys libvirtKeys
+
+	gen := func() (string, string, error) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "")
+		if err != nil {
+			return "", "", err
+		}
+		sshPub, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			return "", "", err
+		}
+		return string(pem.EncodeToMemory(block)), strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))), nil
+	}
+
+	var err error
+	if keys.clientPrivate, keys.clientPublic, err = gen(); err != nil {
+		return keys, fmt.Errorf("generating ssh key: %w", err)
+	}
+	if keys.hostPrivate, keys.hostPublic, err = gen(); err != nil {
+		return keys, fmt.Errorf("generating ssh host key: %w", err)
+	}
+	return keys, nil
+}
+
+func libvirtUserData(args LibvirtArgs, keys libvirtKeys) ([]byte, error) {
+	config := map[string]any{
+		"users": []map[string]any{
+			{
+				"name":                args.User,
+				"sudo":                "ALL=(ALL) NOPASSWD:ALL",
+				"shell":               "/bin/bash",
+				"ssh_authorized_keys": []string{keys.clientPublic},
+			},
+		},
+		"ssh_keys": map[string]string{
+			"ed25519_private": keys.hostPrivate,
+			"ed25519_public":  keys.hostPublic,
+		},
+		"ssh_deletekeys": true,
+	}
+
+	if b := args.Bootstrap; b != nil {
+		user := config["users"].([]map[string]any)[0]
+		user["ssh_authorized_keys"] = append([]string{keys.clientPublic}, b.AuthorizedKeys...)
+
+		if len(b.Packages) > 0 {
+			config["packages"] = b.Packages
+		}
+		if len(b.Files) > 0 {
+			var files []map[string]string
+			for _, f := range b.Files {
+				file := map[string]string{"path": f.Path, "content": f.Content}
+				if f.Permissions != "" {
+					file["permissions"] = f.Permissions
+				}
+				if f.Owner != "" {
+					file["owner"] = f.Owner
+				}
+				files = append(files, file)
+			}
+			config["write_files"] = files
+		}
+		if len(b.RunCmd) > 0 {
+			config["runcmd"] = b.RunCmd
+		}
+	}
+
+	// JSON is a subset of YAML, so it is a valid cloud-config body
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("rendering cloud-config: %w", err)
+	}
+	return append([]byte("#cloud-config\n"), body...), nil
+}
+
+func buildSeedISO(userData []byte, metaData string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "nodemgr-cidata-")
+	if err != nil {
+		return nil, fmt.Errorf("creating seed dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "user-data"), userData, 0o600); err != nil {
+		return nil, fmt.Errorf("writing user-data: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta-data"), []byte(metaData), 0o600); err != nil {
+		return nil, fmt.Errorf("writing meta-data: %w", err)
+	}
+
+	var tool string
+	for _, candidate := range []string{"genisoimage", "mkisofs", "xorrisofs"} {
+		if path, err := exec.LookPath(candidate); err == nil {
+			tool = path
+			break
+		}
+	}
+	if tool == "" {
+		return nil, fmt.Errorf("building cloud-init seed requires genisoimage, mkisofs or xorrisofs")
+	}
+
+	out := filepath.Join(dir, "cidata.iso")
+	cmd := exec.Command(tool, "-output", out, "-volid", "cidata", "-joliet", "-rock",
+		filepath.Join(dir, "user-data"), filepath.Join(dir, "meta-data"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("building cloud-init seed: %w: %s", err, output)
+	}
+
+	return os.ReadFile(out)
+}
+
+type libvirtVolume struct {
+	XMLName      xml.Name             `xml:"volume"`
+	Name         string               `xml:"name"`
+	Capacity     libvirtCapacity      `xml:"capacity"`
+	Format       libvirtFormat        `xml:"target>format"`
+	BackingStore *libvirtBackingStore `xml:"backingStore,omitempty"`
+}
+
+type libvirtCapacity struct {
+	Unit  string `xml:"unit,attr"`
+	Value uint64 `xml:",chardata"`
+}
+
+type libvirtFormat struct {
+	Type string `xml:"type,attr"`
+}
+
+type libvirtBackingStore struct {
+	Path   string        `xml:"path"`
+	Format libvirtFormat `xml:"format"`
+}
+
+type libvirtDomain struct {
+	XMLName xml.Name        `xml:"domain"`
+	Type    string          `xml:"type,attr"`
+	Name    string          `xml:"name"`
+	Memory  libvirtCapacity `xml:"memory"`
+	VCPU    int             `xml:"vcpu"`
+	OS      struct {
+		Type  string `xml:"type"`
+		Boots []struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"boot"`
+	} `xml:"os"`
+	Devices struct {
+		Disks []libvirtDisk `xml:"disk"`
+		Iface struct {
+			Type   string `xml:"type,attr"`
+			Source struct {
+				Network string `xml:"network,attr"`
+			} `xml:"source"`
+			Model libvirtFormat `xml:"model"`
+		} `xml:"interface"`
+		Serial struct {
+			Type   string `xml:"type,attr"`
+			Target struct {
+				Port int `xml:"port,attr"`
+			} `xml:"target"`
+		} `xml:"serial"`
+	} `xml:"devices"`
+}
+
+type libvirtDisk struct {
+	Type   string `xml:"type,attr"`
+	Device string `xml:"device,attr"`
+	Driver struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"driver"`
+	Source struct {
+		File string `xml:"file,attr"`
+	} `xml:"source"`
+	Target struct {
+		Dev string `xml:"dev,attr"`
+		Bus string `xml:"bus,attr"`
+	} `xml:"target"`
+	ReadOnly *struct{} `xml:"readonly"`
+}
+
+func newLibvirtDisk(device string, format string, file string, dev string, bus string) libvirtDisk {
+	var disk libvirtDisk
+	disk.Type = "file"
+	disk.Device = device
+	disk.Driver.Name = "qemu"
+	disk.Driver.Type = format
+	disk.Source.File = file
+	disk.Target.Dev = dev
+	disk.Target.Bus = bus
+	if device == "cdrom" {
+		disk.ReadOnly = &struct{}{}
+	}
+	return disk
+}
+
+func libvirtDomainXML(args LibvirtArgs, diskPath string, seedPath string, imagePath string) (string, error) {
+	var dom libvirtDomain
+	dom.Type = args.DomainType
+	dom.Name = args.Name
+	dom.Memory = libvirtCapacity{Unit: "MiB", Value: uint64(args.MemoryMB)}
+	dom.VCPU = args.CPUs
+	dom.OS.Type = "hvm"
+
+	dom.Devices.Disks = []libvirtDisk{
+		newLibvirtDisk("disk", "qcow2", diskPath, "vda", "virtio"),
+		newLibvirtDisk("cdrom", "raw", seedPath, "sda", "sata"),
+	}
+	boots := []string{"hd"}
+	if args.ImageType == domain.ImageTypeISO {
+		dom.Devices.Disks = append(dom.Devices.Disks, newLibvirtDisk("cdrom", "raw", imagePath, "sdb", "sata"))
+		boots = []string{"hd", "cdrom"}
+	}
+	for _, dev := range boots {
+		dom.OS.Boots = append(dom.OS.Boots, struct {
+			Dev string `xml:"dev,attr"`
+		}{Dev: dev})
+	}
+
+	dom.Devices.Iface.Type = "network"
+	dom.Devices.Iface.Source.Network = args.Network
+	dom.Devices.Iface.Model.Type = "virtio"
+	dom.Devices.Serial.Type = "pty"
+
+	out, err := xml.MarshalIndent(dom, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("rendering domain xml: %w", err)
+	}
+	return string(out), nil
+}
+
+var (
+	_ port.NodeProvider          = (*LibvirtProvider)(nil)
+	_ port.NodeResourceEstimator = (*LibvirtProvider)(nil)
+)
@@ -0,0 +1,352 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/util"
+
+	"github.com/digitalocean/go-libvirt"
+	"github.com/google/uuid"
+)
+
+// The tests run against the mock driver of a local libvirtd, which comes with
+// the domain "test" and the storage pool "default-pool".
+const libvirtTestURI = "test:///default"
+
+func newTestLibvirtProvider(t *testing.T) (*LibvirtProvider, *libvirt.Libvirt) {
+	t.Helper()
+
+	hasTool := func(tool string) bool {
+		_, err := exec.LookPath(tool)
+		return err == nil
+	}
+	if !slices.ContainsFunc([]string{"genisoimage", "mkisofs", "xorrisofs"}, hasTool) {
+		t.Skip("no tool to build the cloud-init seed")
+	}
+
+	uri, _ := url.Parse(libvirtTestURI)
+	conn, err := libvirt.ConnectToURI(uri)
+	if err != nil {
+		t.Skipf("libvirtd is not available: %v", err)
+	}
+	t.Cleanup(func() { conn.Disconnect() })
+
+	return NewLibvirtProvider(libvirtTestURI, t.TempDir()), conn
+}
+
+// createTestImage adds a qcow2 image volume to the test pool.
+func createTestImage(t *testing.T, conn *libvirt.Libvirt) string {
+	t.Helper()
+
+	pool, err := conn.StoragePoolLookupByName("default-pool")
+	if err != nil {
+		t.Fatalf("looking up test pool: %v", err)
+	}
+
+	vol := libvirtVolume{
+		Name:     "image-" + uuid.New().String()[:8] + ".qcow2",
+		Capacity: libvirtCapacity{Unit: "bytes", Value: 1 << 20},
+		Format:   libvirtFormat{Type: "qcow2"},
+	}
+	volXML, err := xml.Marshal(vol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := conn.StorageVolCreateXML(pool, string(volXML), 0)
+	if err != nil {
+		t.Fatalf("creating test image: %v", err)
+	}
+	t.Cleanup(func() { conn.StorageVolDelete(image, libvirt.StorageVolDeleteNormal) })
+
+	return vol.Name
+}
+
+func testLibvirtSpec(name string, image string) domain.NodeSpec {
+	return domain.NodeSpec{
+		ProviderID: "libvirt",
+		Extra: map[string]any{
+			"name":        name,
+			"user":        "ubuntu",
+			"image":       image,
+			"image_type":  "qcow2",
+			"pool":        "default-pool",
+			"domain_type": "test",
+			"memory_mb":   128,
+		},
+	}
+}
+
+func assertNoVolumes(t *testing.T, conn *libvirt.Libvirt, names ...string) {
+	t.Helper()
+
+	pool, err := conn.StoragePoolLookupByName("default-pool")
+	if err != nil {
+		t.Fatalf("looking up test pool: %v", err)
+	}
+	for _, name := range names {
+		if _, err := conn.StorageVolLookupByName(pool, name); err == nil {
+			t.Errorf("volume %q was not rolled back", name)
+		}
+	}
+}
+
+func TestLibvirtRollbackKeepsForeignDomain(t *testing.T) {
+	p, conn := newTestLibvirtProvider(t)
+	image := createTestImage(t, conn)
+
+	// the mock driver already has a domain called "test"
+	_, err := p.Provision(context.Background(), "node-1", testLibvirtSpec("test", image))
+	if err == nil {
+		t.Fatal("provisioning over an existing domain succeeded")
+	}
+
+	if _, err := conn.DomainLookupByName("test"); err != nil {
+		t.Errorf("rollback removed the existing domain: %v", err)
+	}
+	assertNoVolumes(t, conn, "test-root.qcow2", "test-cidata.iso")
+	if _, err := os.Stat(filepath.Join(p.keyDir, "node-1")); err == nil {
+		t.Error("ssh key was not rolled back")
+	}
+}
+
+func TestLibvirtAddressTimeout(t *testing.T) {
+	p, conn := newTestLibvirtProvider(t)
+	p.addressTimeout = 100 * time.Millisecond
+	image := createTestImage(t, conn)
+
+	// mock domains never get a DHCP lease
+	name := "node-" + uuid.New().String()[:8]
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Provision(context.Background(), "node-2", testLibvirtSpec(name, image))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("provisioning without an address succeeded")
+		}
+		var timeout *domain.TimeoutError
+		if !errors.As(err, &timeout) {
+			t.Fatalf("provisioning failed before waiting for the address: %v", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("provisioning did not give up waiting for an address")
+	}
+
+	if _, err := conn.DomainLookupByName(name); err == nil {
+		t.Errorf("domain %q was not rolled back", name)
+	}
+	assertNoVolumes(t, conn, name+"-root.qcow2", name+"-cidata.iso")
+}
+
+func TestLibvirtUserData(t *testing.T) {
+	keys := libvirtKeys{
+		clientPublic: "ssh-ed25519 CLIENT",
+		hostPrivate:  "HOST PRIVATE",
+		hostPublic:   "ssh-ed25519 HOST",
+	}
+	args := LibvirtArgs{
+		User: "ubuntu",
+		Bootstrap: &domain.NodeBootstrap{
+			AuthorizedKeys: []string{"ssh-ed25519 EXTRA"},
+			Packages:       []string{"curl"},
+			Files:          []domain.BootstrapFile{{Path: "/etc/motd", Content: "hi", Permissions: "0644"}},
+			RunCmd:         []string{"systemctl restart sshd"},
+		},
+	}
+
+	userData, err := libvirtUserData(args, keys)
+	if err != nil {
+		t.Fatalf("rendering user data: %v", err)
+	}
+
+	body, found := strings.CutPrefix(string(userData), "#cloud-config\n")
+	if !found {
+		t.Fatalf("user data does not start with #cloud-config: %q", userData)
+	}
+	var config struct {
+		Users []struct {
+			Name              string   `json:"name"`
+			Sudo              string   `json:"sudo"`
+			SSHAuthorizedKeys []string `json:"ssh_authorized_keys"`
+		} `json:"users"`
+		SSHKeys       map[string]string   `json:"ssh_keys"`
+		SSHDeleteKeys bool                `json:"ssh_deletekeys"`
+		Packages      []string            `json:"packages"`
+		WriteFiles    []map[string]string `json:"write_files"`
+		RunCmd        []string            `json:"runcmd"`
+	}
+	if err := json.Unmarshal([]byte(body), &config); err != nil {
+		t.Fatalf("decoding cloud-config: %v", err)
+	}
+
+	if len(config.Users) != 1 || config.Users[0].Name != "ubuntu" {
+		t.Fatalf("users = %+v, want only ubuntu", config.Users)
+	}
+	if got, want := config.Users[0].SSHAuthorizedKeys, []string{"ssh-ed25519 CLIENT", "ssh-ed25519 EXTRA"}; !slices.Equal(got, want) {
+		t.Errorf("authorized keys = %q, want %q", got, want)
+	}
+	if config.SSHKeys["ed25519_private"] != "HOST PRIVATE" || config.SSHKeys["ed25519_public"] != "ssh-ed25519 HOST" {
+		t.Errorf("host keys = %v, want the generated host key", config.SSHKeys)
+	}
+	if !config.SSHDeleteKeys {
+		t.Error("ssh_deletekeys is not set, the image host keys would survive")
+	}
+	if !slices.Equal(config.Packages, []string{"curl"}) {
+		t.Errorf("packages = %q, want [curl]", config.Packages)
+	}
+	if len(config.WriteFiles) != 1 || config.WriteFiles[0]["path"] != "/etc/motd" || config.WriteFiles[0]["permissions"] != "0644" {
+		t.Errorf("write_files = %v, want /etc/motd with 0644", config.WriteFiles)
+	}
+	if _, ok := config.WriteFiles[0]["owner"]; ok {
+		t.Error("empty owner is rendered")
+	}
+	if !slices.Equal(config.RunCmd, []string{"systemctl restart sshd"}) {
+		t.Errorf("runcmd = %q, want the bootstrap commands", config.RunCmd)
+	}
+}
+
+func TestLibvirtUserDataWithoutBootstrap(t *testing.T) {
+	userData, err := libvirtUserData(LibvirtArgs{User: "ubuntu"}, libvirtKeys{clientPublic: "ssh-ed25519 CLIENT"})
+	if err != nil {
+		t.Fatalf("rendering user data: %v", err)
+	}
+	for _, key := range []string{"packages", "write_files", "runcmd"} {
+		if strings.Contains(string(userData), `"`+key+`"`) {
+			t.Errorf("user data without bootstrap contains %s: %s", key, userData)
+		}
+	}
+}
+
+func TestLibvirtDomainXML(t *testing.T) {
+	type disk struct {
+		device, format, file, dev, bus string
+	}
+
+	tests := []struct {
+		name      string
+		imageType domain.ImageType
+		disks     []disk
+		boots     []string
+	}{
+		{
+			name:      "qcow2",
+			imageType: domain.ImageTypeQCOW2,
+			disks: []disk{
+				{"disk", "qcow2", "/pool/root.qcow2", "vda", "virtio"},
+				{"cdrom", "raw", "/pool/cidata.iso", "sda", "sata"},
+			},
+			boots: []string{"hd"},
+		},
+		{
+			name:      "iso",
+			imageType: domain.ImageTypeISO,
+			disks: []disk{
+				{"disk", "qcow2", "/pool/root.qcow2", "vda", "virtio"},
+				{"cdrom", "raw", "/pool/cidata.iso", "sda", "sata"},
+				{"cdrom", "raw", "/pool/installer.iso", "sdb", "sata"},
+			},
+			boots: []string{"hd", "cdrom"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := LibvirtArgs{User: "ubuntu", Image: "image", ImageType: tt.imageType, Name: "node-a", CPUs: 2, MemoryMB: 1024}
+			args.defaults()
+
+			out, err := libvirtDomainXML(args, "/pool/root.qcow2", "/pool/cidata.iso", "/pool/installer.iso")
+			if err != nil {
+				t.Fatalf("rendering domain xml: %v", err)
+			}
+
+			var dom libvirtDomain
+			if err := xml.Unmarshal([]byte(out), &dom); err != nil {
+				t.Fatalf("parsing domain xml: %v", err)
+			}
+
+			if dom.Type != "kvm" || dom.Name != "node-a" || dom.VCPU != 2 {
+				t.Errorf("domain = %s %s with %d cpus, want kvm node-a with 2 cpus", dom.Type, dom.Name, dom.VCPU)
+			}
+			if dom.Memory.Unit != "MiB" || dom.Memory.Value != 1024 {
+				t.Errorf("memory = %d %s, want 1024 MiB", dom.Memory.Value, dom.Memory.Unit)
+			}
+			if dom.Devices.Iface.Type != "network" || dom.Devices.Iface.Source.Network != "default" || dom.Devices.Iface.Model.Type != "virtio" {
+				t.Errorf("interface = %+v, want a virtio nic on the default network", dom.Devices.Iface)
+			}
+
+			var disks []disk
+			for _, d := range dom.Devices.Disks {
+				disks = append(disks, disk{d.Device, d.Driver.Type, d.Source.File, d.Target.Dev, d.Target.Bus})
+				if (d.Device == "cdrom") != (d.ReadOnly != nil) {
+					t.Errorf("disk %s readonly = %v, want only cdroms read only", d.Target.Dev, d.ReadOnly != nil)
+				}
+			}
+			if !slices.Equal(disks, tt.disks) {
+				t.Errorf("disks = %+v, want %+v", disks, tt.disks)
+			}
+
+			var boots []string
+			for _, b := range dom.OS.Boots {
+				boots = append(boots, b.Dev)
+			}
+			if !slices.Equal(boots, tt.boots) {
+				t.Errorf("boot order = %q, want %q", boots, tt.boots)
+			}
+		})
+	}
+}
+
+func TestLibvirtArgsFromOverrides(t *testing.T) {
+	// the way the template provider overrides arrive in the spec
+	extra := map[string]any{
+		"user":       "ubuntu",
+		"image":      "jammy.qcow2",
+		"image_type": "qcow2",
+		"cpus":       2,
+		"memory_mb":  2048,
+		"disk_gb":    5,
+		"network":    "lab",
+		"pool":       "images",
+	}
+
+	args, err := util.DecodeExtraTo[LibvirtArgs](extra)
+	if err != nil {
+		t.Fatalf("decoding args: %v", err)
+	}
+	args.defaults()
+
+	want := LibvirtArgs{
+		Name: args.Name, User: "ubuntu", Image: "jammy.qcow2", ImageType: domain.ImageTypeQCOW2,
+		CPUs: 2, MemoryMB: 2048, DiskGB: 5, Network: "lab", Pool: "images", DomainType: "kvm",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %+v, want %+v", args, want)
+	}
+	if !strings.HasPrefix(args.Name, "node-") {
+		t.Errorf("name = %q, want a generated node- name", args.Name)
+	}
+
+	res, err := NewLibvirtProvider(libvirtTestURI, t.TempDir()).SpecResources(domain.NodeSpec{Extra: map[string]any{"user": "ubuntu"}})
+	if err != nil {
+		t.Fatalf("sizing spec: %v", err)
+	}
+	if res != (domain.Resources{Nodes: 1, CPUs: 1, MemoryMB: 512}) {
+		t.Errorf("resources without overrides = %+v, want the 1 cpu and 512 MiB defaults", res)
+	}
+}
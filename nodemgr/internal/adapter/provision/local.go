@@ -0,0 +1,91 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+)
+
+type LocalProvider struct {
+	baseDir string
+}
+
+func NewLocalProvider(baseDir string) *LocalProvider {
+	if baseDir == "" {
+		baseDir = filepath.Join(os.TempDir(), "nodemgr-local")
+	}
+
+	return &LocalProvider{baseDir: baseDir}
+}
+
+func (p *LocalProvider) ID() domain.ProviderID {
+	return domain.ProviderID("local")
+}
+
+func (p *LocalProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error) {
+	workdir := p.workdir(nodeID)
+	if err := os.MkdirAll(workdir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating node workdir: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("reading hostname: %w", err)
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("reading current user: %w", err)
+	}
+
+	node := domain.Node{
+		NodeID:     nodeID,
+		ProviderID: p.ID(),
+		State:      domain.NodeStateRunning,
+		Meta: map[string]any{
+			"hostname": hostname,
+			"user":     current.Username,
+			"os":       runtime.GOOS,
+			"arch":     runtime.GOARCH,
+			"cpus":     runtime.NumCPU(),
+			"workdir":  workdir,
+		},
+		Cap: map[domain.Cap]bool{
+			domain.LocalExecCap: true,
+		},
+	}
+
+	return &node, nil
+}
+
+func (p *LocalProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
+	if err := os.RemoveAll(p.workdir(nodeID)); err != nil {
+		return fmt.Errorf("removing node workdir: %w", err)
+	}
+	return nil
+}
+
+func (p *LocalProvider) workdir(nodeID domain.NodeID) string {
+	return filepath.Join(p.baseDir, string(nodeID))
+}
+
+// SpecResources only charges the node, local nodes share the host.
+func (p *LocalProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	return domain.Resources{Nodes: 1}, nil
+}
+
+// Unisolated is true, commands of local nodes run as nodemgr on its host.
+func (p *LocalProvider) Unisolated() bool {
+	return true
+}
+
+var (
+	_ port.NodeProvider           = (*LocalProvider)(nil)
+	_ port.NodeResourceEstimator  = (*LocalProvider)(nil)
+	_ port.UnisolatedNodeProvider = (*LocalProvider)(nil)
+)
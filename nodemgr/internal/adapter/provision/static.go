@@ -0,0 +1,264 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"path"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type StaticHost struct {
+	Name    string            `mapstructure:"name" validate:"required"`
+	Address string            `mapstructure:"address" validate:"required"`
+	Port    int               `mapstructure:"port,omitempty"`
+	User    string            `mapstructure:"user" validate:"required"`
+	KeyPath string            `mapstructure:"key_path,omitempty"`
+	HostKey string            `mapstructure:"host_key,omitempty"`
+	Labels  map[string]string `mapstructure:"labels,omitempty"`
+
+	// Capacity is the number of nodes that may lease the host at once
+	Capacity int `mapstructure:"capacity,omitempty" validate:"gte=0"`
+
+	// every lease gets its own directory under Workspace
+	Workspace     string `mapstructure:"workspace,omitempty"`
+	WipeWorkspace bool   `mapstructure:"wipe_workspace,omitempty"`
+}
+
+type StaticArgs struct {
+	Host   string            `mapstructure:"host,omitempty"`
+	Labels map[string]string `mapstructure:"labels,omitempty"`
+}
+
+type StaticProvider struct {
+	mu       sync.Mutex
+	hosts    []StaticHost
+	leases   map[domain.NodeID]*domain.Node
+	executor port.NodeExecProvider
+}
+
+// NewStaticProvider leases nodes from a fixed inventory. The executor is used
+// to prepare and wipe lease workspaces and may be nil when none are configured.
+func NewStaticProvider(hosts []StaticHost, executor port.NodeExecProvider) (*StaticProvider, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+
+	seen := make(map[string]bool)
+	for i, host := range hosts {
+		if err := validate.Struct(host); err != nil {
+			return nil, &domain.InvalidSpecError{Err: fmt.Errorf("validate host %d: %w", i, err)}
+		}
+		if seen[host.Name] {
+			return nil, domain.InvalidSpec("duplicate host name %q", host.Name)
+		}
+		seen[host.Name] = true
+
+		if host.Capacity == 0 {
+			hosts[i].Capacity = 1
+		}
+		if host.Port == 0 {
+			hosts[i].Port = 22
+		}
+	}
+
+	return &StaticProvider{
+		hosts:    hosts,
+		leases:   make(map[domain.NodeID]*domain.Node),
+		executor: executor,
+	}, nil
+}
+
+func (p *StaticProvider) ID() domain.ProviderID {
+	return domain.ProviderID("static")
+}
+
+func (p *StaticProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error) {
+	args, err := util.DecodeExtraTo[StaticArgs](spec.Extra)
+	if err != nil {
+		return nil, &domain.InvalidSpecError{Err: fmt.Errorf("decode extra: %w", err)}
+	}
+
+	p.mu.Lock()
+	host, err := p.pickHost(args)
+	if err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	meta := map[string]any{
+		"static_host": host.Name,
+		"labels":      host.Labels,
+		"ssh_host":    host.Address,
+		"ssh_port":    host.Port,
+		"ssh_user":    host.User,
+	}
+	if host.KeyPath != "" {
+		meta["ssh_key_path"] = host.KeyPath
+	}
+	if host.HostKey != "" {
+		meta["ssh_host_key"] = host.HostKey
+	}
+	if host.Workspace != "" {
+		meta["workdir"] = path.Join(host.Workspace, string(nodeID))
+	}
+
+	node := &domain.Node{
+		NodeID:     nodeID,
+		ProviderID: p.ID(),
+		State:      domain.NodeStateRunning,
+		Meta:       meta,
+		Cap: map[domain.Cap]bool{
+			"exec:ssh": true,
+		},
+	}
+	p.leases[nodeID] = node
+	p.mu.Unlock()
+
+	if host.Workspace != "" {
+		if err := p.run(ctx, node, "mkdir", "-p", meta["workdir"].(string)); err != nil {
+			p.release(nodeID)
+			return nil, fmt.Errorf("preparing workspace: %w", err)
+		}
+	}
+
+	return node, nil
+}
+
+func (p *StaticProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
+	p.mu.Lock()
+	node, ok := p.leases[nodeID]
+	p.mu.Unlock()
+
+	if !ok {
+		return &domain.NotFoundError{Kind: "static lease", ID: string(nodeID)}
+	}
+
+	host := p.host(node.Meta["static_host"].(string))
+	if workdir, ok := node.Meta["workdir"].(string); ok && host.WipeWorkspace {
+		if err := p.run(ctx, node, "rm", "-rf", workdir); err != nil {
+			return fmt.Errorf("wiping workspace: %w", err)
+		}
+	}
+
+	p.release(nodeID)
+	return nil
+}
+
+// pickHost returns the least leased host matching the args, p.mu must be held.
+func (p *StaticProvider) pickHost(args StaticArgs) (*StaticHost, error) {
+	used := make(map[string]int)
+	for _, node := range p.leases {
+		used[node.Meta["static_host"].(string)]++
+	}
+
+	var best *StaticHost
+	matched := false
+	leased, capacity := 0, 0
+	for i := range p.hosts {
+		host := &p.hosts[i]
+		if args.Host != "" && host.Name != args.Host {
+			continue
+		}
+		if !matchLabels(host.Labels, args.Labels) {
+			continue
+		}
+		matched = true
+		leased += used[host.Name]
+		capacity += host.Capacity
+
+		if used[host.Name] >= host.Capacity {
+			continue
+		}
+		if best == nil || used[host.Name] < used[best.Name] {
+			best = host
+		}
+	}
+
+	if !matched {
+		return nil, domain.InvalidSpec("no host in inventory matches host %q and labels %v", args.Host, args.Labels)
+	}
+	if best == nil {
+		return nil, &domain.QuotaExceededError{
+			Scope:     domain.QuotaScopeProvider,
+			Name:      string(p.ID()),
+			Resource:  "leases",
+			Requested: 1,
+			Used:      leased,
+			Limit:     capacity,
+		}
+	}
+	return best, nil
+}
+
+func (p *StaticProvider) host(name string) StaticHost {
+	for _, host := range p.hosts {
+		if host.Name == name {
+			return host
+		}
+	}
+	return StaticHost{}
+}
+
+func (p *StaticProvider) release(nodeID domain.NodeID) {
+	p.mu.Lock()
+	delete(p.leases, nodeID)
+	p.mu.Unlock()
+}
+
+// run executes command on the host of node, closing the handle once ctx is
+// done aborts the command.
+func (p *StaticProvider) run(ctx context.Context, node *domain.Node, command ...string) error {
+	if p.executor == nil {
+		return domain.InvalidSpec("no executor configured to manage workspaces")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	handle, err := p.executor.OpenExecHandle(node)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	defer context.AfterFunc(ctx, func() { handle.Close() })()
+
+	res, err := handle.Exec(domain.ExecRequest{Command: command, WorkingDir: "/"})
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+	if err != nil {
+		return err
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("%v exited with %d: %s", command, res.ExitCode, res.Stderr)
+	}
+	return nil
+}
+
+func matchLabels(have map[string]string, want map[string]string) bool {
+	for k, v := range want {
+		if have[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SpecResources only charges the lease, hosts are shared with their other users.
+func (p *StaticProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	return domain.Resources{Nodes: 1}, nil
+}
+
+// Unisolated is true, leases hand out the hosts themselves.
+func (p *StaticProvider) Unisolated() bool {
+	return true
+}
+
+var (
+	_ port.NodeProvider           = (*StaticProvider)(nil)
+	_ port.NodeResourceEstimator  = (*StaticProvider)(nil)
+	_ port.UnisolatedNodeProvider = (*StaticProvider)(nil)
+)
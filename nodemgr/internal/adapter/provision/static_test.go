@@ -0,0 +1,221 @@
+package provision
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nodemgr/internal/adapter/execute"
+	"nodemgr/internal/adapter/execute/sshtest"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+)
+
+func staticSpec(args map[string]any) domain.NodeSpec {
+	return domain.NodeSpec{ProviderID: "static", Extra: args}
+}
+
+func TestStaticPickHost(t *testing.T) {
+	newProvider := func(t *testing.T) *StaticProvider {
+		t.Helper()
+		p, err := NewStaticProvider([]StaticHost{
+			{Name: "gpu", Address: "10.0.0.1", User: "ci", Labels: map[string]string{"gpu": "a100", "region": "eu"}},
+			{Name: "big", Address: "10.0.0.2", User: "ci", Labels: map[string]string{"region": "eu"}, Capacity: 2},
+			{Name: "us", Address: "10.0.0.3", User: "ci", Labels: map[string]string{"region": "us"}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	ctx := context.Background()
+
+	t.Run("labels", func(t *testing.T) {
+		p := newProvider(t)
+		node, err := p.Provision(ctx, "n1", staticSpec(map[string]any{"labels": map[string]any{"gpu": "a100"}}))
+		if err != nil {
+			t.Fatalf("provision: %v", err)
+		}
+		if node.Meta["static_host"] != "gpu" || node.Meta["ssh_host"] != "10.0.0.1" || node.Meta["ssh_port"] != 22 {
+			t.Errorf("meta = %v, want the gpu host on port 22", node.Meta)
+		}
+	})
+
+	t.Run("host name", func(t *testing.T) {
+		p := newProvider(t)
+		node, err := p.Provision(ctx, "n1", staticSpec(map[string]any{"host": "us"}))
+		if err != nil {
+			t.Fatalf("provision: %v", err)
+		}
+		if node.Meta["static_host"] != "us" {
+			t.Errorf("host = %v, want us", node.Meta["static_host"])
+		}
+	})
+
+	t.Run("least leased host first", func(t *testing.T) {
+		p := newProvider(t)
+		region := staticSpec(map[string]any{"labels": map[string]any{"region": "eu"}})
+
+		var hosts []any
+		for _, id := range []domain.NodeID{"n1", "n2", "n3"} {
+			node, err := p.Provision(ctx, id, region)
+			if err != nil {
+				t.Fatalf("provision %s: %v", id, err)
+			}
+			hosts = append(hosts, node.Meta["static_host"])
+		}
+		// both start empty, then big has room for a second lease
+		if hosts[0] != "gpu" || hosts[1] != "big" || hosts[2] != "big" {
+			t.Errorf("hosts = %v, want gpu, big, big", hosts)
+		}
+
+		_, err := p.Provision(ctx, "n4", region)
+		var quotaErr *domain.QuotaExceededError
+		if !errors.As(err, &quotaErr) {
+			t.Fatalf("err = %v, want a quota error", err)
+		}
+		if quotaErr.Scope != domain.QuotaScopeProvider || quotaErr.Used != 3 || quotaErr.Limit != 3 || domain.Code(err) != domain.ErrorCodeQuotaExceeded {
+			t.Errorf("quota error = %+v, want 3 of 3 provider leases used", quotaErr)
+		}
+
+		// other hosts are not counted against the matching ones
+		if _, err := p.Provision(ctx, "n5", staticSpec(map[string]any{"host": "us"})); err != nil {
+			t.Errorf("provision on a free host: %v", err)
+		}
+
+		if err := p.Destroy(ctx, "n2"); err != nil {
+			t.Fatalf("destroy: %v", err)
+		}
+		node, err := p.Provision(ctx, "n6", region)
+		if err != nil {
+			t.Fatalf("provision after release: %v", err)
+		}
+		if node.Meta["static_host"] != "big" {
+			t.Errorf("host = %v, want the released big", node.Meta["static_host"])
+		}
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		p := newProvider(t)
+		_, err := p.Provision(ctx, "n1", staticSpec(map[string]any{"host": "gpu", "labels": map[string]any{"region": "us"}}))
+		if domain.Code(err) != domain.ErrorCodeInvalidSpec {
+			t.Errorf("code = %q, want invalid_spec", domain.Code(err))
+		}
+	})
+
+	t.Run("unknown lease", func(t *testing.T) {
+		if err := newProvider(t).Destroy(ctx, "n1"); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("err = %v, want not found", err)
+		}
+	})
+}
+
+func TestStaticInventoryValidation(t *testing.T) {
+	for name, hosts := range map[string][]StaticHost{
+		"missing address": {{Name: "a", User: "ci"}},
+		"duplicate name":  {{Name: "a", Address: "10.0.0.1", User: "ci"}, {Name: "a", Address: "10.0.0.2", User: "ci"}},
+		"negative cap":    {{Name: "a", Address: "10.0.0.1", User: "ci", Capacity: -1}},
+	} {
+		if _, err := NewStaticProvider(hosts, nil); domain.Code(err) != domain.ErrorCodeInvalidSpec {
+			t.Errorf("%s: code = %q, want invalid_spec", name, domain.Code(err))
+		}
+	}
+}
+
+// newTestStaticProvider manages workspaces below workspace on a host served by
+// the in-process ssh server.
+func newTestStaticProvider(t *testing.T, workspace string, wipe bool) *StaticProvider {
+	t.Helper()
+	t.Setenv("SSH_AUTH_SOCK", "")
+
+	server := sshtest.NewServer(t)
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, []byte(server.ClientKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := execute.NewSSHExecProvider(util.NewRepository[domain.ExecHandleID, port.ExecHandle](), "")
+	p, err := NewStaticProvider([]StaticHost{{
+		Name:          "host",
+		Address:       server.Addr.IP.String(),
+		Port:          server.Addr.Port,
+		User:          "test",
+		KeyPath:       keyPath,
+		HostKey:       server.AuthorizedHostKey(),
+		Workspace:     workspace,
+		WipeWorkspace: wipe,
+	}}, executor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestStaticWorkspace(t *testing.T) {
+	for _, wipe := range []bool{true, false} {
+		workspace := t.TempDir()
+		p := newTestStaticProvider(t, workspace, wipe)
+		ctx := context.Background()
+
+		node, err := p.Provision(ctx, "n1", staticSpec(nil))
+		if err != nil {
+			t.Fatalf("provision: %v", err)
+		}
+		workdir := filepath.Join(workspace, "n1")
+		if node.Meta["workdir"] != workdir {
+			t.Errorf("workdir = %v, want %s", node.Meta["workdir"], workdir)
+		}
+		if info, err := os.Stat(workdir); err != nil || !info.IsDir() {
+			t.Fatalf("workspace was not created: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(workdir, "build.log"), []byte("left behind"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.Destroy(ctx, "n1"); err != nil {
+			t.Fatalf("destroy: %v", err)
+		}
+		if _, err := os.Stat(workdir); os.IsNotExist(err) != wipe {
+			t.Errorf("wipe %v: workspace exists = %v", wipe, !os.IsNotExist(err))
+		}
+		if len(p.leases) != 0 {
+			t.Errorf("leases = %v, want none", p.leases)
+		}
+	}
+}
+
+func TestStaticWorkspaceFailureReleasesLease(t *testing.T) {
+	// a file where the workspace should be makes mkdir fail
+	workspace := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(workspace, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestStaticProvider(t, workspace, true)
+
+	if _, err := p.Provision(context.Background(), "n1", staticSpec(nil)); err == nil {
+		t.Fatal("provision into a broken workspace succeeded")
+	}
+	if len(p.leases) != 0 {
+		t.Errorf("leases = %v, want the failed one released", p.leases)
+	}
+}
+
+func TestStaticWorkspaceCancelled(t *testing.T) {
+	workspace := t.TempDir()
+	p := newTestStaticProvider(t, workspace, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Provision(ctx, "n1", staticSpec(nil)); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want cancelled", err)
+	}
+	if len(p.leases) != 0 {
+		t.Errorf("leases = %v, want the cancelled one released", p.leases)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "n1")); !os.IsNotExist(err) {
+		t.Errorf("workspace of the cancelled lease was created: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package domain
+
+import "time"
+
+type AuditEventID string
+
+type AuditAction string
+
+const (
+	AuditActionProvision AuditAction = "provision"
+	AuditActionDestroy   AuditAction = "destroy"
+	AuditActionExec      AuditAction = "exec"
+	AuditActionAttach    AuditAction = "attach"
+	AuditActionCopyTo    AuditAction = "copy_to"
+	AuditActionCopyFrom  AuditAction = "copy_from"
+	AuditActionStart     AuditAction = "start"
+	AuditActionStop      AuditAction = "stop"
+	AuditActionReboot    AuditAction = "reboot"
+	AuditActionTerminate AuditAction = "terminate"
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSucceeded AuditOutcome = "succeeded"
+	AuditOutcomeFailed    AuditOutcome = "failed"
+)
+
+// AuditEvent records a single mutation of a node, events are never changed
+// once written.
+type AuditEvent struct {
+	EventID AuditEventID
+	Time    time.Time
+	Actor   string
+	Action  AuditAction
+
+	TenantID    TenantID
+	NodeID      NodeID
+	TemplateID  TemplateID
+	ProviderID  ProviderID
+	OperationID OperationID
+
+	// Params are the request parameters with secrets redacted
+	Params map[string]any
+
+	Outcome AuditOutcome
+	Error   string
+	Code    ErrorCode
+}
+
+func (e AuditEvent) ID() AuditEventID {
+	return e.EventID
+}
+
+// AuditFilter selects audit events, zero fields match any event. Limit keeps
+// only the newest events.
+type AuditFilter struct {
+	Actor    string
+	Action   AuditAction
+	TenantID TenantID
+	NodeID   NodeID
+	Outcome  AuditOutcome
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+func (f AuditFilter) Match(e AuditEvent) bool {
+	switch {
+	case f.Actor != "" && e.Actor != f.Actor:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.TenantID != "" && e.TenantID != f.TenantID:
+		return false
+	case f.NodeID != "" && e.NodeID != f.NodeID:
+		return false
+	case f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Role grants permissions within a tenant, every role includes the ones
+// before it.
+type Role string
+
+const (
+	// RoleViewer can read nodes, jobs, operations and templates
+	RoleViewer Role = "viewer"
+	// RoleUser can additionally provision, exec into and destroy nodes
+	RoleUser Role = "user"
+	// RoleAdmin can additionally manage templates, mappings, tokens and,
+	// without a tenant, pools and quotas
+	RoleAdmin Role = "admin"
+)
+
+var roles = []Role{RoleViewer, RoleUser, RoleAdmin}
+
+func ParseRole(s string) (Role, error) {
+	if !slices.Contains(roles, Role(s)) {
+		return "", InvalidSpec("unknown role %q", s)
+	}
+	return Role(s), nil
+}
+
+// Includes reports whether r grants everything other grants.
+func (r Role) Includes(other Role) bool {
+	i, j := slices.Index(roles, r), slices.Index(roles, other)
+	return i >= 0 && j >= 0 && i >= j
+}
+
+// Identity is an authenticated caller. Identities without a tenant act in
+// every tenant.
+type Identity struct {
+	Subject  string
+	TenantID TenantID
+	Role     Role
+}
+
+// SystemIdentity is the identity of work nodemgr does on its own, like
+// reaping expired leases or refilling pools.
+var SystemIdentity = Identity{Subject: "system", Role: RoleAdmin}
+
+// Allows reports whether the identity holds at least role in tenantID.
+func (i Identity) Allows(role Role, tenantID TenantID) bool {
+	return i.Role.Includes(role) && (i.TenantID == "" || i.TenantID == tenantID)
+}
+
+// CanView reports whether the identity can read resources of tenantID,
+// resources without a tenant are shared with everyone.
+func (i Identity) CanView(tenantID TenantID) bool {
+	return tenantID == "" || i.Allows(RoleViewer, tenantID)
+}
+
+type APITokenID string
+
+// APIToken authenticates as Identity, only the hash of its secret is stored.
+type APIToken struct {
+	TokenID    APITokenID
+	SecretHash string
+	Identity   Identity
+
+	CreatedAt time.Time
+	// ExpiresAt is zero for tokens that never expire
+	ExpiresAt time.Time
+}
+
+func (t APIToken) ID() APITokenID {
+	return t.TokenID
+}
+
+var (
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+type UnauthenticatedError struct {
+	Reason string
+}
+
+func (e *UnauthenticatedError) Error() string {
+	return fmt.Sprintf("unauthenticated: %s", e.Reason)
+}
+
+func (e *UnauthenticatedError) Is(target error) bool {
+	return target == ErrUnauthenticated
+}
+
+type PermissionDeniedError struct {
+	Subject  string
+	Role     Role
+	TenantID TenantID
+}
+
+func (e *PermissionDeniedError) Error() string {
+	if e.TenantID == "" {
+		return fmt.Sprintf("%s lacks the global %s role", e.Subject, e.Role)
+	}
+	return fmt.Sprintf("%s lacks the %s role in tenant %q", e.Subject, e.Role, e.TenantID)
+}
+
+func (e *PermissionDeniedError) Is(target error) bool {
+	return target == ErrPermissionDenied
+}
@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrAlreadyExists       = errors.New("already exists")
+	ErrInvalidSpec         = errors.New("invalid spec")
+	ErrCapabilityMissing   = errors.New("capability missing")
+	ErrProviderUnavailable = errors.New("provider unavailable")
+	ErrTimeout             = errors.New("timeout")
+	ErrConflict            = errors.New("conflict")
+)
+
+type NotFoundError struct {
+	Kind string
+	ID   string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %q not found", e.Kind, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+type AlreadyExistsError struct {
+	Kind string
+	ID   string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("%s %q already exists", e.Kind, e.ID)
+}
+
+func (e *AlreadyExistsError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
+
+// InvalidSpecError is returned for requests that can never succeed as they
+// are, retrying them is pointless.
+type InvalidSpecError struct {
+	Err error
+}
+
+func InvalidSpec(format string, a ...any) error {
+	return &InvalidSpecError{Err: fmt.Errorf(format, a...)}
+}
+
+func (e *InvalidSpecError) Error() string {
+	return fmt.Sprintf("invalid spec: %v", e.Err)
+}
+
+func (e *InvalidSpecError) Unwrap() error {
+	return e.Err
+}
+
+func (e *InvalidSpecError) Is(target error) bool {
+	return target == ErrInvalidSpec
+}
+
+// CapabilityMissingError is returned when a node lacks the capability an
+// action needs, Cap ending with a colon stands for any capability of that
+// kind.
+type CapabilityMissingError struct {
+	NodeID NodeID
+	Cap    Cap
+}
+
+func (e *CapabilityMissingError) Error() string {
+	if kind, wildcard := strings.CutSuffix(string(e.Cap), ":"); wildcard {
+		return fmt.Sprintf("node %s has no supported %s capability", e.NodeID, kind)
+	}
+	return fmt.Sprintf("node %s does not have %s capability", e.NodeID, e.Cap)
+}
+
+func (e *CapabilityMissingError) Is(target error) bool {
+	return target == ErrCapabilityMissing
+}
+
+// ProviderUnavailableError is returned when the backend of a provider, like
+// the docker daemon or libvirt, can not be reached or is busy, like a pulumi
+// stack locked by another update. It is always retryable.
+type ProviderUnavailableError struct {
+	Err error
+}
+
+func ProviderUnavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ProviderUnavailableError{Err: err}
+}
+
+func (e *ProviderUnavailableError) Error() string {
+	return fmt.Sprintf("provider unavailable: %v", e.Err)
+}
+
+func (e *ProviderUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProviderUnavailableError) Is(target error) bool {
+	return target == ErrProviderUnavailable
+}
+
+// ConflictError is returned when a resource is not in the state the request
+// needs, like cancelling a request that was already admitted. Retrying only
+// helps once the state changed.
+type ConflictError struct {
+	Err error
+}
+
+func Conflict(format string, a ...any) error {
+	return &ConflictError{Err: fmt.Errorf(format, a...)}
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+type TimeoutError struct {
+	Op  string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out: %v", e.Op, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// ErrorCode is the stable identifier of an error kind exposed to API clients,
+// codes must never be renamed.
+type ErrorCode string
+
+const (
+	ErrorCodeNotFound            ErrorCode = "not_found"
+	ErrorCodeAlreadyExists       ErrorCode = "already_exists"
+	ErrorCodeInvalidSpec         ErrorCode = "invalid_spec"
+	ErrorCodeCapabilityMissing   ErrorCode = "capability_missing"
+	ErrorCodeProviderUnavailable ErrorCode = "provider_unavailable"
+	ErrorCodeQuotaExceeded       ErrorCode = "quota_exceeded"
+	ErrorCodeTimeout             ErrorCode = "timeout"
+	ErrorCodeInvalidTransition   ErrorCode = "invalid_transition"
+	ErrorCodeUnschedulable       ErrorCode = "unschedulable"
+	ErrorCodeCancelled           ErrorCode = "cancelled"
+	ErrorCodeConflict            ErrorCode = "conflict"
+	ErrorCodeUnauthenticated     ErrorCode = "unauthenticated"
+	ErrorCodePermissionDenied    ErrorCode = "permission_denied"
+	ErrorCodeInternal            ErrorCode = "internal"
+)
+
+// Code maps err to its stable code, errors of unknown kind are internal.
+func Code(err error) ErrorCode {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotFound):
+		return ErrorCodeNotFound
+	case errors.Is(err, ErrAlreadyExists):
+		return ErrorCodeAlreadyExists
+	case errors.Is(err, ErrInvalidSpec):
+		return ErrorCodeInvalidSpec
+	case errors.Is(err, ErrCapabilityMissing):
+		return ErrorCodeCapabilityMissing
+	case errors.Is(err, ErrProviderUnavailable):
+		return ErrorCodeProviderUnavailable
+	case errors.Is(err, ErrQuotaExceeded):
+		return ErrorCodeQuotaExceeded
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeTimeout
+	case errors.Is(err, ErrInvalidTransition):
+		return ErrorCodeInvalidTransition
+	case errors.Is(err, ErrUnschedulable):
+		return ErrorCodeUnschedulable
+	case errors.Is(err, context.Canceled):
+		return ErrorCodeCancelled
+	case errors.Is(err, ErrConflict):
+		return ErrorCodeConflict
+	case errors.Is(err, ErrUnauthenticated):
+		return ErrorCodeUnauthenticated
+	case errors.Is(err, ErrPermissionDenied):
+		return ErrorCodePermissionDenied
+	default:
+		return ErrorCodeInternal
+	}
+}
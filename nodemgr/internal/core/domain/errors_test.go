@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCode
+	}{
+		{nil, ""},
+		{&NotFoundError{Kind: "Node", ID: "n"}, ErrorCodeNotFound},
+		{fmt.Errorf("loading: %w", &AlreadyExistsError{Kind: "Node", ID: "n"}), ErrorCodeAlreadyExists},
+		{InvalidSpec("image is required"), ErrorCodeInvalidSpec},
+		{&CapabilityMissingError{NodeID: "n", Cap: "exec:"}, ErrorCodeCapabilityMissing},
+		{fmt.Errorf("provisioning: %w", ProviderUnavailable(errors.New("stack locked"))), ErrorCodeProviderUnavailable},
+		{&QuotaExceededError{Scope: QuotaScopeTenant}, ErrorCodeQuotaExceeded},
+		{&TimeoutError{Op: "wait", Err: errors.New("slow")}, ErrorCodeTimeout},
+		{context.DeadlineExceeded, ErrorCodeTimeout},
+		{context.Canceled, ErrorCodeCancelled},
+		{Conflict("request already %s", "admitted"), ErrorCodeConflict},
+		{errors.New("boom"), ErrorCodeInternal},
+	}
+
+	for _, tt := range tests {
+		if got := Code(tt.err); got != tt.want {
+			t.Errorf("Code(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestProviderUnavailableOfNil(t *testing.T) {
+	if err := ProviderUnavailable(nil); err != nil {
+		t.Errorf("ProviderUnavailable(nil) = %v, want nil", err)
+	}
+}
@@ -1,10 +1,26 @@
 package domain
 
-import "io"
+import (
+	"io"
+	"strings"
+)
 
 type ExecProviderID string
 type ExecHandleID string
 
+// LocalExecCap is the capability of nodes on the nodemgr host, unlike the
+// `exec:<id>` capabilities of the other executors it names the executor first.
+const LocalExecCap Cap = "local:exec"
+
+// ExecProviderOf returns the executor a capability stands for.
+func ExecProviderOf(c Cap) (ExecProviderID, bool) {
+	if c == LocalExecCap {
+		return "local", true
+	}
+	id, found := strings.CutPrefix(string(c), "exec:")
+	return ExecProviderID(id), found
+}
+
 type AttachRequest struct {
 	NodeID         NodeID
 	ExecProviderID ExecProviderID
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+type JobID string
+
+type JobState string
+
+const (
+	JobStatePending   JobState = "pending"
+	JobStateRunning   JobState = "running"
+	JobStateSucceeded JobState = "succeeded"
+	JobStateFailed    JobState = "failed"
+)
+
+// TeardownPolicy decides what happens to the node of a successful job, nodes
+// of failed jobs are always destroyed.
+type TeardownPolicy string
+
+const (
+	TeardownPolicyDestroy TeardownPolicy = "destroy"
+	TeardownPolicyStop    TeardownPolicy = "stop"
+	TeardownPolicyKeep    TeardownPolicy = "keep"
+)
+
+// JobFile is a file copied between the staging directory of the job tenant on
+// the nodemgr host (Src for inputs, Dst for artifacts) and the node.
+type JobFile struct {
+	Src string
+	Dst string
+}
+
+type JobCommand struct {
+	Command    []string
+	Env        map[string]string
+	WorkingDir string
+}
+
+type JobStepState string
+
+const (
+	JobStepStateSucceeded   JobStepState = "succeeded"
+	JobStepStateFailed      JobStepState = "failed"
+	JobStepStateCompensated JobStepState = "compensated"
+)
+
+type JobStep struct {
+	Name  string
+	State JobStepState
+	Error string
+
+	// ExitCode, Stdout and Stderr are only set for commands
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Job is a declarative provision, copy in, exec, copy out and teardown
+// workflow run by the orchestrator.
+type Job struct {
+	JobID      JobID
+	TemplateID TemplateID
+	ProviderID ProviderID
+	TenantID   TenantID
+
+	Inputs    []JobFile
+	Commands  []JobCommand
+	Artifacts []JobFile
+	Teardown  TeardownPolicy
+
+	State       JobState
+	OperationID OperationID
+	NodeID      NodeID
+	Steps       []JobStep
+	Error       string
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (j Job) ID() JobID {
+	return j.JobID
+}
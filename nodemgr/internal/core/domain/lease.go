@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+type ExpireAction string
+
+const (
+	ExpireActionStop      ExpireAction = "stop"
+	ExpireActionTerminate ExpireAction = "terminate"
+)
+
+// LeasePolicy limits how long a node may live, zero durations disable the
+// respective limit.
+type LeasePolicy struct {
+	TTL         time.Duration `json:"ttl,omitempty" mapstructure:"ttl"`
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty" mapstructure:"idle_timeout"`
+	// OnExpire defaults to terminate
+	OnExpire ExpireAction `json:"on_expire,omitempty" mapstructure:"on_expire" validate:"omitempty,oneof=stop terminate"`
+}
+
+type NodeLease struct {
+	LeasePolicy
+
+	// ExpiresAt is zero when the node has no TTL, heartbeats may move it
+	ExpiresAt    time.Time
+	LastActivity time.Time
+	// Sessions counts open exec handles, a node is never idle while it has any
+	Sessions int
+}
+
+func NewNodeLease(policy LeasePolicy, now time.Time) NodeLease {
+	lease := NodeLease{LeasePolicy: policy, LastActivity: now}
+	if policy.TTL > 0 {
+		lease.ExpiresAt = now.Add(policy.TTL)
+	}
+	return lease
+}
+
+// Expired reports whether the lease ran out at now and why.
+func (l NodeLease) Expired(now time.Time) (bool, string) {
+	if l.TTLExpired(now) {
+		return true, "ttl expired"
+	}
+	if l.IdleTimeout > 0 && l.Sessions == 0 && !l.LastActivity.IsZero() && now.Sub(l.LastActivity) > l.IdleTimeout {
+		return true, "idle timeout"
+	}
+	return false, ""
+}
+
+// TTLExpired reports whether the node outlived its TTL at now, unlike idle
+// nodes such nodes may not be started again.
+func (l NodeLease) TTLExpired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && now.After(l.ExpiresAt)
+}
+
+func (l NodeLease) Action() ExpireAction {
+	if l.OnExpire == "" {
+		return ExpireActionTerminate
+	}
+	return l.OnExpire
+}
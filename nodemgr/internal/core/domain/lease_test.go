@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeLeaseExpired(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		lease      NodeLease
+		now        time.Time
+		wantReason string
+	}{
+		{"no limits", NewNodeLease(LeasePolicy{}, start), start.Add(24 * time.Hour), ""},
+		{"within ttl", NewNodeLease(LeasePolicy{TTL: time.Hour}, start), start.Add(time.Hour), ""},
+		{"past ttl", NewNodeLease(LeasePolicy{TTL: time.Hour}, start), start.Add(time.Hour + time.Second), "ttl expired"},
+		{
+			name:  "heartbeat moved the deadline",
+			lease: NodeLease{LeasePolicy: LeasePolicy{TTL: time.Hour}, ExpiresAt: start.Add(2 * time.Hour), LastActivity: start.Add(time.Hour)},
+			now:   start.Add(90 * time.Minute),
+		},
+		{"within idle timeout", NewNodeLease(LeasePolicy{IdleTimeout: time.Minute}, start), start.Add(time.Minute), ""},
+		{"idle", NewNodeLease(LeasePolicy{IdleTimeout: time.Minute}, start), start.Add(2 * time.Minute), "idle timeout"},
+		{
+			name:  "heartbeat kept it active",
+			lease: NodeLease{LeasePolicy: LeasePolicy{IdleTimeout: time.Minute}, LastActivity: start.Add(90 * time.Second)},
+			now:   start.Add(2 * time.Minute),
+		},
+		{
+			name:  "open session is never idle",
+			lease: NodeLease{LeasePolicy: LeasePolicy{IdleTimeout: time.Minute}, LastActivity: start, Sessions: 1},
+			now:   start.Add(time.Hour),
+		},
+		{
+			name:  "never active",
+			lease: NodeLease{LeasePolicy: LeasePolicy{IdleTimeout: time.Minute}},
+			now:   start,
+		},
+		{
+			name:       "ttl wins over idle timeout",
+			lease:      NewNodeLease(LeasePolicy{TTL: time.Hour, IdleTimeout: time.Minute}, start),
+			now:        start.Add(2 * time.Hour),
+			wantReason: "ttl expired",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired, reason := tt.lease.Expired(tt.now)
+			if expired != (tt.wantReason != "") || reason != tt.wantReason {
+				t.Errorf("Expired() = %v, %q, want %v, %q", expired, reason, tt.wantReason != "", tt.wantReason)
+			}
+			if got, want := tt.lease.TTLExpired(tt.now), tt.wantReason == "ttl expired"; got != want {
+				t.Errorf("TTLExpired() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNodeLeaseAction(t *testing.T) {
+	if got := (NodeLease{}).Action(); got != ExpireActionTerminate {
+		t.Errorf("default action = %q, want terminate", got)
+	}
+	if got := (NodeLease{LeasePolicy: LeasePolicy{OnExpire: ExpireActionStop}}).Action(); got != ExpireActionStop {
+		t.Errorf("action = %q, want stop", got)
+	}
+}
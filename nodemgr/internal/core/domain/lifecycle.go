@@ -1,5 +1,14 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+type LifecycleProviderID string
+
 type NodeState string
 
 const (
@@ -10,3 +19,43 @@ const (
 	NodeStateShuttingDown NodeState = "shutting_down"
 	NodeStateTerminated   NodeState = "terminated"
 )
+
+// Modeled after the EC2 instance lifecycle. Backward edges out of the
+// transitional states are only used to roll back a failed provider action.
+// Pending nodes can not be shut down, the provider is still creating them.
+var nodeStateTransitions = map[NodeState][]NodeState{
+	"":                    {NodeStatePending},
+	NodeStatePending:      {NodeStateRunning, NodeStateStopped, NodeStateTerminated},
+	NodeStateRunning:      {NodeStateRunning, NodeStateStopping, NodeStateShuttingDown},
+	NodeStateStopping:     {NodeStateStopped, NodeStateRunning, NodeStateShuttingDown},
+	NodeStateStopped:      {NodeStatePending, NodeStateShuttingDown},
+	NodeStateShuttingDown: {NodeStateTerminated, NodeStateRunning, NodeStateStopped},
+	NodeStateTerminated:   {},
+}
+
+var ErrInvalidTransition = errors.New("invalid node state transition")
+
+type InvalidTransitionError struct {
+	NodeID NodeID
+	From   NodeState
+	To     NodeState
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("node %s cannot go from %q to %q", e.NodeID, e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+type NodeStateTransition struct {
+	From   NodeState
+	To     NodeState
+	Reason string
+	At     time.Time
+}
+
+func CanTransition(from NodeState, to NodeState) bool {
+	return slices.Contains(nodeStateTransitions[from], to)
+}
@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	states := []NodeState{"", NodeStatePending, NodeStateRunning, NodeStateStopping, NodeStateStopped, NodeStateShuttingDown, NodeStateTerminated}
+	allowed := map[NodeState][]NodeState{
+		"":                    {NodeStatePending},
+		NodeStatePending:      {NodeStateRunning, NodeStateStopped, NodeStateTerminated},
+		NodeStateRunning:      {NodeStateRunning, NodeStateStopping, NodeStateShuttingDown},
+		NodeStateStopping:     {NodeStateStopped, NodeStateRunning, NodeStateShuttingDown},
+		NodeStateStopped:      {NodeStatePending, NodeStateShuttingDown},
+		NodeStateShuttingDown: {NodeStateTerminated, NodeStateRunning, NodeStateStopped},
+		NodeStateTerminated:   {},
+	}
+
+	for _, from := range states {
+		for _, to := range states {
+			want := false
+			for _, s := range allowed[from] {
+				want = want || s == to
+			}
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestNodeTransition(t *testing.T) {
+	tests := []struct {
+		from    NodeState
+		to      NodeState
+		allowed bool
+	}{
+		{NodeStatePending, NodeStateRunning, true},
+		{NodeStateRunning, NodeStateStopping, true},
+		{NodeStateStopped, NodeStatePending, true},
+		{NodeStateShuttingDown, NodeStateTerminated, true},
+		{NodeStateShuttingDown, NodeStateStopping, false},
+		{NodeStatePending, NodeStateShuttingDown, false},
+		{NodeStateStopped, NodeStateRunning, false},
+		{NodeStateTerminated, NodeStateRunning, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s to %s", tt.from, tt.to), func(t *testing.T) {
+			earlier := NodeStateTransition{To: tt.from, Reason: "earlier"}
+			node := &Node{NodeID: "node-1", State: tt.from, History: []NodeStateTransition{earlier}}
+
+			before := time.Now()
+			err := node.Transition(tt.to, "test")
+
+			if !tt.allowed {
+				var transitionErr *InvalidTransitionError
+				if !errors.As(err, &transitionErr) || !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("err = %v, want an invalid transition", err)
+				}
+				if transitionErr.From != tt.from || transitionErr.To != tt.to || Code(err) != ErrorCodeInvalidTransition {
+					t.Errorf("err = %#v, want from %s to %s", transitionErr, tt.from, tt.to)
+				}
+				if node.State != tt.from || len(node.History) != 1 {
+					t.Errorf("rejected transition changed the node: state %s, history %v", node.State, node.History)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("transition: %v", err)
+			}
+			if node.State != tt.to {
+				t.Errorf("state = %s, want %s", node.State, tt.to)
+			}
+			if len(node.History) != 2 || node.History[0] != earlier {
+				t.Fatalf("history = %v, want the earlier entry and one more", node.History)
+			}
+			entry := node.History[1]
+			if entry.From != tt.from || entry.To != tt.to || entry.Reason != "test" || entry.At.Before(before) {
+				t.Errorf("history entry = %+v, want %s to %s for test at or after %v", entry, tt.from, tt.to, before)
+			}
+		})
+	}
+}
+
+func TestNodeTransitionDoesNotShareHistory(t *testing.T) {
+	history := make([]NodeStateTransition, 1, 4)
+	original := &Node{State: NodeStateRunning, History: history}
+	copied := *original
+
+	if err := copied.Transition(NodeStateStopping, "stop"); err != nil {
+		t.Fatal(err)
+	}
+	if err := original.Transition(NodeStateShuttingDown, "destroy"); err != nil {
+		t.Fatal(err)
+	}
+
+	if copied.History[1].To != NodeStateStopping {
+		t.Errorf("history of the copy was overwritten: %v", copied.History)
+	}
+}
@@ -11,6 +11,9 @@ const (
 
 type NodeSpecMapping struct {
 	MappingID MappingID
+	// TenantID owns the mapping, it only applies to specs of that tenant.
+	// Mappings without one apply to every spec.
+	TenantID TenantID
 
 	Match             map[string]string
 	MatchType         MatchType
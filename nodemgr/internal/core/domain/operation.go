@@ -0,0 +1,63 @@
+package domain
+
+import "time"
+
+type OperationID string
+
+type OperationKind string
+
+const (
+	OperationKindProvision OperationKind = "provision"
+	OperationKindDestroy   OperationKind = "destroy"
+	OperationKindJob       OperationKind = "job"
+)
+
+type OperationState string
+
+const (
+	OperationStatePending   OperationState = "pending"
+	OperationStateRunning   OperationState = "running"
+	OperationStateSucceeded OperationState = "succeeded"
+	OperationStateFailed    OperationState = "failed"
+	OperationStateCancelled OperationState = "cancelled"
+)
+
+type Operation struct {
+	OperationID OperationID
+	Kind        OperationKind
+	NodeID      NodeID
+	TenantID    TenantID
+
+	State OperationState
+	Error string
+	// Code is the stable code of Error
+	Code     ErrorCode
+	Attempts []OperationAttempt
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (o Operation) ID() OperationID {
+	return o.OperationID
+}
+
+func (o Operation) Done() bool {
+	switch o.State {
+	case OperationStateSucceeded, OperationStateFailed, OperationStateCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// OperationAttempt is a single try of a retried operation, Retryable tells a
+// flaky failure from a permanent one.
+type OperationAttempt struct {
+	Attempt   int
+	Error     string
+	Retryable bool
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type PoolID string
+
+// NodePool keeps Size idle nodes of a template ready on a provider so
+// provisioning requests can be served without waiting for the provider.
+type NodePool struct {
+	PoolID     PoolID
+	TemplateID TemplateID
+	ProviderID ProviderID
+
+	Size int
+	// MaxIdle is how long a node may wait in the pool before it is replaced,
+	// zero keeps idle nodes forever
+	MaxIdle time.Duration
+	// ResetCommand runs on the node right before it is handed out
+	ResetCommand []string
+}
+
+func (p NodePool) ID() PoolID {
+	return p.PoolID
+}
+
+type NodePoolStatus struct {
+	PoolID       PoolID
+	Idle         int
+	Provisioning int
+	Hits         int
+	Misses       int
+}
@@ -1,21 +1,46 @@
 package domain
 
+import (
+	"slices"
+	"time"
+)
+
 type NodeID string
 type ProviderID string
 type Cap string
 
 type NodeSpec struct {
+	// TemplateID is empty for specs not rendered from a template
+	TemplateID TemplateID
 	ProviderID ProviderID
+	TenantID   TenantID
 	Extra      map[string]any
+
+	Lease LeasePolicy
 }
 
 type Node struct {
 	NodeID     NodeID
 	ProviderID ProviderID
+	TenantID   TenantID
+	TemplateID TemplateID
+	// Owner is the subject the node was provisioned or handed out for
+	Owner string
+
+	// Resources are accounted against tenant quota and provider capacity
+	// until the node is terminated
+	Resources Resources
+
+	State   NodeState
+	History []NodeStateTransition
+
+	Lease NodeLease
 
-	State NodeState
-	Meta  map[string]any
-	Cap   map[Cap]bool
+	// PoolID is set while the node waits idle in a warm pool
+	PoolID PoolID
+
+	Meta map[string]any
+	Cap  map[Cap]bool
 }
 
 func (n Node) ID() NodeID {
@@ -29,3 +54,19 @@ func (n *Node) HasCap(cap Cap) bool {
 func (n *Node) SetCap(cap Cap, value bool) {
 	n.Cap[cap] = value
 }
+
+func (n *Node) Transition(to NodeState, reason string) error {
+	if !CanTransition(n.State, to) {
+		return &InvalidTransitionError{NodeID: n.NodeID, From: n.State, To: to}
+	}
+
+	// history may be shared with other copies of the node, never append in place
+	n.History = append(slices.Clip(n.History), NodeStateTransition{
+		From:   n.State,
+		To:     to,
+		Reason: reason,
+		At:     time.Now(),
+	})
+	n.State = to
+	return nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import "time"
+
+type QueueRequestID string
+
+// QueuePriority orders queued requests, higher priorities are always admitted
+// first.
+type QueuePriority string
+
+const (
+	QueuePriorityInteractive QueuePriority = "interactive"
+	QueuePriorityNormal      QueuePriority = "normal"
+	QueuePriorityBatch       QueuePriority = "batch"
+)
+
+func (p QueuePriority) Rank() int {
+	switch p {
+	case QueuePriorityInteractive:
+		return 2
+	case QueuePriorityBatch:
+		return 0
+	default:
+		return 1
+	}
+}
+
+type QueueRequestState string
+
+const (
+	QueueRequestStateQueued    QueueRequestState = "queued"
+	QueueRequestStateAdmitted  QueueRequestState = "admitted"
+	QueueRequestStateFailed    QueueRequestState = "failed"
+	QueueRequestStateCancelled QueueRequestState = "cancelled"
+)
+
+// QueueRequest is a template provisioning request waiting for capacity. Once
+// admitted it points to the provisioning operation.
+type QueueRequest struct {
+	RequestID  QueueRequestID
+	TemplateID TemplateID
+	ProviderID ProviderID
+	TenantID   TenantID
+	Priority   QueuePriority
+	// Requester is the identity the request is admitted on behalf of
+	Requester Identity
+
+	State       QueueRequestState
+	OperationID OperationID
+	Error       string
+
+	EnqueuedAt time.Time
+	AdmittedAt time.Time
+}
+
+func (r QueueRequest) ID() QueueRequestID {
+	return r.RequestID
+}
+
+type QueuePosition struct {
+	RequestID QueueRequestID
+	// Position is 1 for the request admitted next
+	Position int
+	// EstimatedWait is derived from recent admissions, zero when there is not
+	// enough history yet
+	EstimatedWait time.Duration
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+type TenantID string
+
+// Resources is an amount of nodes, cpus and memory. When used as a limit zero
+// fields are unlimited.
+type Resources struct {
+	Nodes    int
+	CPUs     int
+	MemoryMB int
+}
+
+func (r Resources) Add(o Resources) Resources {
+	return Resources{Nodes: r.Nodes + o.Nodes, CPUs: r.CPUs + o.CPUs, MemoryMB: r.MemoryMB + o.MemoryMB}
+}
+
+func (r Resources) Scale(n int) Resources {
+	return Resources{Nodes: r.Nodes * n, CPUs: r.CPUs * n, MemoryMB: r.MemoryMB * n}
+}
+
+type Quota struct {
+	TenantID TenantID
+	Limits   Resources
+}
+
+func (q Quota) ID() TenantID {
+	return q.TenantID
+}
+
+type Usage struct {
+	Used   Resources
+	Limits Resources
+}
+
+type QuotaScope string
+
+const (
+	QuotaScopeTenant   QuotaScope = "tenant"
+	QuotaScopeProvider QuotaScope = "provider"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+type QuotaExceededError struct {
+	Scope    QuotaScope
+	Name     string
+	Resource string
+
+	Requested int
+	Used      int
+	Limit     int
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s %q %s quota exceeded: requested %d, used %d of %d", e.Scope, e.Name, e.Resource, e.Requested, e.Used, e.Limit)
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Exceeds returns the first resource of r that does not fit into limits
+// together with used, or nil when everything fits.
+func (r Resources) Exceeds(used Resources, limits Resources, scope QuotaScope, name string) error {
+	checks := []struct {
+		resource         string
+		req, used, limit int
+	}{
+		{"nodes", r.Nodes, used.Nodes, limits.Nodes},
+		{"cpus", r.CPUs, used.CPUs, limits.CPUs},
+		{"memory_mb", r.MemoryMB, used.MemoryMB, limits.MemoryMB},
+	}
+
+	for _, c := range checks {
+		if c.limit > 0 && c.used+c.req > c.limit {
+			return &QuotaExceededError{
+				Scope:     scope,
+				Name:      name,
+				Resource:  c.resource,
+				Requested: c.req,
+				Used:      c.used,
+				Limit:     c.limit,
+			}
+		}
+	}
+	return nil
+}
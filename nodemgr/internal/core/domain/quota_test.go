@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResourcesExceeds(t *testing.T) {
+	limits := Resources{Nodes: 4, CPUs: 8, MemoryMB: 1024}
+
+	tests := []struct {
+		name     string
+		req      Resources
+		used     Resources
+		limits   Resources
+		resource string
+	}{
+		{name: "fits", req: Resources{Nodes: 1, CPUs: 2, MemoryMB: 256}, used: Resources{Nodes: 1, CPUs: 2, MemoryMB: 256}, limits: limits},
+		{name: "fills exactly", req: Resources{Nodes: 2, CPUs: 4, MemoryMB: 512}, used: Resources{Nodes: 2, CPUs: 4, MemoryMB: 512}, limits: limits},
+		{name: "zero limits are unlimited", req: Resources{Nodes: 100, CPUs: 100, MemoryMB: 1 << 20}, used: Resources{Nodes: 100}},
+		{name: "nodes", req: Resources{Nodes: 1}, used: Resources{Nodes: 4}, limits: limits, resource: "nodes"},
+		{name: "cpus", req: Resources{Nodes: 1, CPUs: 4}, used: Resources{CPUs: 6}, limits: limits, resource: "cpus"},
+		{name: "memory", req: Resources{Nodes: 1, MemoryMB: 512}, used: Resources{MemoryMB: 768}, limits: limits, resource: "memory_mb"},
+		{name: "first resource wins", req: Resources{Nodes: 5, CPUs: 9}, limits: limits, resource: "nodes"},
+		{name: "request alone", req: Resources{Nodes: 1, CPUs: 16}, limits: limits, resource: "cpus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Exceeds(tt.used, tt.limits, QuotaScopeTenant, "tenant-a")
+			if tt.resource == "" {
+				if err != nil {
+					t.Fatalf("Exceeds() = %v, want nil", err)
+				}
+				return
+			}
+
+			var exceeded *QuotaExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("Exceeds() = %v, want *QuotaExceededError", err)
+			}
+			if !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("error does not match ErrQuotaExceeded")
+			}
+			if exceeded.Resource != tt.resource {
+				t.Errorf("resource = %q, want %q", exceeded.Resource, tt.resource)
+			}
+			if exceeded.Scope != QuotaScopeTenant || exceeded.Name != "tenant-a" {
+				t.Errorf("scope = %s %q, want tenant %q", exceeded.Scope, exceeded.Name, "tenant-a")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy retries operations failing with retryable errors using
+// exponential backoff with jitter.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt, 1 disables retries and zero
+	// uses DefaultRetryPolicy
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes the backoff by this fraction in both directions
+	Jitter float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+func (p RetryPolicy) OrDefault() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		return DefaultRetryPolicy
+	}
+	return p
+}
+
+// Backoff returns how long to wait after the given failed attempt, counted
+// from 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := max(p.Multiplier, 1)
+
+	backoff := float64(p.InitialBackoff)
+	for range attempt - 1 {
+		backoff *= multiplier
+	}
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, float64(p.MaxBackoff))
+	}
+
+	backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(max(backoff, 0))
+}
+
+// RetryPolicies configures retries of a provider, ExecOpen applies to exec
+// handles opened on nodes of the provider.
+type RetryPolicies struct {
+	ProviderID ProviderID
+	Provision  RetryPolicy
+	Destroy    RetryPolicy
+	ExecOpen   RetryPolicy
+}
+
+func (p RetryPolicies) ID() ProviderID {
+	return p.ProviderID
+}
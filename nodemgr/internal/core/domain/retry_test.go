@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffGrowsUpToMax(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := p.Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestBackoffWithoutGrowth(t *testing.T) {
+	// multipliers below 1 would shrink the backoff, they keep it constant
+	for _, multiplier := range []float64{0, 0.5, 1} {
+		p := RetryPolicy{InitialBackoff: time.Second, Multiplier: multiplier}
+		if got := p.Backoff(5); got != time.Second {
+			t.Errorf("Backoff(5) with multiplier %v = %v, want %v", multiplier, got, time.Second)
+		}
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Multiplier: 2, Jitter: 0.25}
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		base := min(time.Second<<(attempt-1), 4*time.Second)
+		lo, hi := base*3/4, base*5/4
+		for range 200 {
+			if got := p.Backoff(attempt); got < lo || got > hi {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, got, lo, hi)
+			}
+		}
+	}
+}
+
+func TestBackoffNeverNegative(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, Jitter: 3}
+	for range 200 {
+		if got := p.Backoff(1); got < 0 {
+			t.Fatalf("Backoff(1) = %v, want not negative", got)
+		}
+	}
+}
+
+func TestRetryPolicyOrDefault(t *testing.T) {
+	if got := (RetryPolicy{}).OrDefault(); got != DefaultRetryPolicy {
+		t.Errorf("zero policy = %+v, want the default", got)
+	}
+
+	disabled := RetryPolicy{MaxAttempts: 1}
+	if got := disabled.OrDefault(); got != disabled {
+		t.Errorf("configured policy = %+v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+type SchedulingPolicy string
+
+const (
+	SchedulingPolicyCheapest     SchedulingPolicy = "cheapest"
+	SchedulingPolicyFastestStart SchedulingPolicy = "fastest-start"
+	SchedulingPolicyPreferLocal  SchedulingPolicy = "prefer-local"
+	SchedulingPolicySpread       SchedulingPolicy = "spread"
+)
+
+var ErrUnschedulable = errors.New("no template and provider satisfy the requirement")
+
+// ProviderProfile describes what a provider offers to the scheduler.
+type ProviderProfile struct {
+	ProviderID ProviderID
+
+	Caps   []Cap
+	Arch   []string
+	Labels map[string]string
+
+	// zero values mean the provider does not limit node size
+	MaxCPUs     int
+	MaxMemoryMB int
+	// Capacity limits all active nodes of the provider together
+	Capacity Resources
+
+	CostPerHour float64
+	StartupTime time.Duration
+	Local       bool
+}
+
+func (p ProviderProfile) ID() ProviderID {
+	return p.ProviderID
+}
+
+type Requirement struct {
+	TenantID TenantID
+
+	MinCPUs     int
+	MinMemoryMB int
+	Caps        []Cap
+	Labels      map[string]string
+	Arch        string
+
+	// Policy defaults to cheapest
+	Policy SchedulingPolicy
+}
+
+type SchedulingCandidate struct {
+	TemplateID TemplateID
+	ProviderID ProviderID
+
+	// Rejections explain why the candidate does not satisfy the requirement,
+	// empty for viable candidates
+	Rejections []string
+	// Score is assigned by the policy to viable candidates, lower is better
+	Score float64
+}
+
+type SchedulingDecision struct {
+	TemplateID TemplateID
+	ProviderID ProviderID
+	Policy     SchedulingPolicy
+
+	// Candidates are ordered from the best viable one to rejected ones
+	Candidates []SchedulingCandidate
+}
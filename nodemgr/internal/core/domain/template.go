@@ -14,6 +14,8 @@ const (
 
 type NodeTemplate struct {
 	TemplateID TemplateID `json:"-"`
+	// TenantID owns the template, templates without one are shared
+	TenantID TenantID `json:"-"`
 
 	Name      string    `json:"name"`
 	Image     string    `json:"image"`
@@ -23,6 +25,16 @@ type NodeTemplate struct {
 	MemoryMB  int       `json:"memory_mb"`
 	DiskMB    int       `json:"disk_mb"`
 
+	Bootstrap *NodeBootstrap `json:"bootstrap,omitempty"`
+
+	// Arch and Labels are only used by the scheduler
+	Arch   string            `json:"-"`
+	Labels map[string]string `json:"-"`
+
+	// Lease is the default lease of nodes provisioned from the template, it
+	// is not passed to providers
+	Lease LeasePolicy `json:"-"`
+
 	Extra             map[string]any                `json:"-"`
 	ProviderOverrides map[ProviderID]map[string]any `json:"-"`
 }
@@ -30,3 +42,19 @@ type NodeTemplate struct {
 func (n NodeTemplate) ID() TemplateID {
 	return n.TemplateID
 }
+
+// NodeBootstrap describes the first boot setup of a node, every provider
+// renders it into its own format like cloud-init or docker exec steps.
+type NodeBootstrap struct {
+	AuthorizedKeys []string        `json:"authorized_keys,omitempty" mapstructure:"authorized_keys"`
+	Packages       []string        `json:"packages,omitempty" mapstructure:"packages"`
+	Files          []BootstrapFile `json:"files,omitempty" mapstructure:"files" validate:"dive"`
+	RunCmd         []string        `json:"run_cmd,omitempty" mapstructure:"run_cmd"`
+}
+
+type BootstrapFile struct {
+	Path        string `json:"path" mapstructure:"path" validate:"required"`
+	Content     string `json:"content" mapstructure:"content"`
+	Permissions string `json:"permissions,omitempty" mapstructure:"permissions"`
+	Owner       string `json:"owner,omitempty" mapstructure:"owner"`
+}
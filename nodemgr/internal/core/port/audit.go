@@ -0,0 +1,23 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+// AuditSink stores audit events, it is append-only.
+type AuditSink interface {
+	Append(ctx context.Context, event domain.AuditEvent) error
+	// Query returns the matching events oldest first. Sinks may drop all but
+	// the latest Limit events, the caller applies Limit either way.
+	Query(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error)
+}
+
+type AuditService interface {
+	// Record stores event on behalf of the actor of ctx with the outcome of
+	// err. Failures to store it are logged, the mutation already happened.
+	Record(ctx context.Context, event domain.AuditEvent, err error)
+	// QueryEvents needs an admin, tenant admins only see events of their
+	// tenant.
+	QueryEvents(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error)
+}
@@ -0,0 +1,30 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+	"time"
+)
+
+type APITokenRepository interface {
+	Create(token domain.APIToken) error
+	Get(id domain.APITokenID) (*domain.APIToken, error)
+	List() ([]*domain.APIToken, error)
+	Delete(id domain.APITokenID) error
+}
+
+// AuthService authenticates callers, transports pass the identity on with
+// util.WithIdentity and services authorize it themselves.
+type AuthService interface {
+	AuthenticateToken(ctx context.Context, token string) (*domain.Identity, error)
+	// AuthenticateJWT accepts NATS user JWTs issued by a trusted account key.
+	// The caller proves it holds the user nkey by signing nonce, which the
+	// transport must never hand out twice. Tenant and role are read from the
+	// `tenant:<id>` and `role:<role>` tags.
+	AuthenticateJWT(ctx context.Context, userJWT string, nonce []byte, sig []byte) (*domain.Identity, error)
+	// CreateToken returns the secret token, it can not be recovered later. A
+	// zero ttl never expires.
+	CreateToken(ctx context.Context, identity domain.Identity, ttl time.Duration) (string, *domain.APIToken, error)
+	ListTokens(ctx context.Context) ([]*domain.APIToken, error)
+	RevokeToken(ctx context.Context, id domain.APITokenID) error
+}
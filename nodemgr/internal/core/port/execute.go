@@ -1,6 +1,7 @@
 package port
 
 import (
+	"context"
 	"io"
 	"nodemgr/internal/core/domain"
 )
@@ -25,6 +26,8 @@ type NodeExecProvider interface {
 
 type ExecHandle interface {
 	ID() domain.ExecHandleID
+	// Close releases the handle and aborts commands and copies still
+	// running on it.
 	Close() error
 
 	Attach(attach domain.AttachRequest) (*domain.AttachResult, error)
@@ -36,10 +39,15 @@ type ExecHandle interface {
 }
 
 type NodeExecuteService interface {
-	Attach(req domain.AttachRequest) (*domain.AttachResult, error)
-	Exec(req domain.ExecRequest) (*domain.ExecResult, error)
-	ExecStream(exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error)
-
-	CopyTo(req domain.CopyToRequest) error
-	CopyFrom(req domain.CopyFromRequest) error
+	Attach(ctx context.Context, req domain.AttachRequest) (*domain.AttachResult, error)
+	// Exec and ExecStream pass the trace context of ctx to the command in
+	// TRACEPARENT like environment variables. Exec and the copies are aborted
+	// when ctx is done.
+	Exec(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error)
+	ExecStream(ctx context.Context, exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error)
+
+	// host paths of the copies are resolved inside the staging directory of
+	// the node tenant, they can not reach other files of the nodemgr host
+	CopyTo(ctx context.Context, req domain.CopyToRequest) error
+	CopyFrom(ctx context.Context, req domain.CopyFromRequest) error
 }
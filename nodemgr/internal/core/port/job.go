@@ -0,0 +1,22 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type JobRepository interface {
+	Create(job domain.Job) error
+	Update(job domain.Job) error
+	Get(id domain.JobID) (*domain.Job, error)
+	List() ([]*domain.Job, error)
+	Delete(id domain.JobID) error
+}
+
+type OrchestratorService interface {
+	// SubmitJob runs the job in the background as an operation of kind job,
+	// cancelling the operation aborts the job and releases its node.
+	SubmitJob(ctx context.Context, job domain.Job) (*domain.Job, error)
+	GetJob(ctx context.Context, id domain.JobID) (*domain.Job, error)
+	ListJobs(ctx context.Context) ([]*domain.Job, error)
+}
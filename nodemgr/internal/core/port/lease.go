@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+	"time"
+)
+
+type NodeLeaseService interface {
+	// Heartbeat marks the node active and, when extend is positive, makes
+	// sure its TTL does not run out for at least extend.
+	Heartbeat(ctx context.Context, nodeID domain.NodeID, extend time.Duration) (*domain.NodeLease, error)
+
+	// Run stops or terminates nodes with expired leases until ctx is done.
+	Run(ctx context.Context)
+}
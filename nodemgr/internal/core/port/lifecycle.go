@@ -1,21 +1,35 @@
 package port
 
-import "nodemgr/internal/core/domain"
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type NodeLifecycleRepository interface {
+	Create(lifecycle NodeLifecycle) error
+	Get(id domain.LifecycleProviderID) (*NodeLifecycle, error)
+	List() ([]*NodeLifecycle, error)
+	Delete(id domain.LifecycleProviderID) error
+}
 
 type NodeLifecycle interface {
+	ID() domain.LifecycleProviderID
 	OpenLifecycleHandle(node *domain.Node) (NodeLifecycleHandle, error)
 }
 
 type NodeLifecycleHandle interface {
-	Start()
-	Stop() // TODO: Hibernate???
-	Reboot()
-	Terminate()
+	Close() error
+
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error // TODO: Hibernate???
+	Reboot(ctx context.Context) error
 }
 
 type NodeLifecycleService interface {
-	StartNode(nodeID domain.NodeID) error
-	StopNode(nodeID domain.NodeID) error
-	RebootNode(nodeID domain.NodeID) error
-	TerminateNode(nodeID domain.NodeID) error
+	StartNode(ctx context.Context, nodeID domain.NodeID) error
+	StopNode(ctx context.Context, nodeID domain.NodeID) error
+	RebootNode(ctx context.Context, nodeID domain.NodeID) error
+	// TerminateNode destroys the node like NodeProvisionService.DestroyNode
+	// and waits until it is gone, it does not need a lifecycle capability.
+	TerminateNode(ctx context.Context, nodeID domain.NodeID) error
 }
@@ -1,6 +1,9 @@
 package port
 
-import "nodemgr/internal/core/domain"
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
 
 type MappingRepository interface {
 	Create(mapping domain.NodeSpecMapping) error
@@ -10,10 +13,11 @@ type MappingRepository interface {
 }
 
 type MappingService interface {
-	CreateMapping(mapping domain.NodeSpecMapping) (domain.MappingID, error)
-	GetMapping(id domain.MappingID) (*domain.NodeSpecMapping, error)
-	ListMappings() ([]*domain.NodeSpecMapping, error)
-	DeleteMapping(id domain.MappingID) error
-
-	ResolveSpecAliases(spec domain.NodeSpec) (domain.NodeSpec, error)
+	CreateMapping(ctx context.Context, mapping domain.NodeSpecMapping) (domain.MappingID, error)
+	GetMapping(ctx context.Context, id domain.MappingID) (*domain.NodeSpecMapping, error)
+	ListMappings(ctx context.Context) ([]*domain.NodeSpecMapping, error)
+	DeleteMapping(ctx context.Context, id domain.MappingID) error
+	// ResolveSpecAliases applies the shared mappings and those of the tenant
+	// of spec.
+	ResolveSpecAliases(ctx context.Context, spec domain.NodeSpec) (domain.NodeSpec, error)
 }
@@ -0,0 +1,16 @@
+package port
+
+import (
+	"nodemgr/internal/core/domain"
+	"time"
+)
+
+// MetricsRecorder receives measurements of provider calls. Gauges like node
+// counts are read from the repositories by the metrics adapter itself.
+type MetricsRecorder interface {
+	ObserveProvision(providerID domain.ProviderID, duration time.Duration, err error)
+	ObserveDestroy(providerID domain.ProviderID, duration time.Duration, err error)
+	// ObserveExec is called for every exec and, once they exit, for streamed
+	// execs and attach sessions. exitCode is only valid when err is nil.
+	ObserveExec(providerID domain.ProviderID, duration time.Duration, exitCode int, err error)
+}
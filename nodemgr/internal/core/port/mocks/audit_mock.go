@@ -0,0 +1,122 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/audit.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/audit.go -destination=internal/core/port/mocks/audit_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAuditSink is a mock of AuditSink interface.
+type MockAuditSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditSinkMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditSinkMockRecorder is the mock recorder for MockAuditSink.
+type MockAuditSinkMockRecorder struct {
+	mock *MockAuditSink
+}
+
+// NewMockAuditSink creates a new mock instance.
+func NewMockAuditSink(ctrl *gomock.Controller) *MockAuditSink {
+	mock := &MockAuditSink{ctrl: ctrl}
+	mock.recorder = &MockAuditSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditSink) EXPECT() *MockAuditSinkMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockAuditSink) Append(ctx context.Context, event domain.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockAuditSinkMockRecorder) Append(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockAuditSink)(nil).Append), ctx, event)
+}
+
+// Query mocks base method.
+func (m *MockAuditSink) Query(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", ctx, filter)
+	ret0, _ := ret[0].([]*domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockAuditSinkMockRecorder) Query(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockAuditSink)(nil).Query), ctx, filter)
+}
+
+// MockAuditService is a mock of AuditService interface.
+type MockAuditService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuditServiceMockRecorder is the mock recorder for MockAuditService.
+type MockAuditServiceMockRecorder struct {
+	mock *MockAuditService
+}
+
+// NewMockAuditService creates a new mock instance.
+func NewMockAuditService(ctrl *gomock.Controller) *MockAuditService {
+	mock := &MockAuditService{ctrl: ctrl}
+	mock.recorder = &MockAuditServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditService) EXPECT() *MockAuditServiceMockRecorder {
+	return m.recorder
+}
+
+// QueryEvents mocks base method.
+func (m *MockAuditService) QueryEvents(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryEvents", ctx, filter)
+	ret0, _ := ret[0].([]*domain.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryEvents indicates an expected call of QueryEvents.
+func (mr *MockAuditServiceMockRecorder) QueryEvents(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryEvents", reflect.TypeOf((*MockAuditService)(nil).QueryEvents), ctx, filter)
+}
+
+// Record mocks base method.
+func (m *MockAuditService) Record(ctx context.Context, event domain.AuditEvent, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", ctx, event, err)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockAuditServiceMockRecorder) Record(ctx, event, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockAuditService)(nil).Record), ctx, event, err)
+}
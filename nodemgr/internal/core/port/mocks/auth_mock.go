@@ -0,0 +1,200 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/auth.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/auth.go -destination=internal/core/port/mocks/auth_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAPITokenRepository is a mock of APITokenRepository interface.
+type MockAPITokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPITokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockAPITokenRepositoryMockRecorder is the mock recorder for MockAPITokenRepository.
+type MockAPITokenRepositoryMockRecorder struct {
+	mock *MockAPITokenRepository
+}
+
+// NewMockAPITokenRepository creates a new mock instance.
+func NewMockAPITokenRepository(ctrl *gomock.Controller) *MockAPITokenRepository {
+	mock := &MockAPITokenRepository{ctrl: ctrl}
+	mock.recorder = &MockAPITokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPITokenRepository) EXPECT() *MockAPITokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAPITokenRepository) Create(token domain.APIToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPITokenRepositoryMockRecorder) Create(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPITokenRepository)(nil).Create), token)
+}
+
+// Delete mocks base method.
+func (m *MockAPITokenRepository) Delete(id domain.APITokenID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockAPITokenRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAPITokenRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockAPITokenRepository) Get(id domain.APITokenID) (*domain.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockAPITokenRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockAPITokenRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockAPITokenRepository) List() ([]*domain.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockAPITokenRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockAPITokenRepository)(nil).List))
+}
+
+// MockAuthService is a mock of AuthService interface.
+type MockAuthService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuthServiceMockRecorder is the mock recorder for MockAuthService.
+type MockAuthServiceMockRecorder struct {
+	mock *MockAuthService
+}
+
+// NewMockAuthService creates a new mock instance.
+func NewMockAuthService(ctrl *gomock.Controller) *MockAuthService {
+	mock := &MockAuthService{ctrl: ctrl}
+	mock.recorder = &MockAuthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthService) EXPECT() *MockAuthServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateJWT mocks base method.
+func (m *MockAuthService) AuthenticateJWT(ctx context.Context, userJWT string, nonce, sig []byte) (*domain.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateJWT", ctx, userJWT, nonce, sig)
+	ret0, _ := ret[0].(*domain.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateJWT indicates an expected call of AuthenticateJWT.
+func (mr *MockAuthServiceMockRecorder) AuthenticateJWT(ctx, userJWT, nonce, sig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateJWT", reflect.TypeOf((*MockAuthService)(nil).AuthenticateJWT), ctx, userJWT, nonce, sig)
+}
+
+// AuthenticateToken mocks base method.
+func (m *MockAuthService) AuthenticateToken(ctx context.Context, token string) (*domain.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateToken", ctx, token)
+	ret0, _ := ret[0].(*domain.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateToken indicates an expected call of AuthenticateToken.
+func (mr *MockAuthServiceMockRecorder) AuthenticateToken(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateToken", reflect.TypeOf((*MockAuthService)(nil).AuthenticateToken), ctx, token)
+}
+
+// CreateToken mocks base method.
+func (m *MockAuthService) CreateToken(ctx context.Context, identity domain.Identity, ttl time.Duration) (string, *domain.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, identity, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(*domain.APIToken)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockAuthServiceMockRecorder) CreateToken(ctx, identity, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockAuthService)(nil).CreateToken), ctx, identity, ttl)
+}
+
+// ListTokens mocks base method.
+func (m *MockAuthService) ListTokens(ctx context.Context) ([]*domain.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTokens", ctx)
+	ret0, _ := ret[0].([]*domain.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTokens indicates an expected call of ListTokens.
+func (mr *MockAuthServiceMockRecorder) ListTokens(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTokens", reflect.TypeOf((*MockAuthService)(nil).ListTokens), ctx)
+}
+
+// RevokeToken mocks base method.
+func (m *MockAuthService) RevokeToken(ctx context.Context, id domain.APITokenID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockAuthServiceMockRecorder) RevokeToken(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockAuthService)(nil).RevokeToken), ctx, id)
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	io "io"
 	domain "nodemgr/internal/core/domain"
 	port "nodemgr/internal/core/port"
@@ -386,74 +387,74 @@ func (m *MockNodeExecuteService) EXPECT() *MockNodeExecuteServiceMockRecorder {
 }
 
 // Attach mocks base method.
-func (m *MockNodeExecuteService) Attach(req domain.AttachRequest) (*domain.AttachResult, error) {
+func (m *MockNodeExecuteService) Attach(ctx context.Context, req domain.AttachRequest) (*domain.AttachResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Attach", req)
+	ret := m.ctrl.Call(m, "Attach", ctx, req)
 	ret0, _ := ret[0].(*domain.AttachResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Attach indicates an expected call of Attach.
-func (mr *MockNodeExecuteServiceMockRecorder) Attach(req any) *gomock.Call {
+func (mr *MockNodeExecuteServiceMockRecorder) Attach(ctx, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attach", reflect.TypeOf((*MockNodeExecuteService)(nil).Attach), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Attach", reflect.TypeOf((*MockNodeExecuteService)(nil).Attach), ctx, req)
 }
 
 // CopyFrom mocks base method.
-func (m *MockNodeExecuteService) CopyFrom(req domain.CopyFromRequest) error {
+func (m *MockNodeExecuteService) CopyFrom(ctx context.Context, req domain.CopyFromRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CopyFrom", req)
+	ret := m.ctrl.Call(m, "CopyFrom", ctx, req)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CopyFrom indicates an expected call of CopyFrom.
-func (mr *MockNodeExecuteServiceMockRecorder) CopyFrom(req any) *gomock.Call {
+func (mr *MockNodeExecuteServiceMockRecorder) CopyFrom(ctx, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyFrom", reflect.TypeOf((*MockNodeExecuteService)(nil).CopyFrom), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyFrom", reflect.TypeOf((*MockNodeExecuteService)(nil).CopyFrom), ctx, req)
 }
 
 // CopyTo mocks base method.
-func (m *MockNodeExecuteService) CopyTo(req domain.CopyToRequest) error {
+func (m *MockNodeExecuteService) CopyTo(ctx context.Context, req domain.CopyToRequest) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CopyTo", req)
+	ret := m.ctrl.Call(m, "CopyTo", ctx, req)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CopyTo indicates an expected call of CopyTo.
-func (mr *MockNodeExecuteServiceMockRecorder) CopyTo(req any) *gomock.Call {
+func (mr *MockNodeExecuteServiceMockRecorder) CopyTo(ctx, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyTo", reflect.TypeOf((*MockNodeExecuteService)(nil).CopyTo), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyTo", reflect.TypeOf((*MockNodeExecuteService)(nil).CopyTo), ctx, req)
 }
 
 // Exec mocks base method.
-func (m *MockNodeExecuteService) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+func (m *MockNodeExecuteService) Exec(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Exec", req)
+	ret := m.ctrl.Call(m, "Exec", ctx, req)
 	ret0, _ := ret[0].(*domain.ExecResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Exec indicates an expected call of Exec.
-func (mr *MockNodeExecuteServiceMockRecorder) Exec(req any) *gomock.Call {
+func (mr *MockNodeExecuteServiceMockRecorder) Exec(ctx, req any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockNodeExecuteService)(nil).Exec), req)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockNodeExecuteService)(nil).Exec), ctx, req)
 }
 
 // ExecStream mocks base method.
-func (m *MockNodeExecuteService) ExecStream(exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
+func (m *MockNodeExecuteService) ExecStream(ctx context.Context, exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExecStream", exec, attach)
+	ret := m.ctrl.Call(m, "ExecStream", ctx, exec, attach)
 	ret0, _ := ret[0].(*domain.AttachResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ExecStream indicates an expected call of ExecStream.
-func (mr *MockNodeExecuteServiceMockRecorder) ExecStream(exec, attach any) *gomock.Call {
+func (mr *MockNodeExecuteServiceMockRecorder) ExecStream(ctx, exec, attach any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecStream", reflect.TypeOf((*MockNodeExecuteService)(nil).ExecStream), exec, attach)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecStream", reflect.TypeOf((*MockNodeExecuteService)(nil).ExecStream), ctx, exec, attach)
 }
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/lease.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/lease.go -destination=internal/core/port/mocks/lease_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNodeLeaseService is a mock of NodeLeaseService interface.
+type MockNodeLeaseService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodeLeaseServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockNodeLeaseServiceMockRecorder is the mock recorder for MockNodeLeaseService.
+type MockNodeLeaseServiceMockRecorder struct {
+	mock *MockNodeLeaseService
+}
+
+// NewMockNodeLeaseService creates a new mock instance.
+func NewMockNodeLeaseService(ctrl *gomock.Controller) *MockNodeLeaseService {
+	mock := &MockNodeLeaseService{ctrl: ctrl}
+	mock.recorder = &MockNodeLeaseServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodeLeaseService) EXPECT() *MockNodeLeaseServiceMockRecorder {
+	return m.recorder
+}
+
+// Heartbeat mocks base method.
+func (m *MockNodeLeaseService) Heartbeat(ctx context.Context, nodeID domain.NodeID, extend time.Duration) (*domain.NodeLease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", ctx, nodeID, extend)
+	ret0, _ := ret[0].(*domain.NodeLease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Heartbeat indicates an expected call of Heartbeat.
+func (mr *MockNodeLeaseServiceMockRecorder) Heartbeat(ctx, nodeID, extend any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockNodeLeaseService)(nil).Heartbeat), ctx, nodeID, extend)
+}
+
+// Run mocks base method.
+func (m *MockNodeLeaseService) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockNodeLeaseServiceMockRecorder) Run(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockNodeLeaseService)(nil).Run), ctx)
+}
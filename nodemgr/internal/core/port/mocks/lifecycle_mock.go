@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	domain "nodemgr/internal/core/domain"
 	port "nodemgr/internal/core/port"
 	reflect "reflect"
@@ -17,6 +18,88 @@ import (
 	gomock "go.uber.org/mock/gomock"
 )
 
+// MockNodeLifecycleRepository is a mock of NodeLifecycleRepository interface.
+type MockNodeLifecycleRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodeLifecycleRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockNodeLifecycleRepositoryMockRecorder is the mock recorder for MockNodeLifecycleRepository.
+type MockNodeLifecycleRepositoryMockRecorder struct {
+	mock *MockNodeLifecycleRepository
+}
+
+// NewMockNodeLifecycleRepository creates a new mock instance.
+func NewMockNodeLifecycleRepository(ctrl *gomock.Controller) *MockNodeLifecycleRepository {
+	mock := &MockNodeLifecycleRepository{ctrl: ctrl}
+	mock.recorder = &MockNodeLifecycleRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodeLifecycleRepository) EXPECT() *MockNodeLifecycleRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockNodeLifecycleRepository) Create(lifecycle port.NodeLifecycle) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", lifecycle)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockNodeLifecycleRepositoryMockRecorder) Create(lifecycle any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockNodeLifecycleRepository)(nil).Create), lifecycle)
+}
+
+// Delete mocks base method.
+func (m *MockNodeLifecycleRepository) Delete(id domain.LifecycleProviderID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockNodeLifecycleRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNodeLifecycleRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockNodeLifecycleRepository) Get(id domain.LifecycleProviderID) (*port.NodeLifecycle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*port.NodeLifecycle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockNodeLifecycleRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockNodeLifecycleRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockNodeLifecycleRepository) List() ([]*port.NodeLifecycle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*port.NodeLifecycle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockNodeLifecycleRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockNodeLifecycleRepository)(nil).List))
+}
+
 // MockNodeLifecycle is a mock of NodeLifecycle interface.
 type MockNodeLifecycle struct {
 	ctrl     *gomock.Controller
@@ -41,6 +124,20 @@ func (m *MockNodeLifecycle) EXPECT() *MockNodeLifecycleMockRecorder {
 	return m.recorder
 }
 
+// ID mocks base method.
+func (m *MockNodeLifecycle) ID() domain.LifecycleProviderID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ID")
+	ret0, _ := ret[0].(domain.LifecycleProviderID)
+	return ret0
+}
+
+// ID indicates an expected call of ID.
+func (mr *MockNodeLifecycleMockRecorder) ID() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ID", reflect.TypeOf((*MockNodeLifecycle)(nil).ID))
+}
+
 // OpenLifecycleHandle mocks base method.
 func (m *MockNodeLifecycle) OpenLifecycleHandle(node *domain.Node) (port.NodeLifecycleHandle, error) {
 	m.ctrl.T.Helper()
@@ -80,52 +177,60 @@ func (m *MockNodeLifecycleHandle) EXPECT() *MockNodeLifecycleHandleMockRecorder
 	return m.recorder
 }
 
-// Reboot mocks base method.
-func (m *MockNodeLifecycleHandle) Reboot() {
+// Close mocks base method.
+func (m *MockNodeLifecycleHandle) Close() error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Reboot")
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Reboot indicates an expected call of Reboot.
-func (mr *MockNodeLifecycleHandleMockRecorder) Reboot() *gomock.Call {
+// Close indicates an expected call of Close.
+func (mr *MockNodeLifecycleHandleMockRecorder) Close() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reboot", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Reboot))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Close))
 }
 
-// Start mocks base method.
-func (m *MockNodeLifecycleHandle) Start() {
+// Reboot mocks base method.
+func (m *MockNodeLifecycleHandle) Reboot(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Start")
+	ret := m.ctrl.Call(m, "Reboot", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Start indicates an expected call of Start.
-func (mr *MockNodeLifecycleHandleMockRecorder) Start() *gomock.Call {
+// Reboot indicates an expected call of Reboot.
+func (mr *MockNodeLifecycleHandleMockRecorder) Reboot(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Start))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reboot", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Reboot), ctx)
 }
 
-// Stop mocks base method.
-func (m *MockNodeLifecycleHandle) Stop() {
+// Start mocks base method.
+func (m *MockNodeLifecycleHandle) Start(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Stop")
+	ret := m.ctrl.Call(m, "Start", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Stop indicates an expected call of Stop.
-func (mr *MockNodeLifecycleHandleMockRecorder) Stop() *gomock.Call {
+// Start indicates an expected call of Start.
+func (mr *MockNodeLifecycleHandleMockRecorder) Start(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Stop))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Start), ctx)
 }
 
-// Terminate mocks base method.
-func (m *MockNodeLifecycleHandle) Terminate() {
+// Stop mocks base method.
+func (m *MockNodeLifecycleHandle) Stop(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Terminate")
+	ret := m.ctrl.Call(m, "Stop", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Terminate indicates an expected call of Terminate.
-func (mr *MockNodeLifecycleHandleMockRecorder) Terminate() *gomock.Call {
+// Stop indicates an expected call of Stop.
+func (mr *MockNodeLifecycleHandleMockRecorder) Stop(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Terminate", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Terminate))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockNodeLifecycleHandle)(nil).Stop), ctx)
 }
 
 // MockNodeLifecycleService is a mock of NodeLifecycleService interface.
@@ -153,57 +258,57 @@ func (m *MockNodeLifecycleService) EXPECT() *MockNodeLifecycleServiceMockRecorde
 }
 
 // RebootNode mocks base method.
-func (m *MockNodeLifecycleService) RebootNode(nodeID domain.NodeID) error {
+func (m *MockNodeLifecycleService) RebootNode(ctx context.Context, nodeID domain.NodeID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RebootNode", nodeID)
+	ret := m.ctrl.Call(m, "RebootNode", ctx, nodeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RebootNode indicates an expected call of RebootNode.
-func (mr *MockNodeLifecycleServiceMockRecorder) RebootNode(nodeID any) *gomock.Call {
+func (mr *MockNodeLifecycleServiceMockRecorder) RebootNode(ctx, nodeID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebootNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).RebootNode), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RebootNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).RebootNode), ctx, nodeID)
 }
 
 // StartNode mocks base method.
-func (m *MockNodeLifecycleService) StartNode(nodeID domain.NodeID) error {
+func (m *MockNodeLifecycleService) StartNode(ctx context.Context, nodeID domain.NodeID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StartNode", nodeID)
+	ret := m.ctrl.Call(m, "StartNode", ctx, nodeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // StartNode indicates an expected call of StartNode.
-func (mr *MockNodeLifecycleServiceMockRecorder) StartNode(nodeID any) *gomock.Call {
+func (mr *MockNodeLifecycleServiceMockRecorder) StartNode(ctx, nodeID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).StartNode), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).StartNode), ctx, nodeID)
 }
 
 // StopNode mocks base method.
-func (m *MockNodeLifecycleService) StopNode(nodeID domain.NodeID) error {
+func (m *MockNodeLifecycleService) StopNode(ctx context.Context, nodeID domain.NodeID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StopNode", nodeID)
+	ret := m.ctrl.Call(m, "StopNode", ctx, nodeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // StopNode indicates an expected call of StopNode.
-func (mr *MockNodeLifecycleServiceMockRecorder) StopNode(nodeID any) *gomock.Call {
+func (mr *MockNodeLifecycleServiceMockRecorder) StopNode(ctx, nodeID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).StopNode), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).StopNode), ctx, nodeID)
 }
 
 // TerminateNode mocks base method.
-func (m *MockNodeLifecycleService) TerminateNode(nodeID domain.NodeID) error {
+func (m *MockNodeLifecycleService) TerminateNode(ctx context.Context, nodeID domain.NodeID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TerminateNode", nodeID)
+	ret := m.ctrl.Call(m, "TerminateNode", ctx, nodeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // TerminateNode indicates an expected call of TerminateNode.
-func (mr *MockNodeLifecycleServiceMockRecorder) TerminateNode(nodeID any) *gomock.Call {
+func (mr *MockNodeLifecycleServiceMockRecorder) TerminateNode(ctx, nodeID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).TerminateNode), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TerminateNode", reflect.TypeOf((*MockNodeLifecycleService)(nil).TerminateNode), ctx, nodeID)
 }
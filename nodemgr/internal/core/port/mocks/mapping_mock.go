@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	domain "nodemgr/internal/core/domain"
 	reflect "reflect"
 
@@ -123,75 +124,75 @@ func (m *MockMappingService) EXPECT() *MockMappingServiceMockRecorder {
 }
 
 // CreateMapping mocks base method.
-func (m *MockMappingService) CreateMapping(mapping domain.NodeSpecMapping) (domain.MappingID, error) {
+func (m *MockMappingService) CreateMapping(ctx context.Context, mapping domain.NodeSpecMapping) (domain.MappingID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateMapping", mapping)
+	ret := m.ctrl.Call(m, "CreateMapping", ctx, mapping)
 	ret0, _ := ret[0].(domain.MappingID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateMapping indicates an expected call of CreateMapping.
-func (mr *MockMappingServiceMockRecorder) CreateMapping(mapping any) *gomock.Call {
+func (mr *MockMappingServiceMockRecorder) CreateMapping(ctx, mapping any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMapping", reflect.TypeOf((*MockMappingService)(nil).CreateMapping), mapping)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMapping", reflect.TypeOf((*MockMappingService)(nil).CreateMapping), ctx, mapping)
 }
 
 // DeleteMapping mocks base method.
-func (m *MockMappingService) DeleteMapping(id domain.MappingID) error {
+func (m *MockMappingService) DeleteMapping(ctx context.Context, id domain.MappingID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteMapping", id)
+	ret := m.ctrl.Call(m, "DeleteMapping", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteMapping indicates an expected call of DeleteMapping.
-func (mr *MockMappingServiceMockRecorder) DeleteMapping(id any) *gomock.Call {
+func (mr *MockMappingServiceMockRecorder) DeleteMapping(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMapping", reflect.TypeOf((*MockMappingService)(nil).DeleteMapping), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMapping", reflect.TypeOf((*MockMappingService)(nil).DeleteMapping), ctx, id)
 }
 
 // GetMapping mocks base method.
-func (m *MockMappingService) GetMapping(id domain.MappingID) (*domain.NodeSpecMapping, error) {
+func (m *MockMappingService) GetMapping(ctx context.Context, id domain.MappingID) (*domain.NodeSpecMapping, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMapping", id)
+	ret := m.ctrl.Call(m, "GetMapping", ctx, id)
 	ret0, _ := ret[0].(*domain.NodeSpecMapping)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMapping indicates an expected call of GetMapping.
-func (mr *MockMappingServiceMockRecorder) GetMapping(id any) *gomock.Call {
+func (mr *MockMappingServiceMockRecorder) GetMapping(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMapping", reflect.TypeOf((*MockMappingService)(nil).GetMapping), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMapping", reflect.TypeOf((*MockMappingService)(nil).GetMapping), ctx, id)
 }
 
 // ListMappings mocks base method.
-func (m *MockMappingService) ListMappings() ([]*domain.NodeSpecMapping, error) {
+func (m *MockMappingService) ListMappings(ctx context.Context) ([]*domain.NodeSpecMapping, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListMappings")
+	ret := m.ctrl.Call(m, "ListMappings", ctx)
 	ret0, _ := ret[0].([]*domain.NodeSpecMapping)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListMappings indicates an expected call of ListMappings.
-func (mr *MockMappingServiceMockRecorder) ListMappings() *gomock.Call {
+func (mr *MockMappingServiceMockRecorder) ListMappings(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMappings", reflect.TypeOf((*MockMappingService)(nil).ListMappings))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMappings", reflect.TypeOf((*MockMappingService)(nil).ListMappings), ctx)
 }
 
 // ResolveSpecAliases mocks base method.
-func (m *MockMappingService) ResolveSpecAliases(spec domain.NodeSpec) (domain.NodeSpec, error) {
+func (m *MockMappingService) ResolveSpecAliases(ctx context.Context, spec domain.NodeSpec) (domain.NodeSpec, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ResolveSpecAliases", spec)
+	ret := m.ctrl.Call(m, "ResolveSpecAliases", ctx, spec)
 	ret0, _ := ret[0].(domain.NodeSpec)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ResolveSpecAliases indicates an expected call of ResolveSpecAliases.
-func (mr *MockMappingServiceMockRecorder) ResolveSpecAliases(spec any) *gomock.Call {
+func (mr *MockMappingServiceMockRecorder) ResolveSpecAliases(ctx, spec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveSpecAliases", reflect.TypeOf((*MockMappingService)(nil).ResolveSpecAliases), spec)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveSpecAliases", reflect.TypeOf((*MockMappingService)(nil).ResolveSpecAliases), ctx, spec)
 }
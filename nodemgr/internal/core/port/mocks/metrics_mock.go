@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/metrics.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/metrics.go -destination=internal/core/port/mocks/metrics_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMetricsRecorder is a mock of MetricsRecorder interface.
+type MockMetricsRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockMetricsRecorderMockRecorder
+	isgomock struct{}
+}
+
+// MockMetricsRecorderMockRecorder is the mock recorder for MockMetricsRecorder.
+type MockMetricsRecorderMockRecorder struct {
+	mock *MockMetricsRecorder
+}
+
+// NewMockMetricsRecorder creates a new mock instance.
+func NewMockMetricsRecorder(ctrl *gomock.Controller) *MockMetricsRecorder {
+	mock := &MockMetricsRecorder{ctrl: ctrl}
+	mock.recorder = &MockMetricsRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMetricsRecorder) EXPECT() *MockMetricsRecorderMockRecorder {
+	return m.recorder
+}
+
+// ObserveDestroy mocks base method.
+func (m *MockMetricsRecorder) ObserveDestroy(providerID domain.ProviderID, duration time.Duration, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveDestroy", providerID, duration, err)
+}
+
+// ObserveDestroy indicates an expected call of ObserveDestroy.
+func (mr *MockMetricsRecorderMockRecorder) ObserveDestroy(providerID, duration, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveDestroy", reflect.TypeOf((*MockMetricsRecorder)(nil).ObserveDestroy), providerID, duration, err)
+}
+
+// ObserveExec mocks base method.
+func (m *MockMetricsRecorder) ObserveExec(providerID domain.ProviderID, duration time.Duration, exitCode int, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveExec", providerID, duration, exitCode, err)
+}
+
+// ObserveExec indicates an expected call of ObserveExec.
+func (mr *MockMetricsRecorderMockRecorder) ObserveExec(providerID, duration, exitCode, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveExec", reflect.TypeOf((*MockMetricsRecorder)(nil).ObserveExec), providerID, duration, exitCode, err)
+}
+
+// ObserveProvision mocks base method.
+func (m *MockMetricsRecorder) ObserveProvision(providerID domain.ProviderID, duration time.Duration, err error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveProvision", providerID, duration, err)
+}
+
+// ObserveProvision indicates an expected call of ObserveProvision.
+func (mr *MockMetricsRecorderMockRecorder) ObserveProvision(providerID, duration, err any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveProvision", reflect.TypeOf((*MockMetricsRecorder)(nil).ObserveProvision), providerID, duration, err)
+}
@@ -0,0 +1,253 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/operation.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/operation.go -destination=internal/core/port/mocks/operation_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOperationRepository is a mock of OperationRepository interface.
+type MockOperationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockOperationRepositoryMockRecorder is the mock recorder for MockOperationRepository.
+type MockOperationRepositoryMockRecorder struct {
+	mock *MockOperationRepository
+}
+
+// NewMockOperationRepository creates a new mock instance.
+func NewMockOperationRepository(ctrl *gomock.Controller) *MockOperationRepository {
+	mock := &MockOperationRepository{ctrl: ctrl}
+	mock.recorder = &MockOperationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOperationRepository) EXPECT() *MockOperationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockOperationRepository) Create(op domain.Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", op)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockOperationRepositoryMockRecorder) Create(op any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockOperationRepository)(nil).Create), op)
+}
+
+// Delete mocks base method.
+func (m *MockOperationRepository) Delete(id domain.OperationID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockOperationRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockOperationRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockOperationRepository) Get(id domain.OperationID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockOperationRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockOperationRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockOperationRepository) List() ([]*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockOperationRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockOperationRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockOperationRepository) Update(op domain.Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", op)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockOperationRepositoryMockRecorder) Update(op any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockOperationRepository)(nil).Update), op)
+}
+
+// MockOperationService is a mock of OperationService interface.
+type MockOperationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockOperationServiceMockRecorder is the mock recorder for MockOperationService.
+type MockOperationServiceMockRecorder struct {
+	mock *MockOperationService
+}
+
+// NewMockOperationService creates a new mock instance.
+func NewMockOperationService(ctrl *gomock.Controller) *MockOperationService {
+	mock := &MockOperationService{ctrl: ctrl}
+	mock.recorder = &MockOperationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOperationService) EXPECT() *MockOperationServiceMockRecorder {
+	return m.recorder
+}
+
+// CancelOperation mocks base method.
+func (m *MockOperationService) CancelOperation(ctx context.Context, id domain.OperationID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelOperation", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelOperation indicates an expected call of CancelOperation.
+func (mr *MockOperationServiceMockRecorder) CancelOperation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelOperation", reflect.TypeOf((*MockOperationService)(nil).CancelOperation), ctx, id)
+}
+
+// GetOperation mocks base method.
+func (m *MockOperationService) GetOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOperation", ctx, id)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOperation indicates an expected call of GetOperation.
+func (mr *MockOperationServiceMockRecorder) GetOperation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockOperationService)(nil).GetOperation), ctx, id)
+}
+
+// ListOperations mocks base method.
+func (m *MockOperationService) ListOperations(ctx context.Context) ([]*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOperations", ctx)
+	ret0, _ := ret[0].([]*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOperations indicates an expected call of ListOperations.
+func (mr *MockOperationServiceMockRecorder) ListOperations(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOperations", reflect.TypeOf((*MockOperationService)(nil).ListOperations), ctx)
+}
+
+// RecordAttempt mocks base method.
+func (m *MockOperationService) RecordAttempt(ctx context.Context, attempt domain.OperationAttempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAttempt", ctx, attempt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAttempt indicates an expected call of RecordAttempt.
+func (mr *MockOperationServiceMockRecorder) RecordAttempt(ctx, attempt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAttempt", reflect.TypeOf((*MockOperationService)(nil).RecordAttempt), ctx, attempt)
+}
+
+// Run mocks base method.
+func (m *MockOperationService) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockOperationServiceMockRecorder) Run(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockOperationService)(nil).Run), ctx)
+}
+
+// StartOperation mocks base method.
+func (m *MockOperationService) StartOperation(ctx context.Context, kind domain.OperationKind, nodeID domain.NodeID, tenantID domain.TenantID, slots chan struct{}, fn func(context.Context) error) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartOperation", ctx, kind, nodeID, tenantID, slots, fn)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartOperation indicates an expected call of StartOperation.
+func (mr *MockOperationServiceMockRecorder) StartOperation(ctx, kind, nodeID, tenantID, slots, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartOperation", reflect.TypeOf((*MockOperationService)(nil).StartOperation), ctx, kind, nodeID, tenantID, slots, fn)
+}
+
+// WaitOperation mocks base method.
+func (m *MockOperationService) WaitOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitOperation", ctx, id)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitOperation indicates an expected call of WaitOperation.
+func (mr *MockOperationServiceMockRecorder) WaitOperation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitOperation", reflect.TypeOf((*MockOperationService)(nil).WaitOperation), ctx, id)
+}
+
+// WatchOperation mocks base method.
+func (m *MockOperationService) WatchOperation(ctx context.Context, id domain.OperationID) (<-chan domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchOperation", ctx, id)
+	ret0, _ := ret[0].(<-chan domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchOperation indicates an expected call of WatchOperation.
+func (mr *MockOperationServiceMockRecorder) WatchOperation(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchOperation", reflect.TypeOf((*MockOperationService)(nil).WatchOperation), ctx, id)
+}
@@ -0,0 +1,342 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/pool.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/pool.go -destination=internal/core/port/mocks/pool_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockNodePoolRepository is a mock of NodePoolRepository interface.
+type MockNodePoolRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodePoolRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockNodePoolRepositoryMockRecorder is the mock recorder for MockNodePoolRepository.
+type MockNodePoolRepositoryMockRecorder struct {
+	mock *MockNodePoolRepository
+}
+
+// NewMockNodePoolRepository creates a new mock instance.
+func NewMockNodePoolRepository(ctrl *gomock.Controller) *MockNodePoolRepository {
+	mock := &MockNodePoolRepository{ctrl: ctrl}
+	mock.recorder = &MockNodePoolRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodePoolRepository) EXPECT() *MockNodePoolRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockNodePoolRepository) Create(pool domain.NodePool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", pool)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockNodePoolRepositoryMockRecorder) Create(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockNodePoolRepository)(nil).Create), pool)
+}
+
+// Delete mocks base method.
+func (m *MockNodePoolRepository) Delete(id domain.PoolID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockNodePoolRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockNodePoolRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockNodePoolRepository) Get(id domain.PoolID) (*domain.NodePool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.NodePool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockNodePoolRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockNodePoolRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockNodePoolRepository) List() ([]*domain.NodePool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.NodePool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockNodePoolRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockNodePoolRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockNodePoolRepository) Update(pool domain.NodePool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", pool)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockNodePoolRepositoryMockRecorder) Update(pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockNodePoolRepository)(nil).Update), pool)
+}
+
+// MockNodePoolService is a mock of NodePoolService interface.
+type MockNodePoolService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodePoolServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockNodePoolServiceMockRecorder is the mock recorder for MockNodePoolService.
+type MockNodePoolServiceMockRecorder struct {
+	mock *MockNodePoolService
+}
+
+// NewMockNodePoolService creates a new mock instance.
+func NewMockNodePoolService(ctrl *gomock.Controller) *MockNodePoolService {
+	mock := &MockNodePoolService{ctrl: ctrl}
+	mock.recorder = &MockNodePoolServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodePoolService) EXPECT() *MockNodePoolServiceMockRecorder {
+	return m.recorder
+}
+
+// CreatePool mocks base method.
+func (m *MockNodePoolService) CreatePool(ctx context.Context, pool domain.NodePool) (domain.PoolID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePool", ctx, pool)
+	ret0, _ := ret[0].(domain.PoolID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePool indicates an expected call of CreatePool.
+func (mr *MockNodePoolServiceMockRecorder) CreatePool(ctx, pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePool", reflect.TypeOf((*MockNodePoolService)(nil).CreatePool), ctx, pool)
+}
+
+// DeletePool mocks base method.
+func (m *MockNodePoolService) DeletePool(ctx context.Context, id domain.PoolID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePool", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePool indicates an expected call of DeletePool.
+func (mr *MockNodePoolServiceMockRecorder) DeletePool(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePool", reflect.TypeOf((*MockNodePoolService)(nil).DeletePool), ctx, id)
+}
+
+// DestroyNode mocks base method.
+func (m *MockNodePoolService) DestroyNode(ctx context.Context, nodeID domain.NodeID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyNode", ctx, nodeID)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DestroyNode indicates an expected call of DestroyNode.
+func (mr *MockNodePoolServiceMockRecorder) DestroyNode(ctx, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyNode", reflect.TypeOf((*MockNodePoolService)(nil).DestroyNode), ctx, nodeID)
+}
+
+// GetNode mocks base method.
+func (m *MockNodePoolService) GetNode(ctx context.Context, nodeID domain.NodeID) (*domain.Node, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNode", ctx, nodeID)
+	ret0, _ := ret[0].(*domain.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNode indicates an expected call of GetNode.
+func (mr *MockNodePoolServiceMockRecorder) GetNode(ctx, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNode", reflect.TypeOf((*MockNodePoolService)(nil).GetNode), ctx, nodeID)
+}
+
+// GetPoolStatus mocks base method.
+func (m *MockNodePoolService) GetPoolStatus(ctx context.Context, id domain.PoolID) (*domain.NodePoolStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPoolStatus", ctx, id)
+	ret0, _ := ret[0].(*domain.NodePoolStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPoolStatus indicates an expected call of GetPoolStatus.
+func (mr *MockNodePoolServiceMockRecorder) GetPoolStatus(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPoolStatus", reflect.TypeOf((*MockNodePoolService)(nil).GetPoolStatus), ctx, id)
+}
+
+// ListNodes mocks base method.
+func (m *MockNodePoolService) ListNodes(ctx context.Context) ([]*domain.Node, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodes", ctx)
+	ret0, _ := ret[0].([]*domain.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodes indicates an expected call of ListNodes.
+func (mr *MockNodePoolServiceMockRecorder) ListNodes(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodes", reflect.TypeOf((*MockNodePoolService)(nil).ListNodes), ctx)
+}
+
+// ListPools mocks base method.
+func (m *MockNodePoolService) ListPools(ctx context.Context) ([]*domain.NodePool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPools", ctx)
+	ret0, _ := ret[0].([]*domain.NodePool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPools indicates an expected call of ListPools.
+func (mr *MockNodePoolServiceMockRecorder) ListPools(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPools", reflect.TypeOf((*MockNodePoolService)(nil).ListPools), ctx)
+}
+
+// PrefetchTemplateImages mocks base method.
+func (m *MockNodePoolService) PrefetchTemplateImages(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrefetchTemplateImages", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PrefetchTemplateImages indicates an expected call of PrefetchTemplateImages.
+func (mr *MockNodePoolServiceMockRecorder) PrefetchTemplateImages(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchTemplateImages", reflect.TypeOf((*MockNodePoolService)(nil).PrefetchTemplateImages), ctx)
+}
+
+// ProvisionFromTemplate mocks base method.
+func (m *MockNodePoolService) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionFromTemplate", ctx, templateID, providerID, tenantID)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvisionFromTemplate indicates an expected call of ProvisionFromTemplate.
+func (mr *MockNodePoolServiceMockRecorder) ProvisionFromTemplate(ctx, templateID, providerID, tenantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionFromTemplate", reflect.TypeOf((*MockNodePoolService)(nil).ProvisionFromTemplate), ctx, templateID, providerID, tenantID)
+}
+
+// ProvisionNode mocks base method.
+func (m *MockNodePoolService) ProvisionNode(ctx context.Context, spec domain.NodeSpec) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionNode", ctx, spec)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvisionNode indicates an expected call of ProvisionNode.
+func (mr *MockNodePoolServiceMockRecorder) ProvisionNode(ctx, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNode", reflect.TypeOf((*MockNodePoolService)(nil).ProvisionNode), ctx, spec)
+}
+
+// ProvisionNodes mocks base method.
+func (m *MockNodePoolService) ProvisionNodes(ctx context.Context, spec domain.NodeSpec, count, parallelism int) ([]*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionNodes", ctx, spec, count, parallelism)
+	ret0, _ := ret[0].([]*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvisionNodes indicates an expected call of ProvisionNodes.
+func (mr *MockNodePoolServiceMockRecorder) ProvisionNodes(ctx, spec, count, parallelism any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNodes", reflect.TypeOf((*MockNodePoolService)(nil).ProvisionNodes), ctx, spec, count, parallelism)
+}
+
+// Run mocks base method.
+func (m *MockNodePoolService) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockNodePoolServiceMockRecorder) Run(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockNodePoolService)(nil).Run), ctx)
+}
+
+// TemplateResources mocks base method.
+func (m *MockNodePoolService) TemplateResources(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.Resources, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TemplateResources", ctx, templateID, providerID, tenantID)
+	ret0, _ := ret[0].(domain.Resources)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TemplateResources indicates an expected call of TemplateResources.
+func (mr *MockNodePoolServiceMockRecorder) TemplateResources(ctx, templateID, providerID, tenantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TemplateResources", reflect.TypeOf((*MockNodePoolService)(nil).TemplateResources), ctx, templateID, providerID, tenantID)
+}
+
+// UpdatePool mocks base method.
+func (m *MockNodePoolService) UpdatePool(ctx context.Context, pool domain.NodePool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePool", ctx, pool)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePool indicates an expected call of UpdatePool.
+func (mr *MockNodePoolServiceMockRecorder) UpdatePool(ctx, pool any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePool", reflect.TypeOf((*MockNodePoolService)(nil).UpdatePool), ctx, pool)
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	domain "nodemgr/internal/core/domain"
 	port "nodemgr/internal/core/port"
 	reflect "reflect"
@@ -99,6 +100,21 @@ func (mr *MockNodeRepositoryMockRecorder) List() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockNodeRepository)(nil).List))
 }
 
+// UpdateFunc mocks base method.
+func (m *MockNodeRepository) UpdateFunc(id domain.NodeID, fn func(*domain.Node) error) (*domain.Node, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFunc", id, fn)
+	ret0, _ := ret[0].(*domain.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateFunc indicates an expected call of UpdateFunc.
+func (mr *MockNodeRepositoryMockRecorder) UpdateFunc(id, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFunc", reflect.TypeOf((*MockNodeRepository)(nil).UpdateFunc), id, fn)
+}
+
 // MockNodeProviderRepository is a mock of NodeProviderRepository interface.
 type MockNodeProviderRepository struct {
 	ctrl     *gomock.Controller
@@ -167,11 +183,12 @@ func (mr *MockNodeProviderRepositoryMockRecorder) Get(id any) *gomock.Call {
 }
 
 // List mocks base method.
-func (m *MockNodeProviderRepository) List() []*port.NodeProvider {
+func (m *MockNodeProviderRepository) List() ([]*port.NodeProvider, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "List")
 	ret0, _ := ret[0].([]*port.NodeProvider)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // List indicates an expected call of List.
@@ -205,17 +222,17 @@ func (m *MockNodeProvider) EXPECT() *MockNodeProviderMockRecorder {
 }
 
 // Destroy mocks base method.
-func (m *MockNodeProvider) Destroy(nodeID domain.NodeID) error {
+func (m *MockNodeProvider) Destroy(ctx context.Context, nodeID domain.NodeID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Destroy", nodeID)
+	ret := m.ctrl.Call(m, "Destroy", ctx, nodeID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Destroy indicates an expected call of Destroy.
-func (mr *MockNodeProviderMockRecorder) Destroy(nodeID any) *gomock.Call {
+func (mr *MockNodeProviderMockRecorder) Destroy(ctx, nodeID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Destroy", reflect.TypeOf((*MockNodeProvider)(nil).Destroy), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Destroy", reflect.TypeOf((*MockNodeProvider)(nil).Destroy), ctx, nodeID)
 }
 
 // ID mocks base method.
@@ -233,18 +250,133 @@ func (mr *MockNodeProviderMockRecorder) ID() *gomock.Call {
 }
 
 // Provision mocks base method.
-func (m *MockNodeProvider) Provision(spec domain.NodeSpec) (*domain.Node, error) {
+func (m *MockNodeProvider) Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Provision", spec)
+	ret := m.ctrl.Call(m, "Provision", ctx, nodeID, spec)
 	ret0, _ := ret[0].(*domain.Node)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Provision indicates an expected call of Provision.
-func (mr *MockNodeProviderMockRecorder) Provision(spec any) *gomock.Call {
+func (mr *MockNodeProviderMockRecorder) Provision(ctx, nodeID, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Provision", reflect.TypeOf((*MockNodeProvider)(nil).Provision), ctx, nodeID, spec)
+}
+
+// MockNodeImagePrefetcher is a mock of NodeImagePrefetcher interface.
+type MockNodeImagePrefetcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodeImagePrefetcherMockRecorder
+	isgomock struct{}
+}
+
+// MockNodeImagePrefetcherMockRecorder is the mock recorder for MockNodeImagePrefetcher.
+type MockNodeImagePrefetcherMockRecorder struct {
+	mock *MockNodeImagePrefetcher
+}
+
+// NewMockNodeImagePrefetcher creates a new mock instance.
+func NewMockNodeImagePrefetcher(ctrl *gomock.Controller) *MockNodeImagePrefetcher {
+	mock := &MockNodeImagePrefetcher{ctrl: ctrl}
+	mock.recorder = &MockNodeImagePrefetcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodeImagePrefetcher) EXPECT() *MockNodeImagePrefetcherMockRecorder {
+	return m.recorder
+}
+
+// PrefetchImage mocks base method.
+func (m *MockNodeImagePrefetcher) PrefetchImage(ctx context.Context, spec domain.NodeSpec) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrefetchImage", ctx, spec)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PrefetchImage indicates an expected call of PrefetchImage.
+func (mr *MockNodeImagePrefetcherMockRecorder) PrefetchImage(ctx, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchImage", reflect.TypeOf((*MockNodeImagePrefetcher)(nil).PrefetchImage), ctx, spec)
+}
+
+// MockNodeResourceEstimator is a mock of NodeResourceEstimator interface.
+type MockNodeResourceEstimator struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodeResourceEstimatorMockRecorder
+	isgomock struct{}
+}
+
+// MockNodeResourceEstimatorMockRecorder is the mock recorder for MockNodeResourceEstimator.
+type MockNodeResourceEstimatorMockRecorder struct {
+	mock *MockNodeResourceEstimator
+}
+
+// NewMockNodeResourceEstimator creates a new mock instance.
+func NewMockNodeResourceEstimator(ctrl *gomock.Controller) *MockNodeResourceEstimator {
+	mock := &MockNodeResourceEstimator{ctrl: ctrl}
+	mock.recorder = &MockNodeResourceEstimatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodeResourceEstimator) EXPECT() *MockNodeResourceEstimatorMockRecorder {
+	return m.recorder
+}
+
+// SpecResources mocks base method.
+func (m *MockNodeResourceEstimator) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpecResources", spec)
+	ret0, _ := ret[0].(domain.Resources)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpecResources indicates an expected call of SpecResources.
+func (mr *MockNodeResourceEstimatorMockRecorder) SpecResources(spec any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Provision", reflect.TypeOf((*MockNodeProvider)(nil).Provision), spec)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpecResources", reflect.TypeOf((*MockNodeResourceEstimator)(nil).SpecResources), spec)
+}
+
+// MockUnisolatedNodeProvider is a mock of UnisolatedNodeProvider interface.
+type MockUnisolatedNodeProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnisolatedNodeProviderMockRecorder
+	isgomock struct{}
+}
+
+// MockUnisolatedNodeProviderMockRecorder is the mock recorder for MockUnisolatedNodeProvider.
+type MockUnisolatedNodeProviderMockRecorder struct {
+	mock *MockUnisolatedNodeProvider
+}
+
+// NewMockUnisolatedNodeProvider creates a new mock instance.
+func NewMockUnisolatedNodeProvider(ctrl *gomock.Controller) *MockUnisolatedNodeProvider {
+	mock := &MockUnisolatedNodeProvider{ctrl: ctrl}
+	mock.recorder = &MockUnisolatedNodeProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnisolatedNodeProvider) EXPECT() *MockUnisolatedNodeProviderMockRecorder {
+	return m.recorder
+}
+
+// Unisolated mocks base method.
+func (m *MockUnisolatedNodeProvider) Unisolated() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unisolated")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Unisolated indicates an expected call of Unisolated.
+func (mr *MockUnisolatedNodeProviderMockRecorder) Unisolated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unisolated", reflect.TypeOf((*MockUnisolatedNodeProvider)(nil).Unisolated))
 }
 
 // MockNodeProvisionService is a mock of NodeProvisionService interface.
@@ -272,30 +404,120 @@ func (m *MockNodeProvisionService) EXPECT() *MockNodeProvisionServiceMockRecorde
 }
 
 // DestroyNode mocks base method.
-func (m *MockNodeProvisionService) DestroyNode(nodeID domain.NodeID) error {
+func (m *MockNodeProvisionService) DestroyNode(ctx context.Context, nodeID domain.NodeID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DestroyNode", ctx, nodeID)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DestroyNode indicates an expected call of DestroyNode.
+func (mr *MockNodeProvisionServiceMockRecorder) DestroyNode(ctx, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyNode", reflect.TypeOf((*MockNodeProvisionService)(nil).DestroyNode), ctx, nodeID)
+}
+
+// GetNode mocks base method.
+func (m *MockNodeProvisionService) GetNode(ctx context.Context, nodeID domain.NodeID) (*domain.Node, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNode", ctx, nodeID)
+	ret0, _ := ret[0].(*domain.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNode indicates an expected call of GetNode.
+func (mr *MockNodeProvisionServiceMockRecorder) GetNode(ctx, nodeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNode", reflect.TypeOf((*MockNodeProvisionService)(nil).GetNode), ctx, nodeID)
+}
+
+// ListNodes mocks base method.
+func (m *MockNodeProvisionService) ListNodes(ctx context.Context) ([]*domain.Node, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DestroyNode", nodeID)
+	ret := m.ctrl.Call(m, "ListNodes", ctx)
+	ret0, _ := ret[0].([]*domain.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodes indicates an expected call of ListNodes.
+func (mr *MockNodeProvisionServiceMockRecorder) ListNodes(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodes", reflect.TypeOf((*MockNodeProvisionService)(nil).ListNodes), ctx)
+}
+
+// PrefetchTemplateImages mocks base method.
+func (m *MockNodeProvisionService) PrefetchTemplateImages(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrefetchTemplateImages", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DestroyNode indicates an expected call of DestroyNode.
-func (mr *MockNodeProvisionServiceMockRecorder) DestroyNode(nodeID any) *gomock.Call {
+// PrefetchTemplateImages indicates an expected call of PrefetchTemplateImages.
+func (mr *MockNodeProvisionServiceMockRecorder) PrefetchTemplateImages(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrefetchTemplateImages", reflect.TypeOf((*MockNodeProvisionService)(nil).PrefetchTemplateImages), ctx)
+}
+
+// ProvisionFromTemplate mocks base method.
+func (m *MockNodeProvisionService) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionFromTemplate", ctx, templateID, providerID, tenantID)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvisionFromTemplate indicates an expected call of ProvisionFromTemplate.
+func (mr *MockNodeProvisionServiceMockRecorder) ProvisionFromTemplate(ctx, templateID, providerID, tenantID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroyNode", reflect.TypeOf((*MockNodeProvisionService)(nil).DestroyNode), nodeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionFromTemplate", reflect.TypeOf((*MockNodeProvisionService)(nil).ProvisionFromTemplate), ctx, templateID, providerID, tenantID)
 }
 
 // ProvisionNode mocks base method.
-func (m *MockNodeProvisionService) ProvisionNode(spec domain.NodeSpec) (*domain.Node, error) {
+func (m *MockNodeProvisionService) ProvisionNode(ctx context.Context, spec domain.NodeSpec) (*domain.Operation, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProvisionNode", spec)
-	ret0, _ := ret[0].(*domain.Node)
+	ret := m.ctrl.Call(m, "ProvisionNode", ctx, spec)
+	ret0, _ := ret[0].(*domain.Operation)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProvisionNode indicates an expected call of ProvisionNode.
-func (mr *MockNodeProvisionServiceMockRecorder) ProvisionNode(spec any) *gomock.Call {
+func (mr *MockNodeProvisionServiceMockRecorder) ProvisionNode(ctx, spec any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNode", reflect.TypeOf((*MockNodeProvisionService)(nil).ProvisionNode), ctx, spec)
+}
+
+// ProvisionNodes mocks base method.
+func (m *MockNodeProvisionService) ProvisionNodes(ctx context.Context, spec domain.NodeSpec, count, parallelism int) ([]*domain.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionNodes", ctx, spec, count, parallelism)
+	ret0, _ := ret[0].([]*domain.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProvisionNodes indicates an expected call of ProvisionNodes.
+func (mr *MockNodeProvisionServiceMockRecorder) ProvisionNodes(ctx, spec, count, parallelism any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNodes", reflect.TypeOf((*MockNodeProvisionService)(nil).ProvisionNodes), ctx, spec, count, parallelism)
+}
+
+// TemplateResources mocks base method.
+func (m *MockNodeProvisionService) TemplateResources(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.Resources, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TemplateResources", ctx, templateID, providerID, tenantID)
+	ret0, _ := ret[0].(domain.Resources)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TemplateResources indicates an expected call of TemplateResources.
+func (mr *MockNodeProvisionServiceMockRecorder) TemplateResources(ctx, templateID, providerID, tenantID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionNode", reflect.TypeOf((*MockNodeProvisionService)(nil).ProvisionNode), spec)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TemplateResources", reflect.TypeOf((*MockNodeProvisionService)(nil).TemplateResources), ctx, templateID, providerID, tenantID)
 }
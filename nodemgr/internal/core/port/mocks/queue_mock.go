@@ -0,0 +1,224 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/queue.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/queue.go -destination=internal/core/port/mocks/queue_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQueueRepository is a mock of QueueRepository interface.
+type MockQueueRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockQueueRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockQueueRepositoryMockRecorder is the mock recorder for MockQueueRepository.
+type MockQueueRepositoryMockRecorder struct {
+	mock *MockQueueRepository
+}
+
+// NewMockQueueRepository creates a new mock instance.
+func NewMockQueueRepository(ctrl *gomock.Controller) *MockQueueRepository {
+	mock := &MockQueueRepository{ctrl: ctrl}
+	mock.recorder = &MockQueueRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQueueRepository) EXPECT() *MockQueueRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockQueueRepository) Create(req domain.QueueRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockQueueRepositoryMockRecorder) Create(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockQueueRepository)(nil).Create), req)
+}
+
+// Delete mocks base method.
+func (m *MockQueueRepository) Delete(id domain.QueueRequestID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockQueueRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockQueueRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockQueueRepository) Get(id domain.QueueRequestID) (*domain.QueueRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.QueueRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockQueueRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockQueueRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockQueueRepository) List() ([]*domain.QueueRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.QueueRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockQueueRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockQueueRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockQueueRepository) Update(req domain.QueueRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockQueueRepositoryMockRecorder) Update(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockQueueRepository)(nil).Update), req)
+}
+
+// MockNodeQueueService is a mock of NodeQueueService interface.
+type MockNodeQueueService struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodeQueueServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockNodeQueueServiceMockRecorder is the mock recorder for MockNodeQueueService.
+type MockNodeQueueServiceMockRecorder struct {
+	mock *MockNodeQueueService
+}
+
+// NewMockNodeQueueService creates a new mock instance.
+func NewMockNodeQueueService(ctrl *gomock.Controller) *MockNodeQueueService {
+	mock := &MockNodeQueueService{ctrl: ctrl}
+	mock.recorder = &MockNodeQueueServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodeQueueService) EXPECT() *MockNodeQueueServiceMockRecorder {
+	return m.recorder
+}
+
+// CancelRequest mocks base method.
+func (m *MockNodeQueueService) CancelRequest(ctx context.Context, id domain.QueueRequestID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelRequest", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelRequest indicates an expected call of CancelRequest.
+func (mr *MockNodeQueueServiceMockRecorder) CancelRequest(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRequest", reflect.TypeOf((*MockNodeQueueService)(nil).CancelRequest), ctx, id)
+}
+
+// Enqueue mocks base method.
+func (m *MockNodeQueueService) Enqueue(ctx context.Context, req domain.QueueRequest) (*domain.QueueRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", ctx, req)
+	ret0, _ := ret[0].(*domain.QueueRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockNodeQueueServiceMockRecorder) Enqueue(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockNodeQueueService)(nil).Enqueue), ctx, req)
+}
+
+// GetPosition mocks base method.
+func (m *MockNodeQueueService) GetPosition(ctx context.Context, id domain.QueueRequestID) (*domain.QueuePosition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPosition", ctx, id)
+	ret0, _ := ret[0].(*domain.QueuePosition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPosition indicates an expected call of GetPosition.
+func (mr *MockNodeQueueServiceMockRecorder) GetPosition(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPosition", reflect.TypeOf((*MockNodeQueueService)(nil).GetPosition), ctx, id)
+}
+
+// GetRequest mocks base method.
+func (m *MockNodeQueueService) GetRequest(ctx context.Context, id domain.QueueRequestID) (*domain.QueueRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRequest", ctx, id)
+	ret0, _ := ret[0].(*domain.QueueRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRequest indicates an expected call of GetRequest.
+func (mr *MockNodeQueueServiceMockRecorder) GetRequest(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRequest", reflect.TypeOf((*MockNodeQueueService)(nil).GetRequest), ctx, id)
+}
+
+// ListRequests mocks base method.
+func (m *MockNodeQueueService) ListRequests(ctx context.Context) ([]*domain.QueueRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRequests", ctx)
+	ret0, _ := ret[0].([]*domain.QueueRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRequests indicates an expected call of ListRequests.
+func (mr *MockNodeQueueServiceMockRecorder) ListRequests(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRequests", reflect.TypeOf((*MockNodeQueueService)(nil).ListRequests), ctx)
+}
+
+// Run mocks base method.
+func (m *MockNodeQueueService) Run(ctx context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", ctx)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockNodeQueueServiceMockRecorder) Run(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockNodeQueueService)(nil).Run), ctx)
+}
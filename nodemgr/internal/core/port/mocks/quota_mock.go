@@ -0,0 +1,254 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/quota.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/quota.go -destination=internal/core/port/mocks/quota_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQuotaRepository is a mock of QuotaRepository interface.
+type MockQuotaRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockQuotaRepositoryMockRecorder is the mock recorder for MockQuotaRepository.
+type MockQuotaRepositoryMockRecorder struct {
+	mock *MockQuotaRepository
+}
+
+// NewMockQuotaRepository creates a new mock instance.
+func NewMockQuotaRepository(ctrl *gomock.Controller) *MockQuotaRepository {
+	mock := &MockQuotaRepository{ctrl: ctrl}
+	mock.recorder = &MockQuotaRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaRepository) EXPECT() *MockQuotaRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockQuotaRepository) Create(quota domain.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockQuotaRepositoryMockRecorder) Create(quota any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockQuotaRepository)(nil).Create), quota)
+}
+
+// Delete mocks base method.
+func (m *MockQuotaRepository) Delete(id domain.TenantID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockQuotaRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockQuotaRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockQuotaRepository) Get(id domain.TenantID) (*domain.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockQuotaRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockQuotaRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockQuotaRepository) List() ([]*domain.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockQuotaRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockQuotaRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockQuotaRepository) Update(quota domain.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockQuotaRepositoryMockRecorder) Update(quota any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockQuotaRepository)(nil).Update), quota)
+}
+
+// MockQuotaService is a mock of QuotaService interface.
+type MockQuotaService struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockQuotaServiceMockRecorder is the mock recorder for MockQuotaService.
+type MockQuotaServiceMockRecorder struct {
+	mock *MockQuotaService
+}
+
+// NewMockQuotaService creates a new mock instance.
+func NewMockQuotaService(ctrl *gomock.Controller) *MockQuotaService {
+	mock := &MockQuotaService{ctrl: ctrl}
+	mock.recorder = &MockQuotaServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQuotaService) EXPECT() *MockQuotaServiceMockRecorder {
+	return m.recorder
+}
+
+// Admit mocks base method.
+func (m *MockQuotaService) Admit(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources, fn func() error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Admit", tenantID, providerID, req, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Admit indicates an expected call of Admit.
+func (mr *MockQuotaServiceMockRecorder) Admit(tenantID, providerID, req, fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Admit", reflect.TypeOf((*MockQuotaService)(nil).Admit), tenantID, providerID, req, fn)
+}
+
+// DeleteQuota mocks base method.
+func (m *MockQuotaService) DeleteQuota(ctx context.Context, tenantID domain.TenantID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteQuota", ctx, tenantID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteQuota indicates an expected call of DeleteQuota.
+func (mr *MockQuotaServiceMockRecorder) DeleteQuota(ctx, tenantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteQuota", reflect.TypeOf((*MockQuotaService)(nil).DeleteQuota), ctx, tenantID)
+}
+
+// Fits mocks base method.
+func (m *MockQuotaService) Fits(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fits", tenantID, providerID, req)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fits indicates an expected call of Fits.
+func (mr *MockQuotaServiceMockRecorder) Fits(tenantID, providerID, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fits", reflect.TypeOf((*MockQuotaService)(nil).Fits), tenantID, providerID, req)
+}
+
+// GetQuota mocks base method.
+func (m *MockQuotaService) GetQuota(ctx context.Context, tenantID domain.TenantID) (*domain.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuota", ctx, tenantID)
+	ret0, _ := ret[0].(*domain.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuota indicates an expected call of GetQuota.
+func (mr *MockQuotaServiceMockRecorder) GetQuota(ctx, tenantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuota", reflect.TypeOf((*MockQuotaService)(nil).GetQuota), ctx, tenantID)
+}
+
+// ListQuotas mocks base method.
+func (m *MockQuotaService) ListQuotas(ctx context.Context) ([]*domain.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListQuotas", ctx)
+	ret0, _ := ret[0].([]*domain.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListQuotas indicates an expected call of ListQuotas.
+func (mr *MockQuotaServiceMockRecorder) ListQuotas(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListQuotas", reflect.TypeOf((*MockQuotaService)(nil).ListQuotas), ctx)
+}
+
+// ProviderUsage mocks base method.
+func (m *MockQuotaService) ProviderUsage(ctx context.Context, providerID domain.ProviderID) (*domain.Usage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProviderUsage", ctx, providerID)
+	ret0, _ := ret[0].(*domain.Usage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProviderUsage indicates an expected call of ProviderUsage.
+func (mr *MockQuotaServiceMockRecorder) ProviderUsage(ctx, providerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProviderUsage", reflect.TypeOf((*MockQuotaService)(nil).ProviderUsage), ctx, providerID)
+}
+
+// SetQuota mocks base method.
+func (m *MockQuotaService) SetQuota(ctx context.Context, quota domain.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetQuota", ctx, quota)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetQuota indicates an expected call of SetQuota.
+func (mr *MockQuotaServiceMockRecorder) SetQuota(ctx, quota any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuota", reflect.TypeOf((*MockQuotaService)(nil).SetQuota), ctx, quota)
+}
+
+// TenantUsage mocks base method.
+func (m *MockQuotaService) TenantUsage(ctx context.Context, tenantID domain.TenantID) (*domain.Usage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TenantUsage", ctx, tenantID)
+	ret0, _ := ret[0].(*domain.Usage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TenantUsage indicates an expected call of TenantUsage.
+func (mr *MockQuotaServiceMockRecorder) TenantUsage(ctx, tenantID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TenantUsage", reflect.TypeOf((*MockQuotaService)(nil).TenantUsage), ctx, tenantID)
+}
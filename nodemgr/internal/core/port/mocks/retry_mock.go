@@ -0,0 +1,113 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/retry.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/retry.go -destination=internal/core/port/mocks/retry_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRetryPolicyRepository is a mock of RetryPolicyRepository interface.
+type MockRetryPolicyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRetryPolicyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRetryPolicyRepositoryMockRecorder is the mock recorder for MockRetryPolicyRepository.
+type MockRetryPolicyRepositoryMockRecorder struct {
+	mock *MockRetryPolicyRepository
+}
+
+// NewMockRetryPolicyRepository creates a new mock instance.
+func NewMockRetryPolicyRepository(ctrl *gomock.Controller) *MockRetryPolicyRepository {
+	mock := &MockRetryPolicyRepository{ctrl: ctrl}
+	mock.recorder = &MockRetryPolicyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRetryPolicyRepository) EXPECT() *MockRetryPolicyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockRetryPolicyRepository) Create(policies domain.RetryPolicies) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", policies)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockRetryPolicyRepositoryMockRecorder) Create(policies any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRetryPolicyRepository)(nil).Create), policies)
+}
+
+// Delete mocks base method.
+func (m *MockRetryPolicyRepository) Delete(id domain.ProviderID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRetryPolicyRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRetryPolicyRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockRetryPolicyRepository) Get(id domain.ProviderID) (*domain.RetryPolicies, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.RetryPolicies)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRetryPolicyRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRetryPolicyRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockRetryPolicyRepository) List() ([]*domain.RetryPolicies, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.RetryPolicies)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockRetryPolicyRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRetryPolicyRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockRetryPolicyRepository) Update(policies domain.RetryPolicies) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", policies)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockRetryPolicyRepositoryMockRecorder) Update(policies any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRetryPolicyRepository)(nil).Update), policies)
+}
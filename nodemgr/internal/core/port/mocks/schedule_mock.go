@@ -0,0 +1,169 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/core/port/schedule.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/core/port/schedule.go -destination=internal/core/port/mocks/schedule_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	domain "nodemgr/internal/core/domain"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProviderProfileRepository is a mock of ProviderProfileRepository interface.
+type MockProviderProfileRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderProfileRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockProviderProfileRepositoryMockRecorder is the mock recorder for MockProviderProfileRepository.
+type MockProviderProfileRepositoryMockRecorder struct {
+	mock *MockProviderProfileRepository
+}
+
+// NewMockProviderProfileRepository creates a new mock instance.
+func NewMockProviderProfileRepository(ctrl *gomock.Controller) *MockProviderProfileRepository {
+	mock := &MockProviderProfileRepository{ctrl: ctrl}
+	mock.recorder = &MockProviderProfileRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProviderProfileRepository) EXPECT() *MockProviderProfileRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockProviderProfileRepository) Create(profile domain.ProviderProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockProviderProfileRepositoryMockRecorder) Create(profile any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockProviderProfileRepository)(nil).Create), profile)
+}
+
+// Delete mocks base method.
+func (m *MockProviderProfileRepository) Delete(id domain.ProviderID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockProviderProfileRepositoryMockRecorder) Delete(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockProviderProfileRepository)(nil).Delete), id)
+}
+
+// Get mocks base method.
+func (m *MockProviderProfileRepository) Get(id domain.ProviderID) (*domain.ProviderProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*domain.ProviderProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockProviderProfileRepositoryMockRecorder) Get(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockProviderProfileRepository)(nil).Get), id)
+}
+
+// List mocks base method.
+func (m *MockProviderProfileRepository) List() ([]*domain.ProviderProfile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*domain.ProviderProfile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockProviderProfileRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockProviderProfileRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockProviderProfileRepository) Update(profile domain.ProviderProfile) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockProviderProfileRepositoryMockRecorder) Update(profile any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockProviderProfileRepository)(nil).Update), profile)
+}
+
+// MockSchedulerService is a mock of SchedulerService interface.
+type MockSchedulerService struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchedulerServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockSchedulerServiceMockRecorder is the mock recorder for MockSchedulerService.
+type MockSchedulerServiceMockRecorder struct {
+	mock *MockSchedulerService
+}
+
+// NewMockSchedulerService creates a new mock instance.
+func NewMockSchedulerService(ctrl *gomock.Controller) *MockSchedulerService {
+	mock := &MockSchedulerService{ctrl: ctrl}
+	mock.recorder = &MockSchedulerServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchedulerService) EXPECT() *MockSchedulerServiceMockRecorder {
+	return m.recorder
+}
+
+// ProvisionFor mocks base method.
+func (m *MockSchedulerService) ProvisionFor(ctx context.Context, req domain.Requirement) (*domain.Operation, *domain.SchedulingDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvisionFor", ctx, req)
+	ret0, _ := ret[0].(*domain.Operation)
+	ret1, _ := ret[1].(*domain.SchedulingDecision)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ProvisionFor indicates an expected call of ProvisionFor.
+func (mr *MockSchedulerServiceMockRecorder) ProvisionFor(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvisionFor", reflect.TypeOf((*MockSchedulerService)(nil).ProvisionFor), ctx, req)
+}
+
+// Schedule mocks base method.
+func (m *MockSchedulerService) Schedule(ctx context.Context, req domain.Requirement) (*domain.SchedulingDecision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Schedule", ctx, req)
+	ret0, _ := ret[0].(*domain.SchedulingDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Schedule indicates an expected call of Schedule.
+func (mr *MockSchedulerServiceMockRecorder) Schedule(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockSchedulerService)(nil).Schedule), ctx, req)
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	domain "nodemgr/internal/core/domain"
 	reflect "reflect"
 
@@ -123,75 +124,75 @@ func (m *MockTemplateService) EXPECT() *MockTemplateServiceMockRecorder {
 }
 
 // CreateTemplate mocks base method.
-func (m *MockTemplateService) CreateTemplate(template domain.NodeTemplate) (domain.TemplateID, error) {
+func (m *MockTemplateService) CreateTemplate(ctx context.Context, template domain.NodeTemplate) (domain.TemplateID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTemplate", template)
+	ret := m.ctrl.Call(m, "CreateTemplate", ctx, template)
 	ret0, _ := ret[0].(domain.TemplateID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateTemplate indicates an expected call of CreateTemplate.
-func (mr *MockTemplateServiceMockRecorder) CreateTemplate(template any) *gomock.Call {
+func (mr *MockTemplateServiceMockRecorder) CreateTemplate(ctx, template any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTemplate", reflect.TypeOf((*MockTemplateService)(nil).CreateTemplate), template)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTemplate", reflect.TypeOf((*MockTemplateService)(nil).CreateTemplate), ctx, template)
 }
 
 // DeleteTemplate mocks base method.
-func (m *MockTemplateService) DeleteTemplate(id domain.TemplateID) error {
+func (m *MockTemplateService) DeleteTemplate(ctx context.Context, id domain.TemplateID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteTemplate", id)
+	ret := m.ctrl.Call(m, "DeleteTemplate", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteTemplate indicates an expected call of DeleteTemplate.
-func (mr *MockTemplateServiceMockRecorder) DeleteTemplate(id any) *gomock.Call {
+func (mr *MockTemplateServiceMockRecorder) DeleteTemplate(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTemplate", reflect.TypeOf((*MockTemplateService)(nil).DeleteTemplate), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTemplate", reflect.TypeOf((*MockTemplateService)(nil).DeleteTemplate), ctx, id)
 }
 
 // GetTemplate mocks base method.
-func (m *MockTemplateService) GetTemplate(id domain.TemplateID) (*domain.NodeTemplate, error) {
+func (m *MockTemplateService) GetTemplate(ctx context.Context, id domain.TemplateID) (*domain.NodeTemplate, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTemplate", id)
+	ret := m.ctrl.Call(m, "GetTemplate", ctx, id)
 	ret0, _ := ret[0].(*domain.NodeTemplate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTemplate indicates an expected call of GetTemplate.
-func (mr *MockTemplateServiceMockRecorder) GetTemplate(id any) *gomock.Call {
+func (mr *MockTemplateServiceMockRecorder) GetTemplate(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTemplate", reflect.TypeOf((*MockTemplateService)(nil).GetTemplate), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTemplate", reflect.TypeOf((*MockTemplateService)(nil).GetTemplate), ctx, id)
 }
 
 // ListTemplates mocks base method.
-func (m *MockTemplateService) ListTemplates() ([]*domain.NodeTemplate, error) {
+func (m *MockTemplateService) ListTemplates(ctx context.Context) ([]*domain.NodeTemplate, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListTemplates")
+	ret := m.ctrl.Call(m, "ListTemplates", ctx)
 	ret0, _ := ret[0].([]*domain.NodeTemplate)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListTemplates indicates an expected call of ListTemplates.
-func (mr *MockTemplateServiceMockRecorder) ListTemplates() *gomock.Call {
+func (mr *MockTemplateServiceMockRecorder) ListTemplates(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTemplates", reflect.TypeOf((*MockTemplateService)(nil).ListTemplates))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTemplates", reflect.TypeOf((*MockTemplateService)(nil).ListTemplates), ctx)
 }
 
 // RenderTemplate mocks base method.
-func (m *MockTemplateService) RenderTemplate(templateID domain.TemplateID, providerID domain.ProviderID) (domain.NodeSpec, error) {
+func (m *MockTemplateService) RenderTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID) (domain.NodeSpec, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RenderTemplate", templateID, providerID)
+	ret := m.ctrl.Call(m, "RenderTemplate", ctx, templateID, providerID)
 	ret0, _ := ret[0].(domain.NodeSpec)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RenderTemplate indicates an expected call of RenderTemplate.
-func (mr *MockTemplateServiceMockRecorder) RenderTemplate(templateID, providerID any) *gomock.Call {
+func (mr *MockTemplateServiceMockRecorder) RenderTemplate(ctx, templateID, providerID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderTemplate", reflect.TypeOf((*MockTemplateService)(nil).RenderTemplate), templateID, providerID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderTemplate", reflect.TypeOf((*MockTemplateService)(nil).RenderTemplate), ctx, templateID, providerID)
 }
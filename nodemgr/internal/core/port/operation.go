@@ -0,0 +1,36 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type OperationRepository interface {
+	Create(op domain.Operation) error
+	Update(op domain.Operation) error
+	Get(id domain.OperationID) (*domain.Operation, error)
+	List() ([]*domain.Operation, error)
+	Delete(id domain.OperationID) error
+}
+
+type OperationService interface {
+	// StartOperation runs fn in the background on behalf of tenantID. When
+	// slots is not nil the operation stays pending until it can take a slot
+	// from it, fn still runs when the operation is cancelled before that so
+	// it can roll back, its context is then already done. The context handed
+	// to fn continues the trace and identity of ctx but is not cancelled with
+	// it.
+	StartOperation(ctx context.Context, kind domain.OperationKind, nodeID domain.NodeID, tenantID domain.TenantID, slots chan struct{}, fn func(ctx context.Context) error) (*domain.Operation, error)
+
+	// RecordAttempt adds an attempt to the operation ctx was handed to.
+	RecordAttempt(ctx context.Context, attempt domain.OperationAttempt) error
+
+	GetOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error)
+	ListOperations(ctx context.Context) ([]*domain.Operation, error)
+	WatchOperation(ctx context.Context, id domain.OperationID) (<-chan domain.Operation, error)
+	WaitOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error)
+	CancelOperation(ctx context.Context, id domain.OperationID) error
+
+	// Run deletes finished operations past their retention until ctx is done.
+	Run(ctx context.Context)
+}
@@ -0,0 +1,30 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type NodePoolRepository interface {
+	Create(pool domain.NodePool) error
+	Update(pool domain.NodePool) error
+	Get(id domain.PoolID) (*domain.NodePool, error)
+	List() ([]*domain.NodePool, error)
+	Delete(id domain.PoolID) error
+}
+
+// NodePoolService wraps the provision service, template provisioning is
+// served from a matching pool when it has an idle healthy node.
+type NodePoolService interface {
+	NodeProvisionService
+
+	// pools are shared by all tenants and managed by global admins
+	CreatePool(ctx context.Context, pool domain.NodePool) (domain.PoolID, error)
+	DeletePool(ctx context.Context, id domain.PoolID) error
+	UpdatePool(ctx context.Context, pool domain.NodePool) error
+	GetPoolStatus(ctx context.Context, id domain.PoolID) (*domain.NodePoolStatus, error)
+	ListPools(ctx context.Context) ([]*domain.NodePool, error)
+
+	// Run refills pools and retires expired nodes until ctx is done.
+	Run(ctx context.Context)
+}
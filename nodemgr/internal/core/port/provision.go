@@ -1,9 +1,15 @@
 package port
 
-import "nodemgr/internal/core/domain"
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
 
 type NodeRepository interface {
 	Create(node domain.Node) error
+	// UpdateFunc applies fn to the stored node atomically, every change of
+	// a stored node goes through it so updates never overwrite each other.
+	UpdateFunc(id domain.NodeID, fn func(node *domain.Node) error) (*domain.Node, error)
 	Get(id domain.NodeID) (*domain.Node, error)
 	List() ([]*domain.Node, error)
 	Delete(id domain.NodeID) error
@@ -12,17 +18,49 @@ type NodeRepository interface {
 type NodeProviderRepository interface {
 	Create(provider NodeProvider) error
 	Get(id domain.ProviderID) (*NodeProvider, error)
-	List() []*NodeProvider
+	List() ([]*NodeProvider, error)
 	Delete(id domain.ProviderID) error
 }
 
 type NodeProvider interface {
 	ID() domain.ProviderID
-	Provision(spec domain.NodeSpec) (*domain.Node, error)
-	Destroy(nodeID domain.NodeID) error
+	Provision(ctx context.Context, nodeID domain.NodeID, spec domain.NodeSpec) (*domain.Node, error)
+	Destroy(ctx context.Context, nodeID domain.NodeID) error
+}
+
+// NodeImagePrefetcher is implemented by providers that can fetch the image of
+// a spec ahead of provisioning.
+type NodeImagePrefetcher interface {
+	PrefetchImage(ctx context.Context, spec domain.NodeSpec) error
+}
+
+// NodeResourceEstimator is implemented by providers that know the resources a
+// spec takes, including their own defaults. Other specs are sized from the
+// common cpus and memory_mb fields.
+type NodeResourceEstimator interface {
+	SpecResources(spec domain.NodeSpec) (domain.Resources, error)
+}
+
+// UnisolatedNodeProvider is implemented by providers whose nodes give access
+// to hosts nodemgr does not isolate, like the local machine or static hosts.
+// Only global admins can provision them.
+type UnisolatedNodeProvider interface {
+	Unisolated() bool
 }
 
 type NodeProvisionService interface {
-	ProvisionNode(spec domain.NodeSpec) (*domain.Node, error)
-	DestroyNode(nodeID domain.NodeID) error
+	// raw specs can set any provider option, so they need an admin of the
+	// tenant, templates only a user
+	ProvisionNode(ctx context.Context, spec domain.NodeSpec) (*domain.Operation, error)
+	ProvisionNodes(ctx context.Context, spec domain.NodeSpec, count int, parallelism int) ([]*domain.Operation, error)
+	ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (*domain.Operation, error)
+	// TemplateResources returns what a node provisioned from the template
+	// would be charged without provisioning it.
+	TemplateResources(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.Resources, error)
+	DestroyNode(ctx context.Context, nodeID domain.NodeID) (*domain.Operation, error)
+	PrefetchTemplateImages(ctx context.Context) error
+
+	// viewers get nodes with secret looking meta, like private keys, redacted
+	GetNode(ctx context.Context, nodeID domain.NodeID) (*domain.Node, error)
+	ListNodes(ctx context.Context) ([]*domain.Node, error)
 }
@@ -0,0 +1,29 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type QueueRepository interface {
+	Create(req domain.QueueRequest) error
+	Update(req domain.QueueRequest) error
+	Get(id domain.QueueRequestID) (*domain.QueueRequest, error)
+	List() ([]*domain.QueueRequest, error)
+	Delete(id domain.QueueRequestID) error
+}
+
+// NodeQueueService holds template provisioning requests until there is
+// capacity for them. Requests are admitted by priority and, within the same
+// priority, to the tenant and then the user holding the least nodes first.
+// Requests that exceed the limits on their own are rejected by Enqueue.
+type NodeQueueService interface {
+	Enqueue(ctx context.Context, req domain.QueueRequest) (*domain.QueueRequest, error)
+	GetRequest(ctx context.Context, id domain.QueueRequestID) (*domain.QueueRequest, error)
+	ListRequests(ctx context.Context) ([]*domain.QueueRequest, error)
+	GetPosition(ctx context.Context, id domain.QueueRequestID) (*domain.QueuePosition, error)
+	CancelRequest(ctx context.Context, id domain.QueueRequestID) error
+
+	// Run admits queued requests as capacity frees up until ctx is done.
+	Run(ctx context.Context)
+}
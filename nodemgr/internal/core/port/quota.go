@@ -0,0 +1,38 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type QuotaRepository interface {
+	Create(quota domain.Quota) error
+	Update(quota domain.Quota) error
+	Get(id domain.TenantID) (*domain.Quota, error)
+	List() ([]*domain.Quota, error)
+	Delete(id domain.TenantID) error
+}
+
+type QuotaService interface {
+	// quotas are set by global admins, tenants can read their own
+	SetQuota(ctx context.Context, quota domain.Quota) error
+	GetQuota(ctx context.Context, tenantID domain.TenantID) (*domain.Quota, error)
+	ListQuotas(ctx context.Context) ([]*domain.Quota, error)
+	DeleteQuota(ctx context.Context, tenantID domain.TenantID) error
+
+	// usage of a tenant is visible to its viewers, usage of a provider sums
+	// every tenant and is only visible to global identities
+	TenantUsage(ctx context.Context, tenantID domain.TenantID) (*domain.Usage, error)
+	ProviderUsage(ctx context.Context, providerID domain.ProviderID) (*domain.Usage, error)
+
+	// Admit runs fn when req fits the tenant quota and the provider capacity,
+	// otherwise it returns *domain.QuotaExceededError. Admissions are
+	// serialized so fn can create the nodes without racing other requests.
+	// Empty tenantID or providerID skip the respective check. Requests are
+	// never held back here, callers that want to wait for room queue them
+	// with the QueueService.
+	Admit(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources, fn func() error) error
+	// Fits returns *domain.QuotaExceededError when req exceeds the tenant
+	// quota or the provider capacity on its own and can never be admitted.
+	Fits(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources) error
+}
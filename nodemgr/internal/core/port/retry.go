@@ -0,0 +1,11 @@
+package port
+
+import "nodemgr/internal/core/domain"
+
+type RetryPolicyRepository interface {
+	Create(policies domain.RetryPolicies) error
+	Update(policies domain.RetryPolicies) error
+	Get(id domain.ProviderID) (*domain.RetryPolicies, error)
+	List() ([]*domain.RetryPolicies, error)
+	Delete(id domain.ProviderID) error
+}
@@ -0,0 +1,22 @@
+package port
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+type ProviderProfileRepository interface {
+	Create(profile domain.ProviderProfile) error
+	Update(profile domain.ProviderProfile) error
+	Get(id domain.ProviderID) (*domain.ProviderProfile, error)
+	List() ([]*domain.ProviderProfile, error)
+	Delete(id domain.ProviderID) error
+}
+
+type SchedulerService interface {
+	// Schedule picks a template and provider for the requirement. When none
+	// fits it returns domain.ErrUnschedulable together with the decision
+	// explaining every rejection.
+	Schedule(ctx context.Context, req domain.Requirement) (*domain.SchedulingDecision, error)
+	ProvisionFor(ctx context.Context, req domain.Requirement) (*domain.Operation, *domain.SchedulingDecision, error)
+}
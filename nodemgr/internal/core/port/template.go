@@ -1,6 +1,9 @@
 package port
 
-import "nodemgr/internal/core/domain"
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
 
 type TemplateRepository interface {
 	Create(tmpl domain.NodeTemplate) error
@@ -10,10 +13,14 @@ type TemplateRepository interface {
 }
 
 type TemplateService interface {
-	CreateTemplate(template domain.NodeTemplate) (domain.TemplateID, error)
-	GetTemplate(id domain.TemplateID) (*domain.NodeTemplate, error)
-	ListTemplates() ([]*domain.NodeTemplate, error)
-	DeleteTemplate(id domain.TemplateID) error
+	CreateTemplate(ctx context.Context, template domain.NodeTemplate) (domain.TemplateID, error)
+	GetTemplate(ctx context.Context, id domain.TemplateID) (*domain.NodeTemplate, error)
+	// ListTemplates returns the shared templates and those of the tenant of
+	// the caller.
+	ListTemplates(ctx context.Context) ([]*domain.NodeTemplate, error)
+	DeleteTemplate(ctx context.Context, id domain.TemplateID) error
 
-	RenderTemplate(templateID domain.TemplateID, providerID domain.ProviderID) (domain.NodeSpec, error)
+	// RenderTemplate sets the tenant of the spec to the one owning the
+	// template.
+	RenderTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID) (domain.NodeSpec, error)
 }
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditService struct {
+	sink port.AuditSink
+}
+
+func NewAuditService(sink port.AuditSink) *AuditService {
+	return &AuditService{sink: sink}
+}
+
+func (s *AuditService) Record(ctx context.Context, event domain.AuditEvent, err error) {
+	event.EventID = domain.AuditEventID(uuid.New().String())
+	event.Time = time.Now()
+	event.Actor = util.Actor(ctx)
+	if id, ok := ctx.Value(operationIDKey{}).(domain.OperationID); ok && event.OperationID == "" {
+		event.OperationID = id
+	}
+	if event.Params != nil {
+		event.Params = util.Redact(event.Params).(map[string]any)
+	}
+
+	if err != nil {
+		event.Outcome = domain.AuditOutcomeFailed
+		event.Error = err.Error()
+		event.Code = domain.Code(err)
+	} else {
+		event.Outcome = domain.AuditOutcomeSucceeded
+	}
+
+	// the event must be written even when the request was cancelled
+	if err := s.sink.Append(context.WithoutCancel(ctx), event); err != nil {
+		slog.ErrorContext(ctx, "writing audit event", "action", event.Action, "node_id", event.NodeID, "err", err)
+	}
+}
+
+func (s *AuditService) QueryEvents(ctx context.Context, filter domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	identity, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if identity.TenantID != "" {
+		filter.TenantID = identity.TenantID
+	}
+	if err := authorize(ctx, domain.RoleAdmin, filter.TenantID); err != nil {
+		return nil, err
+	}
+
+	events, err := s.sink.Query(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("querying audit events: %w", err)
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+var _ port.AuditService = (*AuditService)(nil)
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+)
+
+type AuthService struct {
+	tokenRepository port.APITokenRepository
+	// trustedIssuers are the account public keys, or their signing keys,
+	// allowed to issue user JWTs
+	trustedIssuers []string
+}
+
+func NewAuthService(tokenRepository port.APITokenRepository, trustedIssuers []string) *AuthService {
+	return &AuthService{tokenRepository: tokenRepository, trustedIssuers: trustedIssuers}
+}
+
+// AuthenticateToken checks tokens of the form `<token id>.<secret>`.
+func (s *AuthService) AuthenticateToken(ctx context.Context, token string) (*domain.Identity, error) {
+	id, secret, found := strings.Cut(token, ".")
+	if !found {
+		return nil, &domain.UnauthenticatedError{Reason: "malformed token"}
+	}
+
+	stored, err := s.tokenRepository.Get(domain.APITokenID(id))
+	if err != nil {
+		return nil, &domain.UnauthenticatedError{Reason: "unknown token"}
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.SecretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, &domain.UnauthenticatedError{Reason: "unknown token"}
+	}
+	if !stored.ExpiresAt.IsZero() && time.Now().After(stored.ExpiresAt) {
+		return nil, &domain.UnauthenticatedError{Reason: "token expired"}
+	}
+
+	identity := stored.Identity
+	return &identity, nil
+}
+
+func (s *AuthService) AuthenticateJWT(ctx context.Context, userJWT string, nonce []byte, sig []byte) (*domain.Identity, error) {
+	// decoding verifies the signature of the issuer
+	claims, err := jwt.DecodeUserClaims(userJWT)
+	if err != nil {
+		return nil, &domain.UnauthenticatedError{Reason: fmt.Sprintf("invalid user jwt: %v", err)}
+	}
+	if !slices.Contains(s.trustedIssuers, claims.Issuer) {
+		return nil, &domain.UnauthenticatedError{Reason: fmt.Sprintf("untrusted issuer %s", claims.Issuer)}
+	}
+
+	vr := jwt.CreateValidationResults()
+	claims.Validate(vr)
+	if vr.IsBlocking(true) {
+		return nil, &domain.UnauthenticatedError{Reason: fmt.Sprintf("invalid user jwt: %v", vr.Errors())}
+	}
+
+	user, err := nkeys.FromPublicKey(claims.Subject)
+	if err != nil {
+		return nil, &domain.UnauthenticatedError{Reason: fmt.Sprintf("invalid user nkey: %v", err)}
+	}
+	if len(nonce) == 0 || user.Verify(nonce, sig) != nil {
+		return nil, &domain.UnauthenticatedError{Reason: "nonce signature does not match the user nkey"}
+	}
+
+	// the name is chosen by the issuer and may collide with other subjects,
+	// only the verified user nkey identifies the caller
+	identity := domain.Identity{Subject: claims.Subject}
+	for _, tag := range claims.Tags {
+		key, value, _ := strings.Cut(tag, ":")
+		switch key {
+		case "tenant":
+			identity.TenantID = domain.TenantID(value)
+		case "role":
+			if identity.Role, err = domain.ParseRole(value); err != nil {
+				return nil, &domain.UnauthenticatedError{Reason: err.Error()}
+			}
+		}
+	}
+	// a user jwt without tags must not turn into a global identity
+	if identity.TenantID == "" || identity.Role == "" {
+		return nil, &domain.UnauthenticatedError{Reason: "user jwt has no tenant or role tag"}
+	}
+
+	return &identity, nil
+}
+
+// CreateToken needs an admin of the tenant of identity, only global admins
+// can create global identities.
+func (s *AuthService) CreateToken(ctx context.Context, identity domain.Identity, ttl time.Duration) (string, *domain.APIToken, error) {
+	if err := authorize(ctx, domain.RoleAdmin, identity.TenantID); err != nil {
+		return "", nil, err
+	}
+	if identity.Subject == "" {
+		return "", nil, domain.InvalidSpec("token subject is required")
+	}
+	if _, err := domain.ParseRole(string(identity.Role)); err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generating token secret: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	token := domain.APIToken{
+		TokenID:    domain.APITokenID(uuid.New().String()),
+		SecretHash: hashSecret(encoded),
+		Identity:   identity,
+		CreatedAt:  now,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl)
+	}
+
+	if err := s.tokenRepository.Create(token); err != nil {
+		return "", nil, fmt.Errorf("storing token: %w", err)
+	}
+	return string(token.TokenID) + "." + encoded, &token, nil
+}
+
+func (s *AuthService) ListTokens(ctx context.Context) ([]*domain.APIToken, error) {
+	caller, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenRepository.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing tokens: %w", err)
+	}
+	return slices.DeleteFunc(tokens, func(t *domain.APIToken) bool {
+		return !caller.Allows(domain.RoleAdmin, t.Identity.TenantID)
+	}), nil
+}
+
+func (s *AuthService) RevokeToken(ctx context.Context, id domain.APITokenID) error {
+	token, err := s.tokenRepository.Get(id)
+	if err != nil {
+		return fmt.Errorf("loading token: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleAdmin, token.Identity.TenantID); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepository.Delete(id); err != nil {
+		return fmt.Errorf("deleting token: %w", err)
+	}
+	return nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticated returns the identity of ctx, every service call needs one.
+func authenticated(ctx context.Context) (domain.Identity, error) {
+	identity, ok := util.IdentityFrom(ctx)
+	if !ok {
+		return domain.Identity{}, &domain.UnauthenticatedError{Reason: "no identity"}
+	}
+	return identity, nil
+}
+
+// authorize fails unless the identity of ctx holds at least role in tenantID.
+func authorize(ctx context.Context, role domain.Role, tenantID domain.TenantID) error {
+	identity, err := authenticated(ctx)
+	if err != nil {
+		return err
+	}
+	if !identity.Allows(role, tenantID) {
+		return &domain.PermissionDeniedError{Subject: identity.Subject, Role: role, TenantID: tenantID}
+	}
+	return nil
+}
+
+// authorizeView fails unless the identity of ctx can read templates or
+// mappings of tenantID, those without a tenant are shared.
+func authorizeView(ctx context.Context, tenantID domain.TenantID) error {
+	identity, err := authenticated(ctx)
+	if err != nil {
+		return err
+	}
+	if !identity.CanView(tenantID) {
+		return &domain.PermissionDeniedError{Subject: identity.Subject, Role: domain.RoleViewer, TenantID: tenantID}
+	}
+	return nil
+}
+
+// visible keeps the items the identity of ctx is a viewer of, or when shared
+// is set also the items without a tenant.
+func visible[T any](ctx context.Context, items []*T, shared bool, tenant func(*T) domain.TenantID) ([]*T, error) {
+	identity, err := authenticated(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(items, func(item *T) bool {
+		if shared {
+			return !identity.CanView(tenant(item))
+		}
+		return !identity.Allows(domain.RoleViewer, tenant(item))
+	}), nil
+}
+
+// asSystem returns ctx acting as nodemgr itself, for work done on behalf of
+// nobody in particular like pool refills and health checks.
+func asSystem(ctx context.Context) context.Context {
+	return util.WithIdentity(ctx, domain.SystemIdentity)
+}
+
+var _ port.AuthService = (*AuthService)(nil)
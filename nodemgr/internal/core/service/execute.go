@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ExecuteService struct {
+	nodeRepository         port.NodeRepository
+	execProviderRepository port.NodeExecProviderRepository
+	retryRepository        port.RetryPolicyRepository
+	metrics                port.MetricsRecorder
+	auditService           port.AuditService
+	// stagingDir holds the host side of file copies, nodes of a tenant only
+	// reach the directory of their tenant below it
+	stagingDir string
+}
+
+func NewExecuteService(
+	nodeRepository port.NodeRepository,
+	execProviderRepository port.NodeExecProviderRepository,
+	retryRepository port.RetryPolicyRepository,
+	metrics port.MetricsRecorder,
+	auditService port.AuditService,
+	stagingDir string,
+) *ExecuteService {
+	if stagingDir == "" {
+		stagingDir = filepath.Join(os.TempDir(), "nodemgr-staging")
+	}
+
+	return &ExecuteService{
+		nodeRepository:         nodeRepository,
+		execProviderRepository: execProviderRepository,
+		retryRepository:        retryRepository,
+		metrics:                metrics,
+		auditService:           auditService,
+		stagingDir:             stagingDir,
+	}
+}
+
+func (s *ExecuteService) Attach(ctx context.Context, req domain.AttachRequest) (_ *domain.AttachResult, err error) {
+	ctx, span := util.StartSpan(ctx, "ExecuteService.Attach", attribute.String("node_id", string(req.NodeID)))
+	defer util.EndSpan(span, &err)
+
+	params := map[string]any{"exec_provider_id": req.ExecProviderID}
+	defer func() { s.audit(ctx, domain.AuditActionAttach, req.NodeID, params, err) }()
+
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			s.observeExec(req.NodeID, time.Since(start), nil, err)
+		}
+	}()
+
+	handle, err := s.openHandle(ctx, req.NodeID, req.ExecProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := handle.Attach(req)
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	return s.observeAttached(req.NodeID, start, closeWith(res, handle)), nil
+}
+
+func (s *ExecuteService) Exec(ctx context.Context, req domain.ExecRequest) (res *domain.ExecResult, err error) {
+	ctx, span := util.StartSpan(ctx, "ExecuteService.Exec",
+		attribute.String("node_id", string(req.NodeID)),
+		attribute.StringSlice("command", util.RedactArgs(req.Command)))
+	defer util.EndSpan(span, &err)
+
+	params := execParams(req)
+	defer func() { s.audit(ctx, domain.AuditActionExec, req.NodeID, params, err) }()
+
+	start := time.Now()
+	defer func() { s.observeExec(req.NodeID, time.Since(start), res, err) }()
+
+	handle, err := s.openHandle(ctx, req.NodeID, req.ExecProviderID)
+	if err != nil {
+		return nil, err
+	}
+	defer handle.Close()
+	defer abortOnDone(ctx, handle)()
+
+	req.Env = withTraceEnv(ctx, req.Env)
+	res, err = handle.Exec(req)
+	if cerr := ctx.Err(); cerr != nil {
+		return nil, cerr
+	}
+	if res != nil {
+		span.SetAttributes(attribute.Int("exit_code", res.ExitCode))
+	}
+	return res, err
+}
+
+func (s *ExecuteService) observeExec(nodeID domain.NodeID, duration time.Duration, res *domain.ExecResult, err error) {
+	var providerID domain.ProviderID
+	if node, nerr := s.nodeRepository.Get(nodeID); nerr == nil {
+		providerID = node.ProviderID
+	}
+
+	var exitCode int
+	if res != nil {
+		exitCode = res.ExitCode
+	}
+	s.metrics.ObserveExec(providerID, duration, exitCode, err)
+}
+
+// ExecStream returns once the command is started, its span does not cover the
+// time the command runs.
+func (s *ExecuteService) ExecStream(ctx context.Context, exec domain.ExecRequest, attach domain.AttachRequest) (_ *domain.AttachResult, err error) {
+	ctx, span := util.StartSpan(ctx, "ExecuteService.ExecStream",
+		attribute.String("node_id", string(exec.NodeID)),
+		attribute.StringSlice("command", util.RedactArgs(exec.Command)))
+	defer util.EndSpan(span, &err)
+
+	params := execParams(exec)
+	defer func() { s.audit(ctx, domain.AuditActionExec, exec.NodeID, params, err) }()
+
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			s.observeExec(exec.NodeID, time.Since(start), nil, err)
+		}
+	}()
+
+	handle, err := s.openHandle(ctx, exec.NodeID, exec.ExecProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	exec.Env = withTraceEnv(ctx, exec.Env)
+	res, err := handle.ExecStream(exec, attach)
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	return s.observeAttached(exec.NodeID, start, closeWith(res, handle)), nil
+}
+
+// observeAttached records the exec metrics of an attached process once it
+// exits and passes its exit code on to the caller.
+func (s *ExecuteService) observeAttached(nodeID domain.NodeID, start time.Time, res *domain.AttachResult) *domain.AttachResult {
+	exitCode := make(chan int, 1)
+	src, wait := res.ExitCode, res.Wait
+
+	go func() {
+		defer close(exitCode)
+
+		code, ok := <-src
+		err := wait()
+		s.observeExec(nodeID, time.Since(start), &domain.ExecResult{ExitCode: code}, err)
+		if ok {
+			exitCode <- code
+		}
+	}()
+
+	res.ExitCode = exitCode
+	return res
+}
+
+func (s *ExecuteService) CopyTo(ctx context.Context, req domain.CopyToRequest) (err error) {
+	ctx, span := util.StartSpan(ctx, "ExecuteService.CopyTo",
+		attribute.String("node_id", string(req.NodeID)),
+		attribute.String("dst", req.Dst))
+	defer util.EndSpan(span, &err)
+
+	params := map[string]any{"exec_provider_id": req.ExecProviderID, "src": req.Src, "dst": req.Dst}
+	defer func() { s.audit(ctx, domain.AuditActionCopyTo, req.NodeID, params, err) }()
+
+	handle, err := s.openHandle(ctx, req.NodeID, req.ExecProviderID)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	defer abortOnDone(ctx, handle)()
+
+	srcPath, err := s.stagingPath(req.NodeID, req.Src)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("opening source file: %w", err)
+	}
+	defer f.Close()
+
+	if err := handle.CopyTo(f, req.Dst); err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *ExecuteService) CopyFrom(ctx context.Context, req domain.CopyFromRequest) (err error) {
+	ctx, span := util.StartSpan(ctx, "ExecuteService.CopyFrom",
+		attribute.String("node_id", string(req.NodeID)),
+		attribute.String("src", req.Src))
+	defer util.EndSpan(span, &err)
+
+	params := map[string]any{"exec_provider_id": req.ExecProviderID, "src": req.Src, "dst": req.Dst}
+	defer func() { s.audit(ctx, domain.AuditActionCopyFrom, req.NodeID, params, err) }()
+
+	handle, err := s.openHandle(ctx, req.NodeID, req.ExecProviderID)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+	defer abortOnDone(ctx, handle)()
+
+	src, err := handle.CopyFrom(req.Src)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dstPath, err := s.stagingPath(req.NodeID, req.Dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return fmt.Errorf("creating destination directory: %w", err)
+	}
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return fmt.Errorf("creating destination file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, src); err != nil {
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		return fmt.Errorf("copying file: %w", err)
+	}
+	return f.Close()
+}
+
+// stagingPath resolves path inside the staging directory of the node tenant,
+// absolute paths and symlinks can not leave it.
+func (s *ExecuteService) stagingPath(nodeID domain.NodeID, path string) (string, error) {
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return "", fmt.Errorf("loading node: %w", err)
+	}
+
+	dir, err := securejoin.SecureJoin(s.stagingDir, string(node.TenantID))
+	if err != nil {
+		return "", fmt.Errorf("resolving staging directory: %w", err)
+	}
+	resolved, err := securejoin.SecureJoin(dir, path)
+	if err != nil {
+		return "", &domain.InvalidSpecError{Err: fmt.Errorf("resolving %q inside the staging directory: %w", path, err)}
+	}
+	return resolved, nil
+}
+
+func (s *ExecuteService) audit(ctx context.Context, action domain.AuditAction, nodeID domain.NodeID, params map[string]any, err error) {
+	event := domain.AuditEvent{Action: action, NodeID: nodeID, Params: params}
+	if node, nerr := s.nodeRepository.Get(nodeID); nerr == nil {
+		event.TenantID, event.TemplateID, event.ProviderID = node.TenantID, node.TemplateID, node.ProviderID
+	}
+	s.auditService.Record(ctx, event, err)
+}
+
+func execParams(req domain.ExecRequest) map[string]any {
+	return map[string]any{
+		"exec_provider_id": req.ExecProviderID,
+		"command":          req.Command,
+		"env":              req.Env,
+		"working_dir":      req.WorkingDir,
+	}
+}
+
+// openHandle opens the handle and counts it as an active session of the node
+// until it is closed, which keeps the node from being reaped as idle.
+func (s *ExecuteService) openHandle(ctx context.Context, nodeID domain.NodeID, execProviderID domain.ExecProviderID) (port.ExecHandle, error) {
+	handle, err := s.open(ctx, nodeID, execProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := slog.With("node_id", nodeID, "exec_handle_id", handle.ID())
+	logger.DebugContext(ctx, "exec handle opened")
+
+	s.touch(nodeID, 1)
+	return &sessionHandle{ExecHandle: handle, end: func() {
+		logger.DebugContext(ctx, "exec handle closed")
+		s.touch(nodeID, -1)
+	}}, nil
+}
+
+func (s *ExecuteService) touch(nodeID domain.NodeID, sessions int) {
+	_, _ = s.nodeRepository.UpdateFunc(nodeID, func(node *domain.Node) error {
+		node.Lease.LastActivity = time.Now()
+		node.Lease.Sessions = max(node.Lease.Sessions+sessions, 0)
+		return nil
+	})
+}
+
+// open uses the requested exec provider, or when none is given the one of the
+// first exec capability of the node in sorted order. Every call of the
+// service opens a handle, so this is where users of the node are authorized.
+func (s *ExecuteService) open(ctx context.Context, nodeID domain.NodeID, execProviderID domain.ExecProviderID) (port.ExecHandle, error) {
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("loading node: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleUser, node.TenantID); err != nil {
+		return nil, err
+	}
+
+	if node.State != domain.NodeStateRunning {
+		return nil, domain.Conflict("node %s is %s, not running", nodeID, node.State)
+	}
+
+	if execProviderID != "" {
+		provider, err := s.execProviderRepository.Get(execProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("loading exec provider %q: %w", execProviderID, err)
+		}
+		return s.openWith(ctx, *provider, node)
+	}
+
+	for _, c := range slices.Sorted(maps.Keys(node.Cap)) {
+		id, found := domain.ExecProviderOf(c)
+		if !node.Cap[c] || !found {
+			continue
+		}
+
+		provider, err := s.execProviderRepository.Get(id)
+		if err != nil {
+			continue
+		}
+
+		handle, err := s.openWith(ctx, *provider, node)
+		if err != nil {
+			return nil, fmt.Errorf("opening exec handle: %w", err)
+		}
+		return handle, nil
+	}
+
+	return nil, &domain.CapabilityMissingError{NodeID: nodeID, Cap: "exec:"}
+}
+
+// hasExecCap reports whether the node has the capability of any executor.
+func hasExecCap(node *domain.Node) bool {
+	for c, ok := range node.Cap {
+		if _, found := domain.ExecProviderOf(c); ok && found {
+			return true
+		}
+	}
+	return false
+}
+
+// openWith retries opening the handle according to the ExecOpen policy of the
+// node provider, exec has no operation so failed attempts are only logged.
+func (s *ExecuteService) openWith(ctx context.Context, provider port.NodeExecProvider, node *domain.Node) (port.ExecHandle, error) {
+	var handle port.ExecHandle
+	policy := retryPolicies(s.retryRepository, node.ProviderID).ExecOpen
+	err := retry(ctx, policy, func(ctx context.Context) (err error) {
+		_, span := util.StartSpan(ctx, "NodeExecProvider.OpenExecHandle", attribute.String("exec_provider_id", string(provider.ID())))
+		defer util.EndSpan(span, &err)
+
+		handle, err = provider.OpenExecHandle(node)
+		return err
+	}, func(attempt domain.OperationAttempt) {
+		if attempt.Error != "" {
+			slog.WarnContext(ctx, "opening exec handle", "node_id", node.NodeID, "provider_id", node.ProviderID, "exec_provider_id", provider.ID(), "attempt", attempt.Attempt, "retryable", attempt.Retryable, "err", attempt.Error)
+		}
+	})
+	return handle, err
+}
+
+// withTraceEnv adds the trace context of ctx to env, variables set by the
+// caller take precedence.
+func withTraceEnv(ctx context.Context, env map[string]string) map[string]string {
+	out := util.TraceEnv(ctx)
+	if len(out) == 0 {
+		return env
+	}
+	maps.Copy(out, env)
+	return out
+}
+
+// closeWith releases the handle once the attached process exits or the
+// caller closes it, whichever happens first.
+func closeWith(res *domain.AttachResult, handle port.ExecHandle) *domain.AttachResult {
+	var once sync.Once
+	release := func() { once.Do(func() { handle.Close() }) }
+
+	closeFn, waitFn := res.Close, res.Wait
+	go func() {
+		waitFn()
+		release()
+	}()
+
+	res.Close = func() error {
+		defer release()
+		return closeFn()
+	}
+	return res
+}
+
+// abortOnDone closes the handle once ctx is done, which aborts whatever runs
+// on it. The returned function stops watching ctx.
+func abortOnDone(ctx context.Context, handle port.ExecHandle) func() bool {
+	return context.AfterFunc(ctx, func() { handle.Close() })
+}
+
+type sessionHandle struct {
+	port.ExecHandle
+
+	once sync.Once
+	end  func()
+	err  error
+}
+
+// Close may be called both when ctx is done and by the caller, the handle
+// is only closed once.
+func (h *sessionHandle) Close() error {
+	h.once.Do(func() {
+		h.end()
+		h.err = h.ExecHandle.Close()
+	})
+	return h.err
+}
+
+var _ port.NodeExecuteService = (*ExecuteService)(nil)
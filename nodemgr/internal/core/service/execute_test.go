@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/port/mocks"
+	"nodemgr/internal/core/util"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/mock/gomock"
+)
+
+// blockingExecHandle runs commands until the handle is closed.
+type blockingExecHandle struct {
+	port.ExecHandle
+	closed chan struct{}
+}
+
+func (h *blockingExecHandle) ID() domain.ExecHandleID {
+	return "blocking"
+}
+
+func (h *blockingExecHandle) Close() error {
+	close(h.closed)
+	return nil
+}
+
+func (h *blockingExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+	<-h.closed
+	return nil, errors.New("connection closed")
+}
+
+type fakeExecProvider struct {
+	handle port.ExecHandle
+}
+
+func (p *fakeExecProvider) ID() domain.ExecProviderID {
+	return "fake"
+}
+
+func (p *fakeExecProvider) OpenExecHandle(node *domain.Node) (port.ExecHandle, error) {
+	return p.handle, nil
+}
+
+// streamExecHandle streams commands exiting with the exit code of the handle.
+type streamExecHandle struct {
+	port.ExecHandle
+	exitCode int
+}
+
+func (h *streamExecHandle) ID() domain.ExecHandleID {
+	return "stream"
+}
+
+func (h *streamExecHandle) Close() error {
+	return nil
+}
+
+func (h *streamExecHandle) ExecStream(exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
+	exitCode := make(chan int, 1)
+	exitCode <- h.exitCode
+	close(exitCode)
+	return &domain.AttachResult{
+		ExitCode: exitCode,
+		Close:    func() error { return nil },
+		Wait:     func() error { return nil },
+	}, nil
+}
+
+func (h *streamExecHandle) Attach(attach domain.AttachRequest) (*domain.AttachResult, error) {
+	return h.ExecStream(domain.ExecRequest{}, attach)
+}
+
+// recordingExecHandle keeps the last exec request.
+type recordingExecHandle struct {
+	streamExecHandle
+	req domain.ExecRequest
+}
+
+func (h *recordingExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+	h.req = req
+	return &domain.ExecResult{ExitCode: h.exitCode}, nil
+}
+
+func (h *recordingExecHandle) ExecStream(exec domain.ExecRequest, attach domain.AttachRequest) (*domain.AttachResult, error) {
+	h.req = exec
+	return h.streamExecHandle.ExecStream(exec, attach)
+}
+
+type observedExec struct {
+	exitCode int
+	err      error
+}
+
+// recordingMetrics keeps the observed execs.
+type recordingMetrics struct {
+	port.MetricsRecorder
+	execs chan observedExec
+}
+
+func (m *recordingMetrics) ObserveExec(providerID domain.ProviderID, duration time.Duration, exitCode int, err error) {
+	m.execs <- observedExec{exitCode: exitCode, err: err}
+}
+
+func newTestExecuteService(t *testing.T, handle port.ExecHandle) *ExecuteService {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	metrics := mocks.NewMockMetricsRecorder(ctrl)
+	metrics.EXPECT().ObserveExec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	return newTestExecuteServiceWith(t, handle, metrics)
+}
+
+func newTestExecuteServiceWith(t *testing.T, handle port.ExecHandle, metrics port.MetricsRecorder) *ExecuteService {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	nodes.Create(domain.Node{
+		NodeID:   "node-1",
+		TenantID: "project-a",
+		State:    domain.NodeStateRunning,
+		Cap:      map[domain.Cap]bool{"exec:fake": true},
+	})
+	providers := util.NewRepository[domain.ExecProviderID, port.NodeExecProvider]()
+	providers.Create(&fakeExecProvider{handle: handle})
+
+	audit := mocks.NewMockAuditService(ctrl)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	return NewExecuteService(nodes, providers, util.NewRepository[domain.ProviderID, domain.RetryPolicies](), metrics, audit, t.TempDir())
+}
+
+func TestExecAbortedWhenContextDone(t *testing.T) {
+	handle := &blockingExecHandle{closed: make(chan struct{})}
+	s := newTestExecuteService(t, handle)
+
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Exec(ctx, domain.ExecRequest{NodeID: "node-1", Command: []string{"sleep", "infinity"}})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Exec() = %v, want the context error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("exec was not aborted with its context")
+	}
+}
+
+func TestStreamedExecsAreObserved(t *testing.T) {
+	metrics := &recordingMetrics{execs: make(chan observedExec, 1)}
+	s := newTestExecuteServiceWith(t, &streamExecHandle{exitCode: 3}, metrics)
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+
+	start := map[string]func() (*domain.AttachResult, error){
+		"exec stream": func() (*domain.AttachResult, error) {
+			return s.ExecStream(ctx, domain.ExecRequest{NodeID: "node-1", Command: []string{"make"}}, domain.AttachRequest{})
+		},
+		"attach": func() (*domain.AttachResult, error) {
+			return s.Attach(ctx, domain.AttachRequest{NodeID: "node-1"})
+		},
+	}
+
+	for name, fn := range start {
+		t.Run(name, func(t *testing.T) {
+			res, err := fn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code := <-res.ExitCode; code != 3 {
+				t.Errorf("exit code = %d, want 3", code)
+			}
+
+			select {
+			case observed := <-metrics.execs:
+				if observed.exitCode != 3 || observed.err != nil {
+					t.Errorf("observed = %+v, want exit code 3", observed)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("exec was not observed")
+			}
+		})
+	}
+}
+
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func spanAttributes(t *testing.T, exporter *tracetest.InMemoryExporter, name string) map[attribute.Key]attribute.Value {
+	t.Helper()
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes {
+				attrs[kv.Key] = kv.Value
+			}
+			return attrs
+		}
+	}
+	t.Fatalf("no %s span recorded", name)
+	return nil
+}
+
+func TestExecSpans(t *testing.T) {
+	exporter := recordSpans(t)
+	handle := &recordingExecHandle{streamExecHandle: streamExecHandle{exitCode: 4}}
+	s := newTestExecuteService(t, handle)
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+
+	command := []string{"sh", "-c", "deploy", "--token", "s3cr3t", "API_KEY=hunter2"}
+	tests := []struct {
+		span string
+		run  func() error
+	}{
+		{"ExecuteService.Exec", func() error {
+			_, err := s.Exec(ctx, domain.ExecRequest{NodeID: "node-1", Command: command})
+			return err
+		}},
+		{"ExecuteService.ExecStream", func() error {
+			res, err := s.ExecStream(ctx, domain.ExecRequest{NodeID: "node-1", Command: command}, domain.AttachRequest{})
+			if err == nil {
+				<-res.ExitCode
+			}
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.span, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			attrs := spanAttributes(t, exporter, tt.span)
+
+			if got := attrs["node_id"].AsString(); got != "node-1" {
+				t.Errorf("node_id = %q, want node-1", got)
+			}
+			recorded := strings.Join(attrs["command"].AsStringSlice(), " ")
+			if strings.Contains(recorded, "s3cr3t") || strings.Contains(recorded, "hunter2") {
+				t.Errorf("command attribute leaks secrets: %q", recorded)
+			}
+			if !slices.Contains(attrs["command"].AsStringSlice(), "deploy") {
+				t.Errorf("command attribute = %q, want the command", recorded)
+			}
+
+			// the command runs with the trace of the span and its real arguments
+			if !strings.HasPrefix(handle.req.Env["TRACEPARENT"], "00-") {
+				t.Errorf("TRACEPARENT = %q, want the trace context", handle.req.Env["TRACEPARENT"])
+			}
+			if !slices.Equal(handle.req.Command, command) {
+				t.Errorf("command = %q, want it unredacted", handle.req.Command)
+			}
+		})
+	}
+
+	if got := spanAttributes(t, exporter, "ExecuteService.Exec")["exit_code"].AsInt64(); got != 4 {
+		t.Errorf("exit_code = %d, want 4", got)
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"strings"
+	"time"
+)
+
+const leaseReapInterval = 30 * time.Second
+
+type LeaseService struct {
+	nodeRepository   port.NodeRepository
+	lifecycleService port.NodeLifecycleService
+	provisionService port.NodeProvisionService
+}
+
+func NewLeaseService(
+	nodeRepository port.NodeRepository,
+	lifecycleService port.NodeLifecycleService,
+	provisionService port.NodeProvisionService,
+) *LeaseService {
+	return &LeaseService{
+		nodeRepository:   nodeRepository,
+		lifecycleService: lifecycleService,
+		provisionService: provisionService,
+	}
+}
+
+func (s *LeaseService) Heartbeat(ctx context.Context, nodeID domain.NodeID, extend time.Duration) (*domain.NodeLease, error) {
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("loading node: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleUser, node.TenantID); err != nil {
+		return nil, err
+	}
+
+	// only the lease is changed, state and history stay as they are stored
+	node, err = s.nodeRepository.UpdateFunc(nodeID, func(node *domain.Node) error {
+		if node.State == domain.NodeStateTerminated || node.State == domain.NodeStateShuttingDown {
+			return fmt.Errorf("node %s is %s", nodeID, node.State)
+		}
+
+		now := time.Now()
+		node.Lease.LastActivity = now
+		if deadline := now.Add(extend); extend > 0 && !node.Lease.ExpiresAt.IsZero() && deadline.After(node.Lease.ExpiresAt) {
+			node.Lease.ExpiresAt = deadline
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storing node: %w", err)
+	}
+	return &node.Lease, nil
+}
+
+func (s *LeaseService) Run(ctx context.Context) {
+	ctx = asSystem(ctx)
+	ticker := time.NewTicker(leaseReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reap(ctx, time.Now())
+		}
+	}
+}
+
+func (s *LeaseService) reap(ctx context.Context, now time.Time) {
+	nodes, err := s.nodeRepository.List()
+	if err != nil {
+		slog.Error("listing nodes", "err", err)
+		return
+	}
+
+	for _, node := range nodes {
+		// idle pooled nodes are retired by the pool itself
+		if node.PoolID != "" {
+			continue
+		}
+
+		expired, reason := node.Lease.Expired(now)
+		if !expired {
+			continue
+		}
+
+		action := node.Lease.Action()
+		switch {
+		case node.State == domain.NodeStateRunning:
+		case node.State == domain.NodeStateStopped && (action == domain.ExpireActionTerminate || node.Lease.TTLExpired(now)):
+			// stopped nodes past their ttl can not be started again, so
+			// they are terminated even when expiring only stops nodes
+			action = domain.ExpireActionTerminate
+		default:
+			continue
+		}
+
+		if err := s.expire(ctx, node, action); err != nil {
+			slog.Error("expiring node", "node_id", node.NodeID, "provider_id", node.ProviderID, "reason", reason, "err", err)
+			continue
+		}
+		slog.Info("node lease expired", "node_id", node.NodeID, "provider_id", node.ProviderID, "reason", reason, "action", action)
+	}
+}
+
+// expire stops the node through its lifecycle provider. Termination goes
+// through the provision service so providers release everything they hold
+// for the node, like pulumi stacks.
+func (s *LeaseService) expire(ctx context.Context, node *domain.Node, action domain.ExpireAction) error {
+	if action == domain.ExpireActionStop {
+		if !hasCapPrefix(node, "lifecycle:") {
+			return &domain.CapabilityMissingError{NodeID: node.NodeID, Cap: "lifecycle:"}
+		}
+		return s.lifecycleService.StopNode(ctx, node.NodeID)
+	}
+
+	_, err := s.provisionService.DestroyNode(ctx, node.NodeID)
+	return err
+}
+
+func hasCapPrefix(node *domain.Node, prefix string) bool {
+	for c, ok := range node.Cap {
+		if ok && strings.HasPrefix(string(c), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ port.NodeLeaseService = (*LeaseService)(nil)
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/port/mocks"
+	"nodemgr/internal/core/util"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestLeaseReap(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	lifecycleCap := map[domain.Cap]bool{"lifecycle:docker": true}
+
+	ttlExpired := func(action domain.ExpireAction) domain.NodeLease {
+		return domain.NodeLease{
+			LeasePolicy:  domain.LeasePolicy{TTL: time.Hour, OnExpire: action},
+			ExpiresAt:    now.Add(-time.Minute),
+			LastActivity: now,
+		}
+	}
+	idle := func(action domain.ExpireAction) domain.NodeLease {
+		return domain.NodeLease{
+			LeasePolicy:  domain.LeasePolicy{IdleTimeout: time.Minute, OnExpire: action},
+			LastActivity: now.Add(-time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name string
+		node domain.Node
+		want string
+	}{
+		{
+			name: "active node",
+			node: domain.Node{State: domain.NodeStateRunning, Cap: lifecycleCap, Lease: domain.NewNodeLease(domain.LeasePolicy{TTL: time.Hour}, now)},
+		},
+		{"running past ttl", domain.Node{State: domain.NodeStateRunning, Lease: ttlExpired("")}, "destroy"},
+		{"running idle", domain.Node{State: domain.NodeStateRunning, Lease: idle(domain.ExpireActionTerminate)}, "destroy"},
+		{"running past ttl stops", domain.Node{State: domain.NodeStateRunning, Cap: lifecycleCap, Lease: ttlExpired(domain.ExpireActionStop)}, "stop"},
+		{"running idle stops", domain.Node{State: domain.NodeStateRunning, Cap: lifecycleCap, Lease: idle(domain.ExpireActionStop)}, "stop"},
+		{"running idle without lifecycle", domain.Node{State: domain.NodeStateRunning, Lease: idle(domain.ExpireActionStop)}, ""},
+		{"stopped past ttl", domain.Node{State: domain.NodeStateStopped, Lease: ttlExpired(domain.ExpireActionTerminate)}, "destroy"},
+		{"stopped past ttl after stopping", domain.Node{State: domain.NodeStateStopped, Cap: lifecycleCap, Lease: ttlExpired(domain.ExpireActionStop)}, "destroy"},
+		{"stopped idle", domain.Node{State: domain.NodeStateStopped, Lease: idle(domain.ExpireActionTerminate)}, "destroy"},
+		{"stopped idle stays stopped", domain.Node{State: domain.NodeStateStopped, Cap: lifecycleCap, Lease: idle(domain.ExpireActionStop)}, ""},
+		{"pending", domain.Node{State: domain.NodeStatePending, Lease: ttlExpired("")}, ""},
+		{"shutting down", domain.Node{State: domain.NodeStateShuttingDown, Lease: ttlExpired("")}, ""},
+		{"pooled", domain.Node{State: domain.NodeStateRunning, PoolID: "warm", Lease: idle("")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			lifecycle := mocks.NewMockNodeLifecycleService(ctrl)
+			provision := mocks.NewMockNodeProvisionService(ctrl)
+
+			nodes := util.NewRepository[domain.NodeID, domain.Node]()
+			node := tt.node
+			node.NodeID = "node-1"
+			nodes.Create(node)
+
+			switch tt.want {
+			case "stop":
+				lifecycle.EXPECT().StopNode(gomock.Any(), domain.NodeID("node-1"))
+			case "destroy":
+				provision.EXPECT().DestroyNode(gomock.Any(), domain.NodeID("node-1"))
+			}
+
+			NewLeaseService(nodes, lifecycle, provision).reap(asSystem(context.Background()), now)
+		})
+	}
+}
+
+func TestLeaseHeartbeat(t *testing.T) {
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	s := NewLeaseService(nodes, nil, nil)
+
+	start := time.Now().Add(-time.Hour)
+	nodes.Create(domain.Node{
+		NodeID:   "node-1",
+		TenantID: "project-a",
+		State:    domain.NodeStateRunning,
+		Lease:    domain.NewNodeLease(domain.LeasePolicy{TTL: 90 * time.Minute, IdleTimeout: time.Minute}, start),
+	})
+
+	lease, err := s.Heartbeat(userA, "node-1", time.Hour)
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if expired, reason := lease.Expired(time.Now()); expired {
+		t.Errorf("lease expired after heartbeat: %s", reason)
+	}
+	if lease.ExpiresAt.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("deadline %v was not extended by an hour", lease.ExpiresAt)
+	}
+
+	// a shorter extension never moves the deadline back
+	deadline := lease.ExpiresAt
+	if lease, err = s.Heartbeat(userA, "node-1", time.Minute); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if !lease.ExpiresAt.Equal(deadline) {
+		t.Errorf("deadline = %v, want %v", lease.ExpiresAt, deadline)
+	}
+
+	if _, err := s.Heartbeat(viewerA, "node-1", time.Hour); domain.Code(err) != domain.ErrorCodePermissionDenied {
+		t.Errorf("viewer heartbeat code = %q, want permission_denied", domain.Code(err))
+	}
+}
+
+func TestStartRejectedPastTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	audit := mocks.NewMockAuditService(ctrl)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	lifecycle := mocks.NewMockNodeLifecycle(ctrl)
+
+	lifecycles := util.NewRepository[domain.LifecycleProviderID, port.NodeLifecycle]()
+	lifecycle.EXPECT().ID().Return(domain.LifecycleProviderID("docker")).AnyTimes()
+	lifecycles.Create(lifecycle)
+
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	nodes.Create(domain.Node{
+		NodeID:   "node-1",
+		TenantID: "project-a",
+		State:    domain.NodeStateStopped,
+		Cap:      map[domain.Cap]bool{"lifecycle:docker": true},
+		Lease:    domain.NewNodeLease(domain.LeasePolicy{TTL: time.Hour}, time.Now().Add(-2*time.Hour)),
+	})
+
+	s := NewLifecycleService(nodes, lifecycles, nil, nil, audit)
+	if err := s.StartNode(userA, "node-1"); domain.Code(err) != domain.ErrorCodeConflict {
+		t.Fatalf("start code = %q, want conflict (err = %v)", domain.Code(err), err)
+	}
+
+	node, _ := nodes.Get("node-1")
+	if node.State != domain.NodeStateStopped {
+		t.Errorf("state = %s, want stopped", node.State)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type LifecycleService struct {
+	nodeRepository      port.NodeRepository
+	lifecycleRepository port.NodeLifecycleRepository
+	provisionService    port.NodeProvisionService
+	operationService    port.OperationService
+	auditService        port.AuditService
+}
+
+func NewLifecycleService(
+	nodeRepository port.NodeRepository,
+	lifecycleRepository port.NodeLifecycleRepository,
+	provisionService port.NodeProvisionService,
+	operationService port.OperationService,
+	auditService port.AuditService,
+) *LifecycleService {
+	return &LifecycleService{
+		nodeRepository:      nodeRepository,
+		lifecycleRepository: lifecycleRepository,
+		provisionService:    provisionService,
+		operationService:    operationService,
+		auditService:        auditService,
+	}
+}
+
+func (s *LifecycleService) StartNode(ctx context.Context, nodeID domain.NodeID) error {
+	return s.transition(ctx, nodeID, domain.NodeStatePending, domain.NodeStateRunning, domain.AuditActionStart,
+		s.withHandle(func(ctx context.Context, h port.NodeLifecycleHandle) error { return h.Start(ctx) }))
+}
+
+func (s *LifecycleService) StopNode(ctx context.Context, nodeID domain.NodeID) error {
+	return s.transition(ctx, nodeID, domain.NodeStateStopping, domain.NodeStateStopped, domain.AuditActionStop,
+		s.withHandle(func(ctx context.Context, h port.NodeLifecycleHandle) error { return h.Stop(ctx) }))
+}
+
+func (s *LifecycleService) RebootNode(ctx context.Context, nodeID domain.NodeID) error {
+	return s.transition(ctx, nodeID, domain.NodeStateRunning, domain.NodeStateRunning, domain.AuditActionReboot,
+		s.withHandle(func(ctx context.Context, h port.NodeLifecycleHandle) error { return h.Reboot(ctx) }))
+}
+
+// TerminateNode destroys the node through the provision service, so it is
+// retried, measured and audited like any other destroy and providers release
+// everything they hold for the node, like pulumi stacks. It returns once the
+// node is gone.
+func (s *LifecycleService) TerminateNode(ctx context.Context, nodeID domain.NodeID) (err error) {
+	ctx, span := util.StartSpan(ctx, "LifecycleService "+string(domain.AuditActionTerminate), attribute.String("node_id", string(nodeID)))
+	defer util.EndSpan(span, &err)
+
+	op, err := s.provisionService.DestroyNode(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	finished, err := s.operationService.WaitOperation(ctx, op.ID())
+	if err != nil {
+		return fmt.Errorf("waiting for destruction: %w", err)
+	}
+	if finished.State != domain.OperationStateSucceeded {
+		return fmt.Errorf("destroying node: %s", finished.Error)
+	}
+	return nil
+}
+
+// withHandle runs fn with a lifecycle handle of the node.
+func (s *LifecycleService) withHandle(fn func(ctx context.Context, h port.NodeLifecycleHandle) error) func(ctx context.Context, node *domain.Node) error {
+	return func(ctx context.Context, node *domain.Node) error {
+		handle, err := s.openHandle(node)
+		if err != nil {
+			return err
+		}
+		defer handle.Close()
+
+		return fn(ctx, handle)
+	}
+}
+
+func (s *LifecycleService) transition(
+	ctx context.Context,
+	nodeID domain.NodeID,
+	via domain.NodeState,
+	to domain.NodeState,
+	action domain.AuditAction,
+	fn func(ctx context.Context, node *domain.Node) error,
+) (err error) {
+	ctx, span := util.StartSpan(ctx, "LifecycleService "+string(action), attribute.String("node_id", string(nodeID)))
+	defer util.EndSpan(span, &err)
+
+	event := domain.AuditEvent{Action: action, NodeID: nodeID}
+	defer func() { s.auditService.Record(ctx, event, err) }()
+
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return fmt.Errorf("loading node: %w", err)
+	}
+	event.TenantID, event.TemplateID, event.ProviderID = node.TenantID, node.TemplateID, node.ProviderID
+	if err := authorize(ctx, domain.RoleUser, node.TenantID); err != nil {
+		return err
+	}
+	reason := string(action)
+
+	if !domain.CanTransition(node.State, via) {
+		return &domain.InvalidTransitionError{NodeID: nodeID, From: node.State, To: via}
+	}
+	if via == domain.NodeStatePending && node.Lease.TTLExpired(time.Now()) {
+		return domain.Conflict("lease of node %s expired at %s", nodeID, node.Lease.ExpiresAt.Format(time.RFC3339))
+	}
+
+	// fail before changing the state of nodes without a lifecycle
+	if _, err := s.lifecycleFor(node); err != nil {
+		return err
+	}
+
+	prevState := node.State
+	if err := s.setState(nodeID, prevState, via, reason+" requested"); err != nil {
+		return err
+	}
+
+	if err := fn(ctx, node); err != nil {
+		_ = s.setState(nodeID, via, prevState, reason+" failed")
+		return err
+	}
+
+	if via == to {
+		return nil
+	}
+	return s.setState(nodeID, via, to, reason)
+}
+
+func (s *LifecycleService) openHandle(node *domain.Node) (port.NodeLifecycleHandle, error) {
+	lifecycle, err := s.lifecycleFor(node)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := lifecycle.OpenLifecycleHandle(node)
+	if err != nil {
+		return nil, fmt.Errorf("opening lifecycle handle: %w", err)
+	}
+	return handle, nil
+}
+
+// lifecycleFor picks the lifecycle of the first `lifecycle:<id>` capability of
+// the node in sorted order, so the choice does not change between calls.
+func (s *LifecycleService) lifecycleFor(node *domain.Node) (port.NodeLifecycle, error) {
+	caps := slices.Sorted(maps.Keys(node.Cap))
+	for _, c := range caps {
+		id, found := strings.CutPrefix(string(c), "lifecycle:")
+		if !node.Cap[c] || !found {
+			continue
+		}
+
+		lifecycle, err := s.lifecycleRepository.Get(domain.LifecycleProviderID(id))
+		if err != nil {
+			continue
+		}
+		return *lifecycle, nil
+	}
+
+	return nil, &domain.CapabilityMissingError{NodeID: node.ID(), Cap: "lifecycle:"}
+}
+
+func (s *LifecycleService) setState(nodeID domain.NodeID, from domain.NodeState, to domain.NodeState, reason string) error {
+	return setNodeState(s.nodeRepository, nodeID, from, to, reason, func(node *domain.Node) {
+		if to == domain.NodeStateRunning {
+			// a started node gets a fresh idle window
+			node.Lease.LastActivity = time.Now()
+		}
+	})
+}
+
+var _ port.NodeLifecycleService = (*LifecycleService)(nil)
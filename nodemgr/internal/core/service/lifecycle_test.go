@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/port/mocks"
+	"nodemgr/internal/core/util"
+
+	"go.uber.org/mock/gomock"
+)
+
+// newTestLifecycleService manages nodes of the "docker" provider through the
+// given lifecycle, which may be nil.
+func newTestLifecycleService(t *testing.T, provider port.NodeProvider, lifecycle port.NodeLifecycle) (*LifecycleService, port.NodeRepository) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	providers := util.NewRepository[domain.ProviderID, port.NodeProvider]()
+	providers.Create(provider)
+	lifecycles := util.NewRepository[domain.LifecycleProviderID, port.NodeLifecycle]()
+	if lifecycle != nil {
+		lifecycles.Create(lifecycle)
+	}
+
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	operations := NewOperationService(util.NewRepository[domain.OperationID, domain.Operation]())
+
+	metrics := mocks.NewMockMetricsRecorder(ctrl)
+	metrics.EXPECT().ObserveDestroy(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	audit := mocks.NewMockAuditService(ctrl)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	provision := NewProvisionService(
+		nodes,
+		providers,
+		NewTemplateService(util.NewRepository[domain.TemplateID, domain.NodeTemplate]()),
+		NewMappingService(util.NewRepository[domain.MappingID, domain.NodeSpecMapping]()),
+		operations,
+		NewQuotaService(nodes, util.NewRepository[domain.TenantID, domain.Quota](), util.NewRepository[domain.ProviderID, domain.ProviderProfile]()),
+		util.NewRepository[domain.ProviderID, domain.RetryPolicies](),
+		metrics,
+		audit,
+	)
+	return NewLifecycleService(nodes, lifecycles, provision, operations, audit), nodes
+}
+
+func createTestNode(t *testing.T, nodes port.NodeRepository, state domain.NodeState) {
+	t.Helper()
+
+	node := domain.Node{
+		NodeID:     "node-1",
+		TenantID:   "project-a",
+		ProviderID: "docker",
+		State:      state,
+		Cap:        map[domain.Cap]bool{"lifecycle:docker": true},
+	}
+	if err := nodes.Create(node); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func nodeStates(node *domain.Node) []domain.NodeState {
+	states := []domain.NodeState{}
+	for _, transition := range node.History {
+		states = append(states, transition.To)
+	}
+	return states
+}
+
+func TestTerminateNode(t *testing.T) {
+	s, nodes := newTestLifecycleService(t, &fakeNodeProvider{id: "docker"}, nil)
+	createTestNode(t, nodes, domain.NodeStateStopping)
+
+	if err := s.TerminateNode(userA, "node-1"); err != nil {
+		t.Fatalf("terminate: %v", err)
+	}
+
+	node, _ := nodes.Get("node-1")
+	if want := []domain.NodeState{domain.NodeStateShuttingDown, domain.NodeStateTerminated}; !slices.Equal(nodeStates(node), want) {
+		t.Errorf("history = %v, want %v", nodeStates(node), want)
+	}
+}
+
+func TestTerminateNodeFailure(t *testing.T) {
+	tests := []struct {
+		from domain.NodeState
+		want domain.NodeState
+	}{
+		{domain.NodeStateRunning, domain.NodeStateRunning},
+		{domain.NodeStateStopped, domain.NodeStateStopped},
+		// the interrupted stop never finishes, so stopping is not restored
+		{domain.NodeStateStopping, domain.NodeStateStopped},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from), func(t *testing.T) {
+			s, nodes := newTestLifecycleService(t, &fakeNodeProvider{id: "docker", destroyErr: errors.New("boom")}, nil)
+			createTestNode(t, nodes, tt.from)
+
+			if err := s.TerminateNode(userA, "node-1"); err == nil {
+				t.Fatal("terminate succeeded")
+			}
+
+			node, _ := nodes.Get("node-1")
+			if node.State != tt.want {
+				t.Errorf("state = %s, want %s", node.State, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerminateNodeRejected(t *testing.T) {
+	s, nodes := newTestLifecycleService(t, &fakeNodeProvider{id: "docker"}, nil)
+	createTestNode(t, nodes, domain.NodeStatePending)
+
+	if err := s.TerminateNode(userA, "node-1"); domain.Code(err) != domain.ErrorCodeInvalidTransition {
+		t.Errorf("code = %q, want invalid_transition (err = %v)", domain.Code(err), err)
+	}
+	if err := s.TerminateNode(viewerA, "node-1"); domain.Code(err) != domain.ErrorCodePermissionDenied {
+		t.Errorf("viewer code = %q, want permission_denied", domain.Code(err))
+	}
+}
+
+func TestStopNode(t *testing.T) {
+	tests := []struct {
+		name    string
+		stopErr error
+		want    []domain.NodeState
+	}{
+		{"stopped", nil, []domain.NodeState{domain.NodeStateStopping, domain.NodeStateStopped}},
+		{"rolled back", errors.New("boom"), []domain.NodeState{domain.NodeStateStopping, domain.NodeStateRunning}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			handle := mocks.NewMockNodeLifecycleHandle(ctrl)
+			handle.EXPECT().Stop(gomock.Any()).Return(tt.stopErr)
+			handle.EXPECT().Close()
+			lifecycle := mocks.NewMockNodeLifecycle(ctrl)
+			lifecycle.EXPECT().ID().Return(domain.LifecycleProviderID("docker")).AnyTimes()
+			lifecycle.EXPECT().OpenLifecycleHandle(gomock.Any()).Return(handle, nil)
+
+			s, nodes := newTestLifecycleService(t, &fakeNodeProvider{id: "docker"}, lifecycle)
+			createTestNode(t, nodes, domain.NodeStateRunning)
+
+			if err := s.StopNode(userA, "node-1"); !errors.Is(err, tt.stopErr) {
+				t.Errorf("stop error = %v, want %v", err, tt.stopErr)
+			}
+
+			node, _ := nodes.Get("node-1")
+			if !slices.Equal(nodeStates(node), tt.want) {
+				t.Errorf("history = %v, want %v", nodeStates(node), tt.want)
+			}
+		})
+	}
+}
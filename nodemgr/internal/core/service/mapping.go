@@ -1,12 +1,15 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"maps"
 	"nodemgr/internal/core/domain"
 	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
 
 	"github.com/gobwas/glob"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type MappingService struct {
@@ -17,32 +20,69 @@ func NewMappingService(mappingRepository port.MappingRepository) *MappingService
 	return &MappingService{mappingRepository: mappingRepository}
 }
 
-func (s *MappingService) CreateMapping(mapping domain.NodeSpecMapping) (domain.MappingID, error) {
-	return "", fmt.Errorf("not implemented")
+func (s *MappingService) CreateMapping(ctx context.Context, mapping domain.NodeSpecMapping) (domain.MappingID, error) {
+	if err := authorize(ctx, domain.RoleAdmin, mapping.TenantID); err != nil {
+		return "", err
+	}
+	if mapping.MappingID == "" {
+		return "", domain.InvalidSpec("mapping ID is required")
+	}
+
+	if err := s.mappingRepository.Create(mapping); err != nil {
+		return "", fmt.Errorf("storing mapping: %w", err)
+	}
+	return mapping.MappingID, nil
 }
 
-func (s *MappingService) GetMapping(mappingID domain.MappingID) (*domain.NodeSpecMapping, error) {
-	return nil, fmt.Errorf("not implemented")
+func (s *MappingService) GetMapping(ctx context.Context, mappingID domain.MappingID) (*domain.NodeSpecMapping, error) {
+	mapping, err := s.mappingRepository.Get(mappingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeView(ctx, mapping.TenantID); err != nil {
+		return nil, err
+	}
+	return mapping, nil
 }
 
-func (s *MappingService) ListMappings() ([]*domain.NodeSpecMapping, error) {
-	return nil, fmt.Errorf("not implemented")
+func (s *MappingService) ListMappings(ctx context.Context) ([]*domain.NodeSpecMapping, error) {
+	mappings, err := s.mappingRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, mappings, true, func(m *domain.NodeSpecMapping) domain.TenantID { return m.TenantID })
 }
 
-func (s *MappingService) DeleteMapping(mappingID domain.MappingID) error {
-	return fmt.Errorf("not implemented")
+func (s *MappingService) DeleteMapping(ctx context.Context, mappingID domain.MappingID) error {
+	mapping, err := s.mappingRepository.Get(mappingID)
+	if err != nil {
+		return fmt.Errorf("loading mapping: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleAdmin, mapping.TenantID); err != nil {
+		return err
+	}
+	return s.mappingRepository.Delete(mappingID)
 }
 
-func (s *MappingService) ResolveSpecAliases(spec domain.NodeSpec) (domain.NodeSpec, error) {
+func (s *MappingService) ResolveSpecAliases(ctx context.Context, spec domain.NodeSpec) (_ domain.NodeSpec, err error) {
+	_, span := util.StartSpan(ctx, "MappingService.ResolveSpecAliases", attribute.String("provider_id", string(spec.ProviderID)))
+	defer util.EndSpan(span, &err)
+
 	mappings, err := s.mappingRepository.List()
 	if err != nil {
 		return domain.NodeSpec{}, fmt.Errorf("loading mappings: %w", err)
 	}
 
-	out := domain.NodeSpec{ProviderID: spec.ProviderID, Extra: map[string]any{}}
-	maps.Copy(out.Extra, spec.Extra)
+	out := spec
+	out.Extra = maps.Clone(spec.Extra)
+	if out.Extra == nil {
+		out.Extra = map[string]any{}
+	}
 
 	for _, m := range mappings {
+		if m.TenantID != "" && m.TenantID != spec.TenantID {
+			continue
+		}
 		if matchMapping(out, *m) {
 			if vals, ok := m.ProviderOverrides["all"]; ok {
 				maps.Copy(out.Extra, vals)
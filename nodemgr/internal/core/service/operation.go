@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// operationRetention is how long finished operations can still be looked up
+	operationRetention     = 24 * time.Hour
+	operationSweepInterval = 10 * time.Minute
+)
+
+type operationIDKey struct{}
+
+type operationWatcher struct {
+	ch chan domain.Operation
+	// stop unregisters the unwatch of the watcher context
+	stop func() bool
+}
+
+type OperationService struct {
+	operationRepository port.OperationRepository
+
+	mu       sync.Mutex
+	cancels  map[domain.OperationID]context.CancelFunc
+	watchers map[domain.OperationID][]operationWatcher
+}
+
+func NewOperationService(operationRepository port.OperationRepository) *OperationService {
+	return &OperationService{
+		operationRepository: operationRepository,
+		cancels:             make(map[domain.OperationID]context.CancelFunc),
+		watchers:            make(map[domain.OperationID][]operationWatcher),
+	}
+}
+
+func (s *OperationService) StartOperation(parent context.Context, kind domain.OperationKind, nodeID domain.NodeID, tenantID domain.TenantID, slots chan struct{}, fn func(ctx context.Context) error) (*domain.Operation, error) {
+	now := time.Now()
+	op := domain.Operation{
+		OperationID: domain.OperationID(uuid.New().String()),
+		Kind:        kind,
+		NodeID:      nodeID,
+		TenantID:    tenantID,
+		State:       domain.OperationStatePending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.operationRepository.Create(op); err != nil {
+		return nil, fmt.Errorf("storing operation: %w", err)
+	}
+
+	// the operation outlives the caller, only its trace and identity are carried over
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(parent))
+	if identity, ok := util.IdentityFrom(parent); ok {
+		ctx = util.WithIdentity(ctx, identity)
+	}
+	ctx = context.WithValue(ctx, operationIDKey{}, op.OperationID)
+	ctx = util.WithLogAttrs(ctx, slog.String("operation_id", string(op.OperationID)), slog.String("operation_kind", string(kind)))
+	if nodeID != "" {
+		ctx = util.WithLogAttrs(ctx, slog.String("node_id", string(nodeID)))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancels[op.OperationID] = cancel
+	s.mu.Unlock()
+
+	go s.run(ctx, op, slots, fn)
+
+	return &op, nil
+}
+
+func (s *OperationService) run(ctx context.Context, op domain.Operation, slots chan struct{}, fn func(ctx context.Context) error) {
+	ctx, _ = util.StartSpan(ctx, "Operation "+string(op.Kind),
+		attribute.String("operation_id", string(op.OperationID)),
+		attribute.String("node_id", string(op.NodeID)))
+
+	defer func() {
+		s.mu.Lock()
+		if cancel, ok := s.cancels[op.OperationID]; ok {
+			cancel()
+			delete(s.cancels, op.OperationID)
+		}
+		s.mu.Unlock()
+	}()
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+		case <-ctx.Done():
+			// fn still runs so it can roll back what it prepared for the
+			// operation, like the pending node of a provisioning
+		}
+	}
+
+	s.update(op.OperationID, func(op *domain.Operation) {
+		op.State = domain.OperationStateRunning
+	})
+	slog.DebugContext(ctx, "operation started")
+
+	s.finish(ctx, op, fn(ctx))
+}
+
+// finish stores the outcome and ends the operation span started by run.
+func (s *OperationService) finish(ctx context.Context, op domain.Operation, err error) {
+	defer util.EndSpan(trace.SpanFromContext(ctx), &err)
+
+	if err != nil {
+		slog.WarnContext(ctx, "operation failed", "code", domain.Code(err), "err", err)
+	} else {
+		slog.DebugContext(ctx, "operation succeeded")
+	}
+
+	s.update(op.OperationID, func(op *domain.Operation) {
+		switch {
+		case err == nil:
+			op.State = domain.OperationStateSucceeded
+		case errors.Is(err, context.Canceled) || ctx.Err() != nil:
+			op.State = domain.OperationStateCancelled
+			op.Error = err.Error()
+			op.Code = domain.ErrorCodeCancelled
+		default:
+			op.State = domain.OperationStateFailed
+			op.Error = err.Error()
+			op.Code = domain.Code(err)
+		}
+	})
+}
+
+// update applies fn to the stored operation, attempts may be recorded while
+// the operation runs so it always starts from the latest version.
+func (s *OperationService) update(id domain.OperationID, fn func(op *domain.Operation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the operation was created by us, the only way this fails is a broken repository
+	stored, err := s.operationRepository.Get(id)
+	if err != nil {
+		return
+	}
+	op := *stored
+	fn(&op)
+	op.UpdatedAt = time.Now()
+	_ = s.operationRepository.Update(op)
+
+	watchers := s.watchers[op.OperationID]
+	if !op.Done() {
+		for _, w := range watchers {
+			select {
+			case w.ch <- op:
+			default:
+				// slow watcher, it will still get the final state
+			}
+		}
+		return
+	}
+
+	for _, w := range watchers {
+		// make room for the final state, it must not block on a watcher
+		// that stopped reading
+		select {
+		case <-w.ch:
+		default:
+		}
+		select {
+		case w.ch <- op:
+		default:
+		}
+		close(w.ch)
+		// the watcher got everything, its context no longer matters
+		w.stop()
+	}
+	delete(s.watchers, op.OperationID)
+}
+
+// unwatch drops a watcher whose context ended before the operation did.
+func (s *OperationService) unwatch(id domain.OperationID, ch chan domain.Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watchers := s.watchers[id]
+	for i, w := range watchers {
+		if w.ch == ch {
+			s.watchers[id] = append(watchers[:i:i], watchers[i+1:]...)
+			close(ch)
+			break
+		}
+	}
+	if len(s.watchers[id]) == 0 {
+		delete(s.watchers, id)
+	}
+}
+
+func (s *OperationService) RecordAttempt(ctx context.Context, attempt domain.OperationAttempt) error {
+	id, ok := ctx.Value(operationIDKey{}).(domain.OperationID)
+	if !ok {
+		return errors.New("context does not belong to an operation")
+	}
+
+	s.update(id, func(op *domain.Operation) {
+		op.Attempts = append(op.Attempts, attempt)
+	})
+	return nil
+}
+
+func (s *OperationService) GetOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error) {
+	op, err := s.operationRepository.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, domain.RoleViewer, op.TenantID); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (s *OperationService) ListOperations(ctx context.Context) ([]*domain.Operation, error) {
+	ops, err := s.operationRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, ops, false, func(op *domain.Operation) domain.TenantID { return op.TenantID })
+}
+
+func (s *OperationService) WatchOperation(ctx context.Context, id domain.OperationID) (<-chan domain.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, err := s.operationRepository.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("loading operation: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleViewer, op.TenantID); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan domain.Operation, 4)
+	ch <- *op
+	if op.Done() {
+		close(ch)
+		return ch, nil
+	}
+
+	stop := context.AfterFunc(ctx, func() { s.unwatch(id, ch) })
+	s.watchers[id] = append(s.watchers[id], operationWatcher{ch: ch, stop: stop})
+	return ch, nil
+}
+
+func (s *OperationService) WaitOperation(ctx context.Context, id domain.OperationID) (*domain.Operation, error) {
+	updates, err := s.WatchOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var last domain.Operation
+	for {
+		select {
+		case op, ok := <-updates:
+			if !ok {
+				if !last.Done() {
+					// unwatched because ctx ended
+					return nil, ctx.Err()
+				}
+				return &last, nil
+			}
+			last = op
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *OperationService) CancelOperation(ctx context.Context, id domain.OperationID) error {
+	op, err := s.operationRepository.Get(id)
+	if err != nil {
+		return fmt.Errorf("loading operation: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleUser, op.TenantID); err != nil {
+		return err
+	}
+	if op.Done() {
+		return domain.Conflict("operation already %s", op.State)
+	}
+
+	s.mu.Lock()
+	cancel, ok := s.cancels[id]
+	s.mu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return nil
+}
+
+func (s *OperationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(operationSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(time.Now())
+		}
+	}
+}
+
+// sweep deletes operations which finished more than operationRetention
+// before now, running operations are kept however old they are.
+func (s *OperationService) sweep(now time.Time) {
+	ops, err := s.operationRepository.List()
+	if err != nil {
+		slog.Error("listing operations", "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, op := range ops {
+		if !op.Done() || now.Sub(op.UpdatedAt) <= operationRetention {
+			continue
+		}
+		if err := s.operationRepository.Delete(op.OperationID); err != nil {
+			slog.Error("deleting operation", "operation_id", op.OperationID, "err", err)
+		}
+	}
+}
+
+var _ port.OperationService = (*OperationService)(nil)
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/util"
+)
+
+// afterFuncContext counts the functions registered with context.AfterFunc
+// and how many of them were stopped again.
+type afterFuncContext struct {
+	context.Context
+	done chan struct{}
+
+	mu         sync.Mutex
+	registered int
+	stopped    int
+}
+
+func (c *afterFuncContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *afterFuncContext) AfterFunc(f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registered++
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.stopped++
+		return true
+	}
+}
+
+func TestWatchOperationStopsUnwatchWhenDone(t *testing.T) {
+	s := NewOperationService(util.NewRepository[domain.OperationID, domain.Operation]())
+	release := make(chan struct{})
+	op, err := s.StartOperation(globalAdmin, domain.OperationKindProvision, "node-1", "", nil, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := &afterFuncContext{Context: globalAdmin, done: make(chan struct{})}
+	updates, err := s.WatchOperation(ctx, op.ID())
+	if err != nil {
+		t.Fatalf("watching: %v", err)
+	}
+	close(release)
+
+	var last domain.Operation
+	for op := range updates {
+		last = op
+	}
+	if last.State != domain.OperationStateSucceeded {
+		t.Errorf("last update = %s, want succeeded", last.State)
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.registered != 1 || ctx.stopped != 1 {
+		t.Errorf("registered %d and stopped %d unwatches, want the one stopped", ctx.registered, ctx.stopped)
+	}
+}
+
+func TestWatchOperationUnwatchedWithContext(t *testing.T) {
+	s := NewOperationService(util.NewRepository[domain.OperationID, domain.Operation]())
+	release := make(chan struct{})
+	defer close(release)
+	op, err := s.StartOperation(globalAdmin, domain.OperationKindProvision, "node-1", "", nil, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(globalAdmin)
+	if _, err := s.WatchOperation(ctx, op.ID()); err != nil {
+		t.Fatalf("watching: %v", err)
+	}
+	cancel()
+	if _, err := s.WaitOperation(ctx, op.ID()); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want cancelled", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		watchers := len(s.watchers[op.ID()])
+		s.mu.Unlock()
+		if watchers == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d watchers left after their contexts ended", watchers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOperationSweep(t *testing.T) {
+	operations := util.NewRepository[domain.OperationID, domain.Operation]()
+	s := NewOperationService(operations)
+
+	now := time.Now()
+	for _, op := range []domain.Operation{
+		{OperationID: "old-succeeded", State: domain.OperationStateSucceeded, UpdatedAt: now.Add(-operationRetention - time.Minute)},
+		{OperationID: "old-failed", State: domain.OperationStateFailed, UpdatedAt: now.Add(-operationRetention - time.Minute)},
+		{OperationID: "recent", State: domain.OperationStateCancelled, UpdatedAt: now.Add(-time.Minute)},
+		{OperationID: "old-running", State: domain.OperationStateRunning, UpdatedAt: now.Add(-2 * operationRetention)},
+		{OperationID: "old-pending", State: domain.OperationStatePending, UpdatedAt: now.Add(-2 * operationRetention)},
+	} {
+		if err := operations.Create(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.sweep(now)
+
+	for id, kept := range map[domain.OperationID]bool{
+		"old-succeeded": false,
+		"old-failed":    false,
+		"recent":        true,
+		"old-running":   true,
+		"old-pending":   true,
+	} {
+		if _, err := s.GetOperation(globalAdmin, id); (err == nil) != kept {
+			t.Errorf("%s: err = %v, want kept %v", id, err, kept)
+		}
+	}
+}
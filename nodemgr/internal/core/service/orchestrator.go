@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OrchestratorService runs jobs as sagas. Every completed step that holds
+// resources registers a compensation, when a later step fails the
+// compensations run in reverse order so a failed job never leaks its node.
+type OrchestratorService struct {
+	jobRepository    port.JobRepository
+	provisionService port.NodeProvisionService
+	executeService   port.NodeExecuteService
+	lifecycleService port.NodeLifecycleService
+	operationService port.OperationService
+}
+
+func NewOrchestratorService(
+	jobRepository port.JobRepository,
+	provisionService port.NodeProvisionService,
+	executeService port.NodeExecuteService,
+	lifecycleService port.NodeLifecycleService,
+	operationService port.OperationService,
+) *OrchestratorService {
+	return &OrchestratorService{
+		jobRepository:    jobRepository,
+		provisionService: provisionService,
+		executeService:   executeService,
+		lifecycleService: lifecycleService,
+		operationService: operationService,
+	}
+}
+
+func (s *OrchestratorService) SubmitJob(ctx context.Context, job domain.Job) (_ *domain.Job, err error) {
+	ctx, span := util.StartSpan(ctx, "OrchestratorService.SubmitJob",
+		attribute.String("template_id", string(job.TemplateID)),
+		attribute.String("provider_id", string(job.ProviderID)),
+		attribute.String("tenant_id", string(job.TenantID)))
+	defer util.EndSpan(span, &err)
+
+	if err := authorize(ctx, domain.RoleUser, job.TenantID); err != nil {
+		return nil, err
+	}
+	if job.TemplateID == "" || job.ProviderID == "" {
+		return nil, domain.InvalidSpec("template and provider are required")
+	}
+	for i, cmd := range job.Commands {
+		if len(cmd.Command) == 0 {
+			return nil, domain.InvalidSpec("command %d is empty", i)
+		}
+	}
+	switch job.Teardown {
+	case "":
+		job.Teardown = domain.TeardownPolicyDestroy
+	case domain.TeardownPolicyDestroy, domain.TeardownPolicyStop, domain.TeardownPolicyKeep:
+	default:
+		return nil, domain.InvalidSpec("unknown teardown policy %q", job.Teardown)
+	}
+
+	now := time.Now()
+	job.JobID = domain.JobID(uuid.New().String())
+	span.SetAttributes(attribute.String("job_id", string(job.JobID)))
+	job.State = domain.JobStatePending
+	job.NodeID = ""
+	job.Steps = nil
+	job.Error = ""
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if err := s.jobRepository.Create(job); err != nil {
+		return nil, fmt.Errorf("storing job: %w", err)
+	}
+
+	// the job must know its operation before the saga starts updating it
+	ready := make(chan struct{})
+	op, err := s.operationService.StartOperation(ctx, domain.OperationKindJob, "", job.TenantID, nil, func(ctx context.Context) error {
+		<-ready
+		return s.run(ctx, job)
+	})
+	if err != nil {
+		job.State = domain.JobStateFailed
+		job.Error = err.Error()
+		s.save(&job)
+		return nil, fmt.Errorf("starting job: %w", err)
+	}
+
+	job.OperationID = op.ID()
+	s.save(&job)
+	close(ready)
+
+	return &job, nil
+}
+
+func (s *OrchestratorService) GetJob(ctx context.Context, id domain.JobID) (*domain.Job, error) {
+	job, err := s.jobRepository.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, domain.RoleViewer, job.TenantID); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *OrchestratorService) ListJobs(ctx context.Context) ([]*domain.Job, error) {
+	jobs, err := s.jobRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, jobs, false, func(job *domain.Job) domain.TenantID { return job.TenantID })
+}
+
+// saga holds the compensations of completed steps, newest last.
+type saga struct {
+	job           *domain.Job
+	compensations []func(ctx context.Context) error
+}
+
+func (s *OrchestratorService) run(ctx context.Context, job domain.Job) error {
+	job.State = domain.JobStateRunning
+	s.save(&job)
+
+	sg := &saga{job: &job}
+	err := s.steps(ctx, sg)
+	if err == nil {
+		job.State = domain.JobStateSucceeded
+		s.save(&job)
+		return nil
+	}
+
+	for i := len(sg.compensations) - 1; i >= 0; i-- {
+		if cerr := sg.compensations[i](ctx); cerr != nil {
+			slog.ErrorContext(ctx, "compensating job", "job_id", job.JobID, "node_id", job.NodeID, "err", cerr)
+			err = errors.Join(err, cerr)
+		}
+	}
+
+	job.State = domain.JobStateFailed
+	job.Error = err.Error()
+	s.save(&job)
+	return err
+}
+
+func (s *OrchestratorService) steps(ctx context.Context, sg *saga) error {
+	job := sg.job
+
+	err := s.step(ctx, sg, "provision", func(ctx context.Context, step *domain.JobStep) error {
+		nodeID, err := s.provision(ctx, job)
+		if err != nil {
+			return err
+		}
+		job.NodeID = nodeID
+
+		idx := len(job.Steps)
+		sg.compensations = append(sg.compensations, func(ctx context.Context) (err error) {
+			ctx, span := util.StartSpan(ctx, "Job compensate provision", attribute.String("node_id", string(nodeID)))
+			defer util.EndSpan(span, &err)
+
+			if err := s.destroy(ctx, nodeID); err != nil {
+				return err
+			}
+			job.Steps[idx].State = domain.JobStepStateCompensated
+			s.save(job)
+			return nil
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range job.Inputs {
+		err := s.step(ctx, sg, "copy in "+f.Dst, func(ctx context.Context, step *domain.JobStep) error {
+			return s.executeService.CopyTo(ctx, domain.CopyToRequest{NodeID: job.NodeID, Src: f.Src, Dst: f.Dst})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, cmd := range job.Commands {
+		err := s.step(ctx, sg, "exec "+strings.Join(util.RedactArgs(cmd.Command), " "), func(ctx context.Context, step *domain.JobStep) error {
+			res, err := s.executeService.Exec(ctx, domain.ExecRequest{
+				NodeID:     job.NodeID,
+				Command:    cmd.Command,
+				Env:        cmd.Env,
+				WorkingDir: cmd.WorkingDir,
+			})
+			if err != nil {
+				return err
+			}
+
+			step.ExitCode, step.Stdout, step.Stderr = res.ExitCode, res.Stdout, res.Stderr
+			if res.ExitCode != 0 {
+				return fmt.Errorf("exited with %d", res.ExitCode)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range job.Artifacts {
+		err := s.step(ctx, sg, "copy out "+f.Src, func(ctx context.Context, step *domain.JobStep) error {
+			return s.executeService.CopyFrom(ctx, domain.CopyFromRequest{NodeID: job.NodeID, Src: f.Src, Dst: f.Dst})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.step(ctx, sg, "teardown "+string(job.Teardown), func(ctx context.Context, step *domain.JobStep) error {
+		switch job.Teardown {
+		case domain.TeardownPolicyStop:
+			return s.lifecycleService.StopNode(ctx, job.NodeID)
+		case domain.TeardownPolicyKeep:
+			return nil
+		default:
+			if err := s.destroy(ctx, job.NodeID); err != nil {
+				return err
+			}
+			// nothing is left to compensate
+			sg.compensations = nil
+			return nil
+		}
+	})
+}
+
+// step runs fn and records its outcome on the job, no new step starts once
+// the job is cancelled.
+func (s *OrchestratorService) step(ctx context.Context, sg *saga, name string, fn func(ctx context.Context, step *domain.JobStep) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, span := util.StartSpan(ctx, "Job step "+name, attribute.String("job_id", string(sg.job.JobID)))
+	defer util.EndSpan(span, &err)
+
+	step := domain.JobStep{Name: name, StartedAt: time.Now()}
+	err = fn(ctx, &step)
+	step.FinishedAt = time.Now()
+
+	if err != nil {
+		step.State = domain.JobStepStateFailed
+		step.Error = err.Error()
+		err = fmt.Errorf("%s: %w", name, err)
+	} else {
+		step.State = domain.JobStepStateSucceeded
+	}
+
+	sg.job.Steps = append(sg.job.Steps, step)
+	s.save(sg.job)
+	return err
+}
+
+func (s *OrchestratorService) provision(ctx context.Context, job *domain.Job) (domain.NodeID, error) {
+	op, err := s.provisionService.ProvisionFromTemplate(ctx, job.TemplateID, job.ProviderID, job.TenantID)
+	if err != nil {
+		return "", err
+	}
+
+	finished, err := s.operationService.WaitOperation(ctx, op.ID())
+	if err != nil {
+		// the job was cancelled, a cancelled provision destroys the node
+		// itself should it still come up
+		_ = s.operationService.CancelOperation(ctx, op.ID())
+		return "", err
+	}
+	if finished.State != domain.OperationStateSucceeded {
+		return "", fmt.Errorf("provisioning %s: %s", finished.State, finished.Error)
+	}
+	return finished.NodeID, nil
+}
+
+// destroy waits for the node to be gone, it is not cancelled with the job
+// context so compensations still run after cancellation.
+func (s *OrchestratorService) destroy(ctx context.Context, nodeID domain.NodeID) error {
+	ctx = context.WithoutCancel(ctx)
+	op, err := s.provisionService.DestroyNode(ctx, nodeID)
+	if err != nil {
+		return fmt.Errorf("destroying node %s: %w", nodeID, err)
+	}
+
+	finished, err := s.operationService.WaitOperation(ctx, op.ID())
+	if err != nil {
+		return fmt.Errorf("waiting for destruction of %s: %w", nodeID, err)
+	}
+	if finished.State != domain.OperationStateSucceeded {
+		return fmt.Errorf("destroying node %s: %s", nodeID, finished.Error)
+	}
+	return nil
+}
+
+func (s *OrchestratorService) save(job *domain.Job) {
+	job.UpdatedAt = time.Now()
+	if err := s.jobRepository.Update(*job); err != nil {
+		slog.Error("storing job", "job_id", job.JobID, "err", err)
+	}
+}
+
+var _ port.OrchestratorService = (*OrchestratorService)(nil)
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+)
+
+// fakeJobProvisioner provisions "node-1" in the tenant of the test jobs and
+// records destroyed nodes.
+type fakeJobProvisioner struct {
+	port.NodeProvisionService
+	operations port.OperationService
+	// provision runs as the provisioning operation when set
+	provision func(ctx context.Context) error
+
+	mu        sync.Mutex
+	destroyed []domain.NodeID
+}
+
+func (p *fakeJobProvisioner) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (*domain.Operation, error) {
+	return p.operations.StartOperation(ctx, domain.OperationKindProvision, "node-1", tenantID, nil, func(ctx context.Context) error {
+		if p.provision != nil {
+			return p.provision(ctx)
+		}
+		return nil
+	})
+}
+
+func (p *fakeJobProvisioner) DestroyNode(ctx context.Context, nodeID domain.NodeID) (*domain.Operation, error) {
+	return p.operations.StartOperation(ctx, domain.OperationKindDestroy, nodeID, "project-a", nil, func(ctx context.Context) error {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.destroyed = append(p.destroyed, nodeID)
+		return nil
+	})
+}
+
+func (p *fakeJobProvisioner) destroyedNodes() []domain.NodeID {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.destroyed)
+}
+
+// fakeJobExecutor runs exec as every command of the job.
+type fakeJobExecutor struct {
+	port.NodeExecuteService
+	exec func(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error)
+}
+
+func (e *fakeJobExecutor) Exec(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error) {
+	return e.exec(ctx, req)
+}
+
+func newTestOrchestrator(exec func(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error)) (*OrchestratorService, *fakeJobProvisioner, port.OperationService) {
+	operations := NewOperationService(util.NewRepository[domain.OperationID, domain.Operation]())
+	provisioner := &fakeJobProvisioner{operations: operations}
+	executor := &fakeJobExecutor{exec: exec}
+
+	orchestrator := NewOrchestratorService(util.NewRepository[domain.JobID, domain.Job](), provisioner, executor, nil, operations)
+	return orchestrator, provisioner, operations
+}
+
+func testJobContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return util.WithIdentity(ctx, domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+}
+
+func submitTestJob(t *testing.T, ctx context.Context, s *OrchestratorService) *domain.Job {
+	t.Helper()
+
+	job, err := s.SubmitJob(ctx, domain.Job{
+		TemplateID: "build",
+		ProviderID: "docker",
+		TenantID:   "project-a",
+		Commands:   []domain.JobCommand{{Command: []string{"make"}}},
+	})
+	if err != nil {
+		t.Fatalf("submitting job: %v", err)
+	}
+	return job
+}
+
+func waitTestJob(t *testing.T, ctx context.Context, s *OrchestratorService, operations port.OperationService, job *domain.Job) *domain.Job {
+	t.Helper()
+
+	if _, err := operations.WaitOperation(ctx, job.OperationID); err != nil {
+		t.Fatalf("waiting for job: %v", err)
+	}
+	finished, err := s.GetJob(ctx, job.JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return finished
+}
+
+func TestJobFailedExecDestroysNode(t *testing.T) {
+	ctx := testJobContext(t)
+	s, provisioner, operations := newTestOrchestrator(func(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error) {
+		return &domain.ExecResult{ExitCode: 2, Stderr: []byte("no rule to make target")}, nil
+	})
+
+	job := waitTestJob(t, ctx, s, operations, submitTestJob(t, ctx, s))
+
+	if job.State != domain.JobStateFailed {
+		t.Errorf("job is %s, want failed", job.State)
+	}
+	if got := provisioner.destroyedNodes(); !slices.Equal(got, []domain.NodeID{"node-1"}) {
+		t.Errorf("destroyed = %v, want the job node", got)
+	}
+	if len(job.Steps) != 2 {
+		t.Fatalf("steps = %+v, want provision and exec", job.Steps)
+	}
+	if job.Steps[0].State != domain.JobStepStateCompensated {
+		t.Errorf("provision step is %s, want compensated", job.Steps[0].State)
+	}
+	if job.Steps[1].State != domain.JobStepStateFailed || job.Steps[1].ExitCode != 2 {
+		t.Errorf("exec step = %+v, want failed with exit code 2", job.Steps[1])
+	}
+}
+
+func TestJobCancelStopsRunningExec(t *testing.T) {
+	ctx := testJobContext(t)
+	started := make(chan struct{})
+	s, provisioner, operations := newTestOrchestrator(func(ctx context.Context, req domain.ExecRequest) (*domain.ExecResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	job := submitTestJob(t, ctx, s)
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("exec did not start")
+	}
+	if err := operations.CancelOperation(ctx, job.OperationID); err != nil {
+		t.Fatalf("cancelling job: %v", err)
+	}
+
+	job = waitTestJob(t, ctx, s, operations, job)
+	if job.State != domain.JobStateFailed {
+		t.Errorf("job is %s, want failed", job.State)
+	}
+	if got := provisioner.destroyedNodes(); !slices.Equal(got, []domain.NodeID{"node-1"}) {
+		t.Errorf("destroyed = %v, want the job node", got)
+	}
+}
+
+func TestJobCancelDoesNotWaitForProvision(t *testing.T) {
+	ctx := testJobContext(t)
+	s, provisioner, operations := newTestOrchestrator(nil)
+
+	// a provider that ignores cancellation
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	started := make(chan struct{})
+	provisioner.provision = func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	job := submitTestJob(t, ctx, s)
+	select {
+	case <-started:
+	case <-ctx.Done():
+		t.Fatal("provisioning did not start")
+	}
+	if err := operations.CancelOperation(ctx, job.OperationID); err != nil {
+		t.Fatalf("cancelling job: %v", err)
+	}
+
+	job = waitTestJob(t, ctx, s, operations, job)
+	if job.State != domain.JobStateFailed {
+		t.Errorf("job is %s, want failed", job.State)
+	}
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const poolReconcileInterval = 10 * time.Second
+
+type pooledNode struct {
+	nodeID domain.NodeID
+	since  time.Time
+}
+
+// PoolService decorates the provision service with warm pools. Pools are only
+// filled while Run is running.
+type PoolService struct {
+	port.NodeProvisionService
+
+	templateService  port.TemplateService
+	poolRepository   port.NodePoolRepository
+	nodeRepository   port.NodeRepository
+	operationService port.OperationService
+	executeService   port.NodeExecuteService
+	quotaService     port.QuotaService
+	auditService     port.AuditService
+
+	mu      sync.Mutex
+	idle    map[domain.PoolID][]pooledNode
+	pending map[domain.PoolID]int
+	hits    map[domain.PoolID]int
+	misses  map[domain.PoolID]int
+	kick    chan struct{}
+}
+
+func NewPoolService(
+	provisionService port.NodeProvisionService,
+	templateService port.TemplateService,
+	poolRepository port.NodePoolRepository,
+	nodeRepository port.NodeRepository,
+	operationService port.OperationService,
+	executeService port.NodeExecuteService,
+	quotaService port.QuotaService,
+	auditService port.AuditService,
+) *PoolService {
+	return &PoolService{
+		NodeProvisionService: provisionService,
+		templateService:      templateService,
+		poolRepository:       poolRepository,
+		nodeRepository:       nodeRepository,
+		operationService:     operationService,
+		executeService:       executeService,
+		quotaService:         quotaService,
+		auditService:         auditService,
+		idle:                 make(map[domain.PoolID][]pooledNode),
+		pending:              make(map[domain.PoolID]int),
+		hits:                 make(map[domain.PoolID]int),
+		misses:               make(map[domain.PoolID]int),
+		kick:                 make(chan struct{}, 1),
+	}
+}
+
+// CreatePool only accepts shared templates, an idle node can be handed out
+// to any tenant.
+func (s *PoolService) CreatePool(ctx context.Context, pool domain.NodePool) (domain.PoolID, error) {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return "", err
+	}
+	if pool.Size < 0 {
+		return "", domain.InvalidSpec("pool size must not be negative, got %d", pool.Size)
+	}
+	if pool.PoolID == "" {
+		pool.PoolID = domain.PoolID(fmt.Sprintf("%s-%s", pool.TemplateID, pool.ProviderID))
+	}
+
+	tmpl, err := s.templateService.GetTemplate(ctx, pool.TemplateID)
+	if err != nil {
+		return "", fmt.Errorf("loading template: %w", err)
+	}
+	if tmpl.TenantID != "" {
+		return "", domain.InvalidSpec("template %q belongs to tenant %q, pools only serve shared templates", pool.TemplateID, tmpl.TenantID)
+	}
+
+	if p, _ := s.findPool(pool.TemplateID, pool.ProviderID); p != nil {
+		return "", fmt.Errorf("template %q on %q is already served: %w", pool.TemplateID, pool.ProviderID, &domain.AlreadyExistsError{Kind: "NodePool", ID: string(p.PoolID)})
+	}
+
+	if err := s.poolRepository.Create(pool); err != nil {
+		return "", fmt.Errorf("storing pool: %w", err)
+	}
+
+	s.refill()
+	return pool.PoolID, nil
+}
+
+func (s *PoolService) DeletePool(ctx context.Context, id domain.PoolID) error {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return err
+	}
+	if err := s.poolRepository.Delete(id); err != nil {
+		return fmt.Errorf("deleting pool: %w", err)
+	}
+
+	// refills still in flight retire their nodes once they see the pool is gone
+	s.mu.Lock()
+	idle := s.idle[id]
+	delete(s.idle, id)
+	delete(s.pending, id)
+	delete(s.hits, id)
+	delete(s.misses, id)
+	s.mu.Unlock()
+
+	for _, n := range idle {
+		s.retire(asSystem(ctx), n.nodeID, "pool deleted")
+	}
+	return nil
+}
+
+// UpdatePool changes size, max idle time and reset command of a pool, idle
+// nodes above a smaller size are retired by the next reconcile.
+func (s *PoolService) UpdatePool(ctx context.Context, pool domain.NodePool) error {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return err
+	}
+	if pool.Size < 0 {
+		return domain.InvalidSpec("pool size must not be negative, got %d", pool.Size)
+	}
+
+	stored, err := s.poolRepository.Get(pool.PoolID)
+	if err != nil {
+		return fmt.Errorf("loading pool: %w", err)
+	}
+	if pool.TemplateID != stored.TemplateID || pool.ProviderID != stored.ProviderID {
+		return domain.InvalidSpec("template and provider of pool %q can not be changed", pool.PoolID)
+	}
+
+	if err := s.poolRepository.Update(pool); err != nil {
+		return fmt.Errorf("storing pool: %w", err)
+	}
+
+	s.refill()
+	return nil
+}
+
+func (s *PoolService) GetPoolStatus(ctx context.Context, id domain.PoolID) (*domain.NodePoolStatus, error) {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return nil, err
+	}
+	if _, err := s.poolRepository.Get(id); err != nil {
+		return nil, fmt.Errorf("loading pool: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return &domain.NodePoolStatus{
+		PoolID:       id,
+		Idle:         len(s.idle[id]),
+		Provisioning: s.pending[id],
+		Hits:         s.hits[id],
+		Misses:       s.misses[id],
+	}, nil
+}
+
+func (s *PoolService) ListPools(ctx context.Context) ([]*domain.NodePool, error) {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return nil, err
+	}
+	return s.poolRepository.List()
+}
+
+// ProvisionFromTemplate hands out an idle node of the matching pool and falls
+// back to regular provisioning when the pool is empty.
+func (s *PoolService) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (_ *domain.Operation, err error) {
+	ctx, span := util.StartSpan(ctx, "PoolService.ProvisionFromTemplate",
+		attribute.String("template_id", string(templateID)),
+		attribute.String("provider_id", string(providerID)),
+		attribute.String("tenant_id", string(tenantID)))
+	defer util.EndSpan(span, &err)
+
+	pool, err := s.findPool(templateID, providerID)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil {
+		return s.NodeProvisionService.ProvisionFromTemplate(ctx, templateID, providerID, tenantID)
+	}
+	span.SetAttributes(attribute.String("pool_id", string(pool.PoolID)))
+
+	event := domain.AuditEvent{
+		Action:     domain.AuditActionProvision,
+		TenantID:   tenantID,
+		TemplateID: templateID,
+		ProviderID: providerID,
+		Params:     map[string]any{"pool_id": pool.PoolID},
+	}
+	if err := authorize(ctx, domain.RoleUser, tenantID); err != nil {
+		s.auditService.Record(ctx, event, err)
+		return nil, err
+	}
+
+	defer s.refill()
+
+	// checking and resetting pooled nodes is done by nodemgr, not the caller
+	node := s.take(asSystem(ctx), pool.PoolID)
+	if node == nil {
+		s.mu.Lock()
+		s.misses[pool.PoolID]++
+		s.mu.Unlock()
+		return s.NodeProvisionService.ProvisionFromTemplate(ctx, templateID, providerID, tenantID)
+	}
+	event.NodeID = node.NodeID
+
+	// the node already counts against the provider capacity, only the tenant
+	// quota is left to check
+	err = s.quotaService.Admit(tenantID, "", node.Resources, func() error {
+		_, err := s.nodeRepository.UpdateFunc(node.NodeID, func(node *domain.Node) error {
+			// the lease starts when the node is handed out, not when it was pooled
+			node.TenantID = tenantID
+			node.Owner = util.Actor(ctx)
+			node.PoolID = ""
+			node.Lease = domain.NewNodeLease(node.Lease.LeasePolicy, time.Now())
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		s.putBack(pool.PoolID, node.NodeID)
+		s.auditService.Record(ctx, event, err)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.hits[pool.PoolID]++
+	s.mu.Unlock()
+
+	return s.operationService.StartOperation(ctx, domain.OperationKindProvision, node.NodeID, tenantID, nil, func(ctx context.Context) (err error) {
+		defer func() { s.auditService.Record(ctx, event, err) }()
+
+		// the node is destroyed even when the handout was cancelled
+		system := asSystem(context.WithoutCancel(ctx))
+		if len(pool.ResetCommand) > 0 {
+			if err := s.exec(asSystem(ctx), node.NodeID, pool.ResetCommand); err != nil {
+				s.retire(system, node.NodeID, "reset failed")
+				return fmt.Errorf("resetting pooled node: %w", err)
+			}
+		}
+
+		// like a cancelled provision, a cancelled handout leaves nothing behind
+		if err := ctx.Err(); err != nil {
+			s.retire(system, node.NodeID, "handout cancelled")
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *PoolService) Run(ctx context.Context) {
+	ctx = asSystem(ctx)
+	ticker := time.NewTicker(poolReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		s.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+	}
+}
+
+func (s *PoolService) reconcile(ctx context.Context) {
+	pools, err := s.poolRepository.List()
+	if err != nil {
+		slog.Error("listing pools", "err", err)
+		return
+	}
+
+	for _, pool := range pools {
+		var expired []pooledNode
+
+		s.mu.Lock()
+		if pool.MaxIdle > 0 {
+			kept := s.idle[pool.PoolID][:0]
+			for _, n := range s.idle[pool.PoolID] {
+				if time.Since(n.since) > pool.MaxIdle {
+					expired = append(expired, n)
+				} else {
+					kept = append(kept, n)
+				}
+			}
+			s.idle[pool.PoolID] = kept
+		}
+		// the pool shrank, the oldest idle nodes go first
+		if surplus := len(s.idle[pool.PoolID]) - pool.Size; surplus > 0 {
+			expired = append(expired, s.idle[pool.PoolID][:surplus]...)
+			s.idle[pool.PoolID] = s.idle[pool.PoolID][surplus:]
+		}
+		missing := pool.Size - len(s.idle[pool.PoolID]) - s.pending[pool.PoolID]
+		s.pending[pool.PoolID] += max(missing, 0)
+		s.mu.Unlock()
+
+		for _, n := range expired {
+			s.retire(ctx, n.nodeID, "max idle time exceeded or pool shrunk")
+		}
+
+		for range missing {
+			op, err := s.NodeProvisionService.ProvisionFromTemplate(ctx, pool.TemplateID, pool.ProviderID, "")
+			if err != nil {
+				slog.Error("refilling pool", "pool_id", pool.PoolID, "err", err)
+				s.donePending(pool.PoolID)
+				continue
+			}
+			go s.await(ctx, pool.PoolID, op.ID())
+		}
+	}
+}
+
+// await adds the node of a refill operation to the pool once it is ready.
+func (s *PoolService) await(ctx context.Context, poolID domain.PoolID, opID domain.OperationID) {
+	// the pool outlives the refill request
+	ctx = context.WithoutCancel(ctx)
+	op, err := s.operationService.WaitOperation(ctx, opID)
+	s.donePending(poolID)
+
+	if err != nil {
+		slog.Error("waiting for pool refill", "pool_id", poolID, "operation_id", opID, "err", err)
+		return
+	}
+	if op.State != domain.OperationStateSucceeded {
+		slog.Error("refilling pool", "pool_id", poolID, "operation_id", opID, "err", op.Error)
+		return
+	}
+
+	if _, err := s.poolRepository.Get(poolID); err != nil {
+		s.retire(ctx, op.NodeID, "pool deleted")
+		return
+	}
+
+	_, err = s.nodeRepository.UpdateFunc(op.NodeID, func(node *domain.Node) error {
+		node.PoolID = poolID
+		return nil
+	})
+	if err != nil {
+		slog.Error("storing pooled node", "pool_id", poolID, "node_id", op.NodeID, "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.idle[poolID] = append(s.idle[poolID], pooledNode{nodeID: op.NodeID, since: time.Now()})
+	s.mu.Unlock()
+}
+
+// donePending counts a refill of the pool as finished, the count is gone
+// already when the pool was deleted in the meantime.
+func (s *PoolService) donePending(poolID domain.PoolID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, ok := s.pending[poolID]; ok {
+		if n <= 1 {
+			delete(s.pending, poolID)
+		} else {
+			s.pending[poolID] = n - 1
+		}
+	}
+}
+
+// putBack returns a node taken from the pool, it keeps its place in line.
+func (s *PoolService) putBack(poolID domain.PoolID, nodeID domain.NodeID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idle[poolID] = append([]pooledNode{{nodeID: nodeID, since: time.Now()}}, s.idle[poolID]...)
+}
+
+// take pops idle nodes, oldest first, until one passes the health check.
+func (s *PoolService) take(ctx context.Context, poolID domain.PoolID) *domain.Node {
+	for {
+		s.mu.Lock()
+		idle := s.idle[poolID]
+		if len(idle) == 0 {
+			s.mu.Unlock()
+			return nil
+		}
+		n := idle[0]
+		s.idle[poolID] = idle[1:]
+		s.mu.Unlock()
+
+		node, err := s.healthy(ctx, n.nodeID)
+		if err == nil {
+			return node
+		}
+		slog.WarnContext(ctx, "discarding unhealthy pooled node", "pool_id", poolID, "node_id", n.nodeID, "err", err)
+		s.retire(ctx, n.nodeID, "health check failed")
+	}
+}
+
+func (s *PoolService) healthy(ctx context.Context, nodeID domain.NodeID) (*domain.Node, error) {
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("loading node: %w", err)
+	}
+	if node.State != domain.NodeStateRunning {
+		return nil, domain.Conflict("node is %s, not running", node.State)
+	}
+
+	// nodes without an executor can only be judged by their state
+	if hasExecCap(node) {
+		return node, s.exec(ctx, nodeID, []string{"true"})
+	}
+	return node, nil
+}
+
+func (s *PoolService) exec(ctx context.Context, nodeID domain.NodeID, command []string) error {
+	res, err := s.executeService.Exec(ctx, domain.ExecRequest{NodeID: nodeID, Command: command})
+	if err != nil {
+		return err
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("%v exited with %d: %s", command, res.ExitCode, res.Stderr)
+	}
+	return nil
+}
+
+func (s *PoolService) retire(ctx context.Context, nodeID domain.NodeID, reason string) {
+	if _, err := s.NodeProvisionService.DestroyNode(ctx, nodeID); err != nil {
+		slog.ErrorContext(ctx, "destroying pooled node", "node_id", nodeID, "reason", reason, "err", err)
+	}
+}
+
+func (s *PoolService) findPool(templateID domain.TemplateID, providerID domain.ProviderID) (*domain.NodePool, error) {
+	pools, err := s.poolRepository.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing pools: %w", err)
+	}
+
+	for _, pool := range pools {
+		if pool.TemplateID == templateID && pool.ProviderID == providerID {
+			return pool, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *PoolService) refill() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+var _ port.NodePoolService = (*PoolService)(nil)
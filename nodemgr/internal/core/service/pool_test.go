@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/port/mocks"
+	"nodemgr/internal/core/util"
+
+	"go.uber.org/mock/gomock"
+)
+
+// poolExecHandle fails health checks of unhealthy nodes and records the other
+// commands run on each node.
+type poolExecHandle struct {
+	streamExecHandle
+
+	mu        sync.Mutex
+	unhealthy map[domain.NodeID]bool
+	commands  map[domain.NodeID][][]string
+}
+
+func (h *poolExecHandle) Exec(req domain.ExecRequest) (*domain.ExecResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if slices.Equal(req.Command, []string{"true"}) {
+		if h.unhealthy[req.NodeID] {
+			return &domain.ExecResult{ExitCode: 1, Stderr: []byte("unhealthy")}, nil
+		}
+		return &domain.ExecResult{}, nil
+	}
+	h.commands[req.NodeID] = append(h.commands[req.NodeID], req.Command)
+	return &domain.ExecResult{}, nil
+}
+
+func (h *poolExecHandle) ran(nodeID domain.NodeID) [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.commands[nodeID]
+}
+
+type poolFixture struct {
+	nodes      port.NodeRepository
+	operations *OperationService
+	exec       *poolExecHandle
+	service    *PoolService
+}
+
+// newPoolFixture pools the shared template "small" with a one hour lease on a
+// fake "docker" provider whose nodes can run commands.
+func newPoolFixture(t *testing.T) *poolFixture {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	providers := util.NewRepository[domain.ProviderID, port.NodeProvider]()
+	providers.Create(&fakeNodeProvider{id: "docker", caps: map[domain.Cap]bool{"exec:fake": true}})
+	templates := util.NewRepository[domain.TemplateID, domain.NodeTemplate]()
+	templates.Create(domain.NodeTemplate{TemplateID: "small", Image: "alpine", Lease: domain.LeasePolicy{TTL: time.Hour}})
+	templateService := NewTemplateService(templates)
+
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	operations := NewOperationService(util.NewRepository[domain.OperationID, domain.Operation]())
+	quota := NewQuotaService(nodes, util.NewRepository[domain.TenantID, domain.Quota](), util.NewRepository[domain.ProviderID, domain.ProviderProfile]())
+
+	metrics := mocks.NewMockMetricsRecorder(ctrl)
+	metrics.EXPECT().ObserveProvision(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	metrics.EXPECT().ObserveDestroy(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	metrics.EXPECT().ObserveExec(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	audit := mocks.NewMockAuditService(ctrl)
+	audit.EXPECT().Record(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+
+	provision := NewProvisionService(
+		nodes,
+		providers,
+		templateService,
+		NewMappingService(util.NewRepository[domain.MappingID, domain.NodeSpecMapping]()),
+		operations,
+		quota,
+		util.NewRepository[domain.ProviderID, domain.RetryPolicies](),
+		metrics,
+		audit,
+	)
+
+	exec := &poolExecHandle{unhealthy: map[domain.NodeID]bool{}, commands: map[domain.NodeID][][]string{}}
+	execProviders := util.NewRepository[domain.ExecProviderID, port.NodeExecProvider]()
+	execProviders.Create(&fakeExecProvider{handle: exec})
+	execute := NewExecuteService(nodes, execProviders, util.NewRepository[domain.ProviderID, domain.RetryPolicies](), metrics, audit, t.TempDir())
+
+	return &poolFixture{
+		nodes:      nodes,
+		operations: operations,
+		exec:       exec,
+		service: NewPoolService(
+			provision,
+			templateService,
+			util.NewRepository[domain.PoolID, domain.NodePool](),
+			nodes,
+			operations,
+			execute,
+			quota,
+			audit,
+		),
+	}
+}
+
+// fill creates the pool and reconciles until all of its nodes are idle.
+func (f *poolFixture) fill(t *testing.T, pool domain.NodePool) domain.PoolID {
+	t.Helper()
+
+	id, err := f.service.CreatePool(globalAdmin, pool)
+	if err != nil {
+		t.Fatalf("creating pool: %v", err)
+	}
+	f.reconcile(t, id, pool.Size)
+	return id
+}
+
+// reconcile runs one reconcile and waits for its refills to finish with idle
+// nodes in the pool.
+func (f *poolFixture) reconcile(t *testing.T, id domain.PoolID, idle int) {
+	t.Helper()
+
+	f.service.reconcile(asSystem(context.Background()))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := f.service.GetPoolStatus(globalAdmin, id)
+		if err != nil {
+			t.Fatalf("pool status: %v", err)
+		}
+		if status.Idle == idle && status.Provisioning == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool status = %+v, want %d idle nodes", status, idle)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// idle lists the idle nodes of the pool, oldest first.
+func (f *poolFixture) idle(id domain.PoolID) []domain.NodeID {
+	f.service.mu.Lock()
+	defer f.service.mu.Unlock()
+
+	var ids []domain.NodeID
+	for _, n := range f.service.idle[id] {
+		ids = append(ids, n.nodeID)
+	}
+	return ids
+}
+
+// waitState waits until the node reaches state, retired nodes are destroyed
+// by an operation of their own.
+func (f *poolFixture) waitState(t *testing.T, nodeID domain.NodeID, state domain.NodeState) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		node, err := f.nodes.Get(nodeID)
+		if err != nil {
+			t.Fatalf("loading node: %v", err)
+		}
+		if node.State == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node %s is %s, want %s", nodeID, node.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (f *poolFixture) claim(t *testing.T) *domain.Node {
+	t.Helper()
+
+	op, err := f.service.ProvisionFromTemplate(userA, "small", "docker", "project-a")
+	if err != nil {
+		t.Fatalf("provisioning from the pool: %v", err)
+	}
+	done, err := f.operations.WaitOperation(userA, op.ID())
+	if err != nil {
+		t.Fatalf("waiting for the handout: %v", err)
+	}
+	if done.State != domain.OperationStateSucceeded {
+		t.Fatalf("handout %s: %s", done.State, done.Error)
+	}
+	node, err := f.nodes.Get(done.NodeID)
+	if err != nil {
+		t.Fatalf("loading node: %v", err)
+	}
+	return node
+}
+
+func TestPoolRefill(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 2})
+
+	idle := f.idle(id)
+	for _, nodeID := range idle {
+		node, err := f.nodes.Get(nodeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.PoolID != id || node.State != domain.NodeStateRunning || node.TenantID != "" {
+			t.Errorf("pooled node = %+v, want a running shared node of %s", node, id)
+		}
+	}
+
+	// a full pool is left alone
+	f.reconcile(t, id, 2)
+	if nodes, _ := f.nodes.List(); len(nodes) != 2 {
+		t.Errorf("nodes = %d, want the 2 pooled ones", len(nodes))
+	}
+	if got := f.idle(id); !slices.Equal(got, idle) {
+		t.Errorf("idle = %v, want %v", got, idle)
+	}
+}
+
+func TestPoolRefillReplacesExpired(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 1, MaxIdle: time.Millisecond})
+	expired := f.idle(id)[0]
+
+	time.Sleep(2 * time.Millisecond)
+	f.reconcile(t, id, 1)
+	f.waitState(t, expired, domain.NodeStateTerminated)
+	if got := f.idle(id); got[0] == expired {
+		t.Errorf("idle = %v, want the expired node replaced", got)
+	}
+}
+
+func TestPoolClaim(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 1, ResetCommand: []string{"rm", "-rf", "/work"}})
+	pooled := f.idle(id)[0]
+
+	before := time.Now()
+	node := f.claim(t)
+	if node.NodeID != pooled {
+		t.Fatalf("node = %s, want the pooled %s", node.NodeID, pooled)
+	}
+	if node.TenantID != "project-a" || node.Owner != "bob" || node.PoolID != "" {
+		t.Errorf("node = %+v, want it handed to bob of project-a", node)
+	}
+	if node.Lease.ExpiresAt.Before(before.Add(time.Hour)) {
+		t.Errorf("lease expires at %v, want it to start with the handout at %v", node.Lease.ExpiresAt, before)
+	}
+	if got := f.exec.ran(pooled); len(got) != 1 || !slices.Equal(got[0], []string{"rm", "-rf", "/work"}) {
+		t.Errorf("commands = %q, want the reset command", got)
+	}
+
+	// the empty pool falls back to regular provisioning
+	fallback := f.claim(t)
+	if fallback.NodeID == pooled || fallback.TenantID != "project-a" {
+		t.Errorf("fallback node = %+v, want a new node of project-a", fallback)
+	}
+
+	status, err := f.service.GetPoolStatus(globalAdmin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Hits != 1 || status.Misses != 1 || status.Idle != 0 {
+		t.Errorf("status = %+v, want one hit, one miss and no idle node", status)
+	}
+}
+
+func TestPoolClaimSkipsUnhealthy(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 2})
+	idle := f.idle(id)
+	f.exec.unhealthy[idle[0]] = true
+
+	if node := f.claim(t); node.NodeID != idle[1] {
+		t.Errorf("node = %s, want the healthy %s", node.NodeID, idle[1])
+	}
+	f.waitState(t, idle[0], domain.NodeStateTerminated)
+}
+
+func TestPoolClaimRejected(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 1})
+
+	if _, err := f.service.ProvisionFromTemplate(viewerA, "small", "docker", "project-a"); domain.Code(err) != domain.ErrorCodePermissionDenied {
+		t.Errorf("code = %q, want permission_denied", domain.Code(err))
+	}
+	if got := f.idle(id); len(got) != 1 {
+		t.Errorf("idle = %v, want the pooled node kept", got)
+	}
+}
+
+func TestPoolShrink(t *testing.T) {
+	f := newPoolFixture(t)
+	id := f.fill(t, domain.NodePool{TemplateID: "small", ProviderID: "docker", Size: 3})
+	idle := f.idle(id)
+
+	if err := f.service.UpdatePool(globalAdmin, domain.NodePool{PoolID: id, TemplateID: "small", ProviderID: "docker", Size: 1}); err != nil {
+		t.Fatalf("updating pool: %v", err)
+	}
+	f.reconcile(t, id, 1)
+
+	// the oldest idle nodes go first
+	if got := f.idle(id); !slices.Equal(got, idle[2:]) {
+		t.Errorf("idle = %v, want the newest %v", got, idle[2:])
+	}
+	for _, nodeID := range idle[:2] {
+		f.waitState(t, nodeID, domain.NodeStateTerminated)
+	}
+
+	err := f.service.UpdatePool(globalAdmin, domain.NodePool{PoolID: id, TemplateID: "small", ProviderID: "local", Size: 1})
+	if domain.Code(err) != domain.ErrorCodeInvalidSpec {
+		t.Errorf("code = %q, want invalid_spec when moving the pool", domain.Code(err))
+	}
+}
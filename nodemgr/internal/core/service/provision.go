@@ -0,0 +1,528 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type ProvisionService struct {
+	nodeRepository     port.NodeRepository
+	providerRepository port.NodeProviderRepository
+	templateService    port.TemplateService
+	mappingService     port.MappingService
+	operationService   port.OperationService
+	quotaService       port.QuotaService
+	retryRepository    port.RetryPolicyRepository
+	metrics            port.MetricsRecorder
+	auditService       port.AuditService
+}
+
+func NewProvisionService(
+	nodeRepository port.NodeRepository,
+	providerRepository port.NodeProviderRepository,
+	templateService port.TemplateService,
+	mappingService port.MappingService,
+	operationService port.OperationService,
+	quotaService port.QuotaService,
+	retryRepository port.RetryPolicyRepository,
+	metrics port.MetricsRecorder,
+	auditService port.AuditService,
+) *ProvisionService {
+	return &ProvisionService{
+		nodeRepository:     nodeRepository,
+		providerRepository: providerRepository,
+		templateService:    templateService,
+		mappingService:     mappingService,
+		operationService:   operationService,
+		quotaService:       quotaService,
+		retryRepository:    retryRepository,
+		metrics:            metrics,
+		auditService:       auditService,
+	}
+}
+
+func (s *ProvisionService) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (_ *domain.Operation, err error) {
+	ctx, span := util.StartSpan(ctx, "ProvisionService.ProvisionFromTemplate",
+		attribute.String("template_id", string(templateID)),
+		attribute.String("provider_id", string(providerID)),
+		attribute.String("tenant_id", string(tenantID)))
+	defer util.EndSpan(span, &err)
+
+	spec, err := s.renderTemplate(ctx, templateID, providerID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := s.provisionNodes(ctx, spec, 1, 0, domain.RoleUser)
+	if err != nil {
+		return nil, err
+	}
+	return ops[0], nil
+}
+
+func (s *ProvisionService) TemplateResources(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.Resources, error) {
+	if err := authorize(ctx, domain.RoleUser, tenantID); err != nil {
+		return domain.Resources{}, err
+	}
+
+	spec, err := s.renderTemplate(ctx, templateID, providerID, tenantID)
+	if err != nil {
+		return domain.Resources{}, err
+	}
+	spec, err = s.mappingService.ResolveSpecAliases(ctx, spec)
+	if err != nil {
+		return domain.Resources{}, fmt.Errorf("resolving spec aliases: %w", err)
+	}
+
+	provider, err := s.providerRepository.Get(spec.ProviderID)
+	if err != nil {
+		return domain.Resources{}, fmt.Errorf("loading provider %q: %w", spec.ProviderID, err)
+	}
+	if err := authorizeProvider(ctx, *provider); err != nil {
+		return domain.Resources{}, err
+	}
+	res, err := specResources(*provider, spec)
+	if err != nil {
+		return domain.Resources{}, fmt.Errorf("sizing node: %w", err)
+	}
+	return res, nil
+}
+
+// renderTemplate renders the spec of a template for tenantID, which must be
+// the tenant of the template unless it is shared.
+func (s *ProvisionService) renderTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.NodeSpec, error) {
+	spec, err := s.templateService.RenderTemplate(ctx, templateID, providerID)
+	if err != nil {
+		return domain.NodeSpec{}, fmt.Errorf("rendering template: %w", err)
+	}
+	if spec.TenantID != "" && spec.TenantID != tenantID {
+		return domain.NodeSpec{}, domain.InvalidSpec("template %q belongs to tenant %q", templateID, spec.TenantID)
+	}
+	spec.TenantID = tenantID
+	return spec, nil
+}
+
+func (s *ProvisionService) ProvisionNode(ctx context.Context, spec domain.NodeSpec) (*domain.Operation, error) {
+	ops, err := s.ProvisionNodes(ctx, spec, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ops[0], nil
+}
+
+func (s *ProvisionService) ProvisionNodes(ctx context.Context, spec domain.NodeSpec, count int, parallelism int) ([]*domain.Operation, error) {
+	return s.provisionNodes(ctx, spec, count, parallelism, domain.RoleAdmin)
+}
+
+// provisionNodes needs role in the tenant of spec. Raw specs can set any
+// provider option, like privileged containers or host mounts, and need an
+// admin, specs rendered from templates were written by one.
+func (s *ProvisionService) provisionNodes(ctx context.Context, spec domain.NodeSpec, count int, parallelism int, role domain.Role) (_ []*domain.Operation, err error) {
+	ctx, span := util.StartSpan(ctx, "ProvisionService.ProvisionNodes",
+		attribute.String("provider_id", string(spec.ProviderID)),
+		attribute.Int("count", count))
+	defer util.EndSpan(span, &err)
+
+	// started operations are audited once they finish, requests failing
+	// before that are audited here
+	defer func() {
+		if err != nil {
+			s.auditService.Record(ctx, provisionEvent(spec, ""), err)
+		}
+	}()
+
+	if err := authorize(ctx, role, spec.TenantID); err != nil {
+		return nil, err
+	}
+	if count < 1 {
+		return nil, domain.InvalidSpec("node count must be positive, got %d", count)
+	}
+
+	provider, err := s.providerRepository.Get(spec.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("loading provider %q: %w", spec.ProviderID, err)
+	}
+	if err := authorizeProvider(ctx, *provider); err != nil {
+		return nil, err
+	}
+
+	spec, err = s.mappingService.ResolveSpecAliases(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("resolving spec aliases: %w", err)
+	}
+
+	var slots chan struct{}
+	if parallelism > 0 {
+		slots = make(chan struct{}, parallelism)
+	}
+
+	res, err := specResources(*provider, spec)
+	if err != nil {
+		return nil, fmt.Errorf("sizing node: %w", err)
+	}
+	ops := make([]*domain.Operation, 0, count)
+	err = s.quotaService.Admit(spec.TenantID, spec.ProviderID, res.Scale(count), func() error {
+		for range count {
+			op, err := s.startProvision(ctx, *provider, spec, res, slots)
+			if err != nil {
+				return err
+			}
+			ops = append(ops, op)
+		}
+		return nil
+	})
+
+	return ops, err
+}
+
+func (s *ProvisionService) startProvision(ctx context.Context, provider port.NodeProvider, spec domain.NodeSpec, res domain.Resources, slots chan struct{}) (*domain.Operation, error) {
+	node := domain.Node{
+		NodeID:     domain.NodeID(uuid.New().String()),
+		ProviderID: provider.ID(),
+		TenantID:   spec.TenantID,
+		TemplateID: spec.TemplateID,
+		Owner:      util.Actor(ctx),
+		Resources:  res,
+		Lease:      domain.NewNodeLease(spec.Lease, time.Now()),
+		Meta:       map[string]any{},
+		Cap:        map[domain.Cap]bool{},
+	}
+	if err := node.Transition(domain.NodeStatePending, "provision requested"); err != nil {
+		return nil, err
+	}
+	if err := s.nodeRepository.Create(node); err != nil {
+		return nil, fmt.Errorf("storing node: %w", err)
+	}
+
+	return s.operationService.StartOperation(ctx, domain.OperationKindProvision, node.NodeID, node.TenantID, slots, func(ctx context.Context) (err error) {
+		defer func() { s.auditService.Record(ctx, provisionEvent(spec, node.NodeID), err) }()
+
+		ctx = util.WithLogAttrs(ctx, slog.String("provider_id", string(provider.ID())))
+		if err := ctx.Err(); err != nil {
+			// cancelled while waiting for a slot, nothing was created yet
+			_ = s.setState(node.NodeID, domain.NodeStatePending, domain.NodeStateTerminated, "provision cancelled")
+			return err
+		}
+
+		var provisioned *domain.Node
+		start := time.Now()
+		policy := retryPolicies(s.retryRepository, provider.ID()).Provision
+		err = retry(ctx, policy, func(ctx context.Context) (err error) {
+			ctx, span := util.StartSpan(ctx, "NodeProvider.Provision", attribute.String("provider_id", string(provider.ID())))
+			defer util.EndSpan(span, &err)
+
+			provisioned, err = provider.Provision(ctx, node.NodeID, spec)
+			return err
+		}, s.recordAttempt(ctx))
+		s.metrics.ObserveProvision(provider.ID(), time.Since(start), err)
+		if err != nil {
+			_ = s.setState(node.NodeID, domain.NodeStatePending, domain.NodeStateTerminated, "provision failed")
+			return fmt.Errorf("provisioning node: %w", err)
+		}
+
+		// a provision cancelled while the node came up must not leave it
+		// behind, nobody waits for it anymore
+		reason := "provision cancelled"
+		err = ctx.Err()
+		if err == nil {
+			reason = "storing provisioned node failed"
+			err = s.storeProvisioned(node.NodeID, provisioned)
+		}
+		if err != nil {
+			// nobody could destroy a node we lost track of
+			if derr := provider.Destroy(context.WithoutCancel(ctx), node.NodeID); derr != nil {
+				slog.ErrorContext(ctx, "destroying unstored node", "err", derr)
+			}
+			_ = s.setState(node.NodeID, domain.NodeStatePending, domain.NodeStateTerminated, reason)
+			return err
+		}
+		return nil
+	})
+}
+
+// storeProvisioned moves the pending node to the state the provider left it in.
+func (s *ProvisionService) storeProvisioned(nodeID domain.NodeID, provisioned *domain.Node) error {
+	_, err := s.nodeRepository.UpdateFunc(nodeID, func(stored *domain.Node) error {
+		if stored.State != domain.NodeStatePending {
+			return &domain.InvalidTransitionError{NodeID: nodeID, From: stored.State, To: provisioned.State}
+		}
+		stored.Meta = provisioned.Meta
+		stored.Cap = provisioned.Cap
+		return stored.Transition(provisioned.State, "provisioned")
+	})
+	if err != nil {
+		return fmt.Errorf("storing node: %w", err)
+	}
+	return nil
+}
+
+func (s *ProvisionService) DestroyNode(ctx context.Context, nodeID domain.NodeID) (_ *domain.Operation, err error) {
+	ctx, span := util.StartSpan(ctx, "ProvisionService.DestroyNode", attribute.String("node_id", string(nodeID)))
+	defer util.EndSpan(span, &err)
+
+	event := domain.AuditEvent{Action: domain.AuditActionDestroy, NodeID: nodeID}
+	defer func() {
+		if err != nil {
+			s.auditService.Record(ctx, event, err)
+		}
+	}()
+
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("loading node: %w", err)
+	}
+	event.TenantID, event.TemplateID, event.ProviderID = node.TenantID, node.TemplateID, node.ProviderID
+	if err := authorize(ctx, domain.RoleUser, node.TenantID); err != nil {
+		return nil, err
+	}
+
+	provider, err := s.providerRepository.Get(node.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("loading provider %q: %w", node.ProviderID, err)
+	}
+
+	prevState := node.State
+	if err := s.setState(nodeID, prevState, domain.NodeStateShuttingDown, "destroy requested"); err != nil {
+		return nil, err
+	}
+
+	return s.operationService.StartOperation(ctx, domain.OperationKindDestroy, nodeID, node.TenantID, nil, func(ctx context.Context) (err error) {
+		defer func() { s.auditService.Record(ctx, event, err) }()
+
+		ctx = util.WithLogAttrs(ctx, slog.String("provider_id", string(node.ProviderID)))
+		start := time.Now()
+		policy := retryPolicies(s.retryRepository, node.ProviderID).Destroy
+		err = retry(ctx, policy, func(ctx context.Context) (err error) {
+			ctx, span := util.StartSpan(ctx, "NodeProvider.Destroy", attribute.String("provider_id", string(node.ProviderID)))
+			defer util.EndSpan(span, &err)
+
+			return (*provider).Destroy(ctx, nodeID)
+		}, s.recordAttempt(ctx))
+		s.metrics.ObserveDestroy(node.ProviderID, time.Since(start), err)
+		if err != nil {
+			// the node is most likely still alive, do not pretend otherwise
+			_ = s.setState(nodeID, domain.NodeStateShuttingDown, destroyRollbackState(prevState), "destroy failed")
+			return fmt.Errorf("destroying node: %w", err)
+		}
+
+		return s.setState(nodeID, domain.NodeStateShuttingDown, domain.NodeStateTerminated, "destroyed")
+	})
+}
+
+// destroyRollbackState is the state a node returns to when destroying it from
+// prev fails. A stop the destroy interrupted can not complete anymore, so such
+// nodes are reported stopped instead of staying in stopping forever.
+func destroyRollbackState(prev domain.NodeState) domain.NodeState {
+	if prev == domain.NodeStateStopping {
+		return domain.NodeStateStopped
+	}
+	return prev
+}
+
+func (s *ProvisionService) recordAttempt(ctx context.Context) func(attempt domain.OperationAttempt) {
+	return func(attempt domain.OperationAttempt) {
+		if err := s.operationService.RecordAttempt(ctx, attempt); err != nil {
+			slog.ErrorContext(ctx, "recording attempt", "err", err)
+		}
+	}
+}
+
+// PrefetchTemplateImages renders every known template for every provider able
+// to prefetch images and fetches them in the background.
+func (s *ProvisionService) PrefetchTemplateImages(ctx context.Context) (err error) {
+	ctx, span := util.StartSpan(ctx, "ProvisionService.PrefetchTemplateImages")
+	defer util.EndSpan(span, &err)
+
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return err
+	}
+
+	templates, err := s.templateService.ListTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("listing templates: %w", err)
+	}
+
+	providers, err := s.providerRepository.List()
+	if err != nil {
+		return fmt.Errorf("listing providers: %w", err)
+	}
+
+	// one broken template must not keep the images of the others from
+	// being prefetched
+	var errs []error
+	for _, provider := range providers {
+		prefetcher, ok := (*provider).(port.NodeImagePrefetcher)
+		if !ok {
+			continue
+		}
+
+		for _, tmpl := range templates {
+			spec, err := s.templateService.RenderTemplate(ctx, tmpl.ID(), (*provider).ID())
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rendering template %q for provider %q: %w", tmpl.ID(), (*provider).ID(), err))
+				continue
+			}
+			spec, err = s.mappingService.ResolveSpecAliases(ctx, spec)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("resolving spec aliases of template %q: %w", tmpl.ID(), err))
+				continue
+			}
+
+			// prefetching outlives the call, only its trace is carried over
+			go func() {
+				ctx, span := util.StartSpan(context.WithoutCancel(ctx), "NodeImagePrefetcher.PrefetchImage",
+					attribute.String("template_id", string(tmpl.ID())),
+					attribute.String("provider_id", string(spec.ProviderID)))
+				var err error
+				defer util.EndSpan(span, &err)
+
+				if err = prefetcher.PrefetchImage(ctx, spec); err != nil {
+					slog.Warn("prefetching template image", "template_id", tmpl.ID(), "provider_id", spec.ProviderID, "err", err)
+				}
+			}()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *ProvisionService) GetNode(ctx context.Context, nodeID domain.NodeID) (*domain.Node, error) {
+	node, err := s.nodeRepository.Get(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, domain.RoleViewer, node.TenantID); err != nil {
+		return nil, err
+	}
+	return nodeView(ctx, node), nil
+}
+
+func (s *ProvisionService) ListNodes(ctx context.Context) ([]*domain.Node, error) {
+	nodes, err := s.nodeRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err = visible(ctx, nodes, false, func(n *domain.Node) domain.TenantID { return n.TenantID })
+	if err != nil {
+		return nil, err
+	}
+	for i, node := range nodes {
+		nodes[i] = nodeView(ctx, node)
+	}
+	return nodes, nil
+}
+
+// nodeView redacts secret looking meta of node for callers who can only
+// read the node, users can exec into it anyway.
+func nodeView(ctx context.Context, node *domain.Node) *domain.Node {
+	if identity, ok := util.IdentityFrom(ctx); ok && identity.Allows(domain.RoleUser, node.TenantID) {
+		return node
+	}
+
+	view := *node
+	view.Meta = make(map[string]any, len(node.Meta))
+	for k, v := range node.Meta {
+		if util.IsSecretKey(k) {
+			v = util.Redacted
+		}
+		view.Meta[k] = v
+	}
+	return &view
+}
+
+// setState moves the node from state from to state to, it fails when the node
+// was changed concurrently so only one of two racing callers gets through.
+func (s *ProvisionService) setState(nodeID domain.NodeID, from domain.NodeState, to domain.NodeState, reason string) error {
+	return setNodeState(s.nodeRepository, nodeID, from, to, reason, nil)
+}
+
+// setNodeState is the compare-and-set of node states shared by the services,
+// fn may change the node along with its state.
+func setNodeState(repository port.NodeRepository, nodeID domain.NodeID, from domain.NodeState, to domain.NodeState, reason string, fn func(node *domain.Node)) error {
+	_, err := repository.UpdateFunc(nodeID, func(node *domain.Node) error {
+		if node.State != from {
+			return &domain.InvalidTransitionError{NodeID: nodeID, From: node.State, To: to}
+		}
+		if err := node.Transition(to, reason); err != nil {
+			return err
+		}
+		if fn != nil {
+			fn(node)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("updating node state: %w", err)
+	}
+	return nil
+}
+
+// authorizeProvider fails unless the identity of ctx is a global admin when
+// provider hands out unisolated hosts.
+func authorizeProvider(ctx context.Context, provider port.NodeProvider) error {
+	if unisolated, ok := provider.(port.UnisolatedNodeProvider); ok && unisolated.Unisolated() {
+		return authorize(ctx, domain.RoleAdmin, "")
+	}
+	return nil
+}
+
+func provisionEvent(spec domain.NodeSpec, nodeID domain.NodeID) domain.AuditEvent {
+	return domain.AuditEvent{
+		Action:     domain.AuditActionProvision,
+		TenantID:   spec.TenantID,
+		NodeID:     nodeID,
+		TemplateID: spec.TemplateID,
+		ProviderID: spec.ProviderID,
+		Params: map[string]any{
+			"extra": spec.Extra,
+		},
+	}
+}
+
+// specResources asks the provider for the node size and falls back to the
+// common template fields for providers which can not tell.
+func specResources(provider port.NodeProvider, spec domain.NodeSpec) (domain.Resources, error) {
+	if estimator, ok := provider.(port.NodeResourceEstimator); ok {
+		res, err := estimator.SpecResources(spec)
+		if err != nil {
+			return domain.Resources{}, err
+		}
+		// every spec is exactly one node
+		res.Nodes = 1
+		return res, nil
+	}
+
+	return domain.Resources{
+		Nodes:    1,
+		CPUs:     int(math.Ceil(toFloat(spec.Extra["cpus"]))),
+		MemoryMB: int(toFloat(spec.Extra["memory_mb"])),
+	}, nil
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+var _ port.NodeProvisionService = (*ProvisionService)(nil)
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	queueAdmitInterval = 5 * time.Second
+	// queueHistory is how many recent admissions the wait estimate is based on
+	queueHistory = 20
+)
+
+// QueueService keeps requests in the repository so a persistent repository
+// carries them over restarts, only the admission history used for estimates
+// lives in memory.
+type QueueService struct {
+	queueRepository  port.QueueRepository
+	nodeRepository   port.NodeRepository
+	provisionService port.NodeProvisionService
+	quotaService     port.QuotaService
+
+	mu       sync.Mutex
+	admitted []time.Time
+	// admitting is the request being provisioned, it can not be cancelled
+	admitting domain.QueueRequestID
+	kick      chan struct{}
+}
+
+func NewQueueService(queueRepository port.QueueRepository, nodeRepository port.NodeRepository, provisionService port.NodeProvisionService, quotaService port.QuotaService) *QueueService {
+	return &QueueService{
+		queueRepository:  queueRepository,
+		nodeRepository:   nodeRepository,
+		provisionService: provisionService,
+		quotaService:     quotaService,
+		kick:             make(chan struct{}, 1),
+	}
+}
+
+func (s *QueueService) Enqueue(ctx context.Context, req domain.QueueRequest) (*domain.QueueRequest, error) {
+	if err := authorize(ctx, domain.RoleUser, req.TenantID); err != nil {
+		return nil, err
+	}
+	if req.TemplateID == "" || req.ProviderID == "" {
+		return nil, domain.InvalidSpec("template and provider are required")
+	}
+	switch req.Priority {
+	case "":
+		req.Priority = domain.QueuePriorityNormal
+	case domain.QueuePriorityInteractive, domain.QueuePriorityNormal, domain.QueuePriorityBatch:
+	default:
+		return nil, domain.InvalidSpec("unknown priority %q", req.Priority)
+	}
+
+	// requests larger than the limits would wait forever
+	res, err := s.provisionService.TemplateResources(ctx, req.TemplateID, req.ProviderID, req.TenantID)
+	if err != nil {
+		return nil, fmt.Errorf("sizing request: %w", err)
+	}
+	if err := s.quotaService.Fits(req.TenantID, req.ProviderID, res); err != nil {
+		return nil, err
+	}
+
+	req.RequestID = domain.QueueRequestID(uuid.New().String())
+	req.Requester, _ = util.IdentityFrom(ctx)
+	req.State = domain.QueueRequestStateQueued
+	req.OperationID = ""
+	req.Error = ""
+	req.EnqueuedAt = time.Now()
+	req.AdmittedAt = time.Time{}
+
+	if err := s.queueRepository.Create(req); err != nil {
+		return nil, fmt.Errorf("storing request: %w", err)
+	}
+
+	s.wake()
+	return &req, nil
+}
+
+func (s *QueueService) GetRequest(ctx context.Context, id domain.QueueRequestID) (*domain.QueueRequest, error) {
+	req, err := s.queueRepository.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, domain.RoleViewer, req.TenantID); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func (s *QueueService) ListRequests(ctx context.Context) ([]*domain.QueueRequest, error) {
+	reqs, err := s.queueRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, reqs, false, func(req *domain.QueueRequest) domain.TenantID { return req.TenantID })
+}
+
+func (s *QueueService) GetPosition(ctx context.Context, id domain.QueueRequestID) (*domain.QueuePosition, error) {
+	req, err := s.queueRepository.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("loading request: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleViewer, req.TenantID); err != nil {
+		return nil, err
+	}
+	if req.State != domain.QueueRequestStateQueued {
+		return nil, domain.Conflict("request is %s, not queued", req.State)
+	}
+
+	queued, err := s.ordered()
+	if err != nil {
+		return nil, err
+	}
+
+	pos := &domain.QueuePosition{RequestID: id}
+	for i, r := range queued {
+		if r.RequestID == id {
+			pos.Position = i + 1
+			break
+		}
+	}
+
+	s.mu.Lock()
+	admitted := slices.Clone(s.admitted)
+	s.mu.Unlock()
+
+	// spread the recent admissions evenly over time to get the admission rate
+	if n := len(admitted); n >= 2 {
+		interval := admitted[n-1].Sub(admitted[0]) / time.Duration(n-1)
+		pos.EstimatedWait = interval * time.Duration(pos.Position)
+	}
+	return pos, nil
+}
+
+func (s *QueueService) CancelRequest(ctx context.Context, id domain.QueueRequestID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, err := s.queueRepository.Get(id)
+	if err != nil {
+		return fmt.Errorf("loading request: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleUser, req.TenantID); err != nil {
+		return err
+	}
+
+	switch req.State {
+	case domain.QueueRequestStateQueued:
+		if s.admitting == id {
+			return domain.Conflict("request is being admitted")
+		}
+	case domain.QueueRequestStateAdmitted:
+		return domain.Conflict("request already admitted, cancel operation %s instead", req.OperationID)
+	default:
+		return domain.Conflict("request already %s", req.State)
+	}
+
+	req.State = domain.QueueRequestStateCancelled
+	if err := s.queueRepository.Update(*req); err != nil {
+		return fmt.Errorf("storing request: %w", err)
+	}
+
+	// a cancelled request may have been blocking others of its tenant
+	s.wake()
+	return nil
+}
+
+func (s *QueueService) Run(ctx context.Context) {
+	ticker := time.NewTicker(queueAdmitInterval)
+	defer ticker.Stop()
+
+	for {
+		s.admit(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+	}
+}
+
+// admit goes through the queue in order and provisions every request that
+// fits. Once a request is over the quota of its tenant or the capacity of its
+// provider, the requests after it for the same tenant or provider wait too, so
+// smaller requests can not starve it. The lock is only held while claiming and
+// storing requests so lookups and cancellations are not held up by
+// provisioning.
+func (s *QueueService) admit(ctx context.Context) {
+	queued, err := s.ordered()
+	if err != nil {
+		slog.Error("ordering queue", "err", err)
+		return
+	}
+
+	blockedTenants := make(map[domain.TenantID]bool)
+	blockedProviders := make(map[domain.ProviderID]bool)
+	for _, req := range queued {
+		if blockedTenants[req.TenantID] || blockedProviders[req.ProviderID] {
+			continue
+		}
+
+		req := s.claim(req.RequestID)
+		if req == nil {
+			continue
+		}
+
+		// provisioning is authorized and audited as the requester
+		ctx := util.WithIdentity(ctx, req.Requester)
+		op, err := s.provisionService.ProvisionFromTemplate(ctx, req.TemplateID, req.ProviderID, req.TenantID)
+
+		var exceeded *domain.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			switch exceeded.Scope {
+			case domain.QuotaScopeTenant:
+				blockedTenants[req.TenantID] = true
+			case domain.QuotaScopeProvider:
+				blockedProviders[req.ProviderID] = true
+			}
+			s.unclaim()
+			continue
+		}
+
+		s.finish(req, op, err)
+	}
+}
+
+// claim reloads a queued request and marks it as being admitted, it returns
+// nil when the request was cancelled in the meantime.
+func (s *QueueService) claim(id domain.QueueRequestID) *domain.QueueRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, err := s.queueRepository.Get(id)
+	if err != nil || req.State != domain.QueueRequestStateQueued {
+		return nil
+	}
+	s.admitting = id
+	return req
+}
+
+func (s *QueueService) unclaim() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.admitting = ""
+}
+
+// finish stores the outcome of provisioning a claimed request.
+func (s *QueueService) finish(req *domain.QueueRequest, op *domain.Operation, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.admitting = ""
+	if err != nil {
+		req.State = domain.QueueRequestStateFailed
+		req.Error = err.Error()
+	} else {
+		req.State = domain.QueueRequestStateAdmitted
+		req.OperationID = op.ID()
+		req.AdmittedAt = time.Now()
+
+		s.admitted = append(s.admitted, req.AdmittedAt)
+		if len(s.admitted) > queueHistory {
+			s.admitted = s.admitted[1:]
+		}
+	}
+
+	if err := s.queueRepository.Update(*req); err != nil {
+		slog.Error("storing queue request", "request_id", req.RequestID, "err", err)
+	}
+}
+
+// queueShare is how many nodes tenants and users hold, counting requests
+// ordered so far.
+type queueShare struct {
+	tenants map[domain.TenantID]int
+	users   map[string]int
+}
+
+func (sh queueShare) add(req *domain.QueueRequest) {
+	sh.tenants[req.TenantID]++
+	sh.users[req.Requester.Subject]++
+}
+
+// ordered returns queued requests in admission order. Within a priority the
+// next request goes to the tenant (project) holding the least nodes and,
+// within a tenant, to the user holding the least nodes, counting the requests
+// ordered before it, so neither a single project nor a single user can starve
+// the others.
+func (s *QueueService) ordered() ([]*domain.QueueRequest, error) {
+	reqs, err := s.queueRepository.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing requests: %w", err)
+	}
+	nodes, err := s.nodeRepository.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	share := queueShare{tenants: make(map[domain.TenantID]int), users: make(map[string]int)}
+	for _, node := range nodes {
+		// idle pooled nodes belong to nobody until they are handed out
+		if node.State != domain.NodeStateTerminated && node.PoolID == "" {
+			share.tenants[node.TenantID]++
+			share.users[node.Owner]++
+		}
+	}
+
+	var pending []*domain.QueueRequest
+	for _, req := range reqs {
+		if req.State == domain.QueueRequestStateQueued {
+			pending = append(pending, req)
+		}
+	}
+
+	slices.SortFunc(pending, func(a, b *domain.QueueRequest) int {
+		return a.EnqueuedAt.Compare(b.EnqueuedAt)
+	})
+
+	ordered := make([]*domain.QueueRequest, 0, len(pending))
+	for len(pending) > 0 {
+		next := 0
+		for i, req := range pending[1:] {
+			if queuedBefore(req, pending[next], share) {
+				next = i + 1
+			}
+		}
+
+		req := pending[next]
+		share.add(req)
+		ordered = append(ordered, req)
+		pending = slices.Delete(pending, next, next+1)
+	}
+	return ordered, nil
+}
+
+// queuedBefore reports whether a goes before b, pending requests are sorted by
+// enqueue time so ties keep the older request.
+func queuedBefore(a, b *domain.QueueRequest, share queueShare) bool {
+	if a.Priority.Rank() != b.Priority.Rank() {
+		return a.Priority.Rank() > b.Priority.Rank()
+	}
+	if share.tenants[a.TenantID] != share.tenants[b.TenantID] {
+		return share.tenants[a.TenantID] < share.tenants[b.TenantID]
+	}
+	return share.users[a.Requester.Subject] < share.users[b.Requester.Subject]
+}
+
+func (s *QueueService) wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+var _ port.NodeQueueService = (*QueueService)(nil)
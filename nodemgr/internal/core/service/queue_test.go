@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+
+	"github.com/google/uuid"
+)
+
+// fakeTemplateProvisioner provisions nodes of fixed size per template through
+// the real quota admission.
+type fakeTemplateProvisioner struct {
+	port.NodeProvisionService
+	quotas *quotaFixture
+	sizes  map[domain.TemplateID]domain.Resources
+
+	provisioned []domain.TemplateID
+	// during runs while a request is provisioned
+	during func()
+}
+
+func (p *fakeTemplateProvisioner) TemplateResources(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (domain.Resources, error) {
+	return p.sizes[templateID], nil
+}
+
+func (p *fakeTemplateProvisioner) ProvisionFromTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID, tenantID domain.TenantID) (*domain.Operation, error) {
+	if p.during != nil {
+		p.during()
+	}
+
+	res := p.sizes[templateID]
+	err := p.quotas.service.Admit(tenantID, providerID, res, func() error {
+		return p.quotas.nodes.Create(domain.Node{
+			NodeID:     domain.NodeID(uuid.New().String()),
+			ProviderID: providerID,
+			TenantID:   tenantID,
+			Owner:      util.Actor(ctx),
+			State:      domain.NodeStateRunning,
+			Resources:  res,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.provisioned = append(p.provisioned, templateID)
+	return &domain.Operation{OperationID: domain.OperationID(uuid.New().String())}, nil
+}
+
+func newTestQueue() (*QueueService, *fakeTemplateProvisioner) {
+	quotas := newQuotaFixture()
+	provisioner := &fakeTemplateProvisioner{
+		quotas: quotas,
+		sizes: map[domain.TemplateID]domain.Resources{
+			"small": {Nodes: 1, CPUs: 1},
+			"large": {Nodes: 1, CPUs: 4},
+		},
+	}
+	queue := NewQueueService(util.NewRepository[domain.QueueRequestID, domain.QueueRequest](), quotas.nodes, provisioner, quotas.service)
+	return queue, provisioner
+}
+
+func enqueue(t *testing.T, s *QueueService, subject string, req domain.QueueRequest) *domain.QueueRequest {
+	t.Helper()
+
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: subject, TenantID: req.TenantID, Role: domain.RoleUser})
+	if req.ProviderID == "" {
+		req.ProviderID = "docker"
+	}
+	queued, err := s.Enqueue(ctx, req)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	// enqueue times order requests of the same share
+	time.Sleep(time.Millisecond)
+	return queued
+}
+
+func queueOrder(t *testing.T, s *QueueService) []domain.QueueRequestID {
+	t.Helper()
+
+	ordered, err := s.ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]domain.QueueRequestID, 0, len(ordered))
+	for _, req := range ordered {
+		ids = append(ids, req.RequestID)
+	}
+	return ids
+}
+
+func TestQueueOrder(t *testing.T) {
+	s, _ := newTestQueue()
+
+	batch := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "small", TenantID: "project-a", Priority: domain.QueuePriorityBatch})
+	a1 := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+	a2 := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+	b1 := enqueue(t, s, "bob", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+	c1 := enqueue(t, s, "carol", domain.QueueRequest{TemplateID: "small", TenantID: "project-b"})
+	shell := enqueue(t, s, "dave", domain.QueueRequest{TemplateID: "small", TenantID: "project-a", Priority: domain.QueuePriorityInteractive})
+
+	// interactive first, then projects and users alternate, batch last
+	want := []domain.QueueRequestID{shell.RequestID, c1.RequestID, a1.RequestID, b1.RequestID, a2.RequestID, batch.RequestID}
+	if got := queueOrder(t, s); !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestQueueOrderCountsHeldNodes(t *testing.T) {
+	s, p := newTestQueue()
+
+	// alice already holds a node in the project
+	p.quotas.addNode(t, domain.Node{TenantID: "project-a", Owner: "alice", Resources: domain.Resources{Nodes: 1}})
+
+	a := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+	b := enqueue(t, s, "bob", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+
+	want := []domain.QueueRequestID{b.RequestID, a.RequestID}
+	if got := queueOrder(t, s); !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestQueueBlockedRequestHoldsBackLaterOnes(t *testing.T) {
+	s, p := newTestQueue()
+	p.quotas.profiles.Create(domain.ProviderProfile{ProviderID: "docker", Capacity: domain.Resources{CPUs: 4}})
+	p.quotas.addNode(t, domain.Node{ProviderID: "docker", TenantID: "project-b", Resources: domain.Resources{Nodes: 1, CPUs: 2}})
+
+	large := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "large", TenantID: "project-a", Priority: domain.QueuePriorityInteractive})
+	small := enqueue(t, s, "bob", domain.QueueRequest{TemplateID: "small", TenantID: "project-c"})
+	other := enqueue(t, s, "carol", domain.QueueRequest{TemplateID: "small", TenantID: "project-c", ProviderID: "libvirt"})
+
+	s.admit(context.Background())
+
+	if want := []domain.TemplateID{"small"}; !slices.Equal(p.provisioned, want) {
+		t.Errorf("provisioned = %v, want only the request of the other provider", p.provisioned)
+	}
+	for _, tt := range []struct {
+		req   *domain.QueueRequest
+		state domain.QueueRequestState
+	}{
+		{large, domain.QueueRequestStateQueued},
+		{small, domain.QueueRequestStateQueued},
+		{other, domain.QueueRequestStateAdmitted},
+	} {
+		req, _ := s.queueRepository.Get(tt.req.RequestID)
+		if req.State != tt.state {
+			t.Errorf("request %s is %s, want %s", req.TemplateID, req.State, tt.state)
+		}
+	}
+}
+
+func TestQueueRejectsRequestsOverTheLimits(t *testing.T) {
+	s, p := newTestQueue()
+	p.quotas.quotas.Create(domain.Quota{TenantID: "project-a", Limits: domain.Resources{CPUs: 2}})
+
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+	_, err := s.Enqueue(ctx, domain.QueueRequest{TemplateID: "large", ProviderID: "docker", TenantID: "project-a"})
+	if !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("Enqueue() = %v, want quota exceeded", err)
+	}
+
+	if _, err := s.Enqueue(ctx, domain.QueueRequest{TemplateID: "small", ProviderID: "docker", TenantID: "project-a"}); err != nil {
+		t.Errorf("Enqueue() within the quota = %v", err)
+	}
+}
+
+func TestQueueCancelWhileAdmitting(t *testing.T) {
+	s, p := newTestQueue()
+	req := enqueue(t, s, "alice", domain.QueueRequest{TemplateID: "small", TenantID: "project-a"})
+	ctx := util.WithIdentity(context.Background(), domain.Identity{Subject: "alice", TenantID: "project-a", Role: domain.RoleUser})
+
+	var cancelErr, positionErr error
+	p.during = func() {
+		// the queue must not be locked while provisioning
+		_, positionErr = s.GetPosition(ctx, req.RequestID)
+		cancelErr = s.CancelRequest(ctx, req.RequestID)
+	}
+	s.admit(context.Background())
+
+	if positionErr != nil {
+		t.Errorf("GetPosition() while admitting = %v", positionErr)
+	}
+	if cancelErr == nil {
+		t.Error("cancelling a request being admitted succeeded")
+	}
+	stored, _ := s.queueRepository.Get(req.RequestID)
+	if stored.State != domain.QueueRequestStateAdmitted {
+		t.Errorf("request is %s, want admitted", stored.State)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"sync"
+)
+
+type QuotaService struct {
+	nodeRepository    port.NodeRepository
+	quotaRepository   port.QuotaRepository
+	profileRepository port.ProviderProfileRepository
+
+	mu sync.Mutex
+}
+
+func NewQuotaService(nodeRepository port.NodeRepository, quotaRepository port.QuotaRepository, profileRepository port.ProviderProfileRepository) *QuotaService {
+	return &QuotaService{
+		nodeRepository:    nodeRepository,
+		quotaRepository:   quotaRepository,
+		profileRepository: profileRepository,
+	}
+}
+
+func (s *QuotaService) SetQuota(ctx context.Context, quota domain.Quota) error {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return err
+	}
+	if _, err := s.quotaRepository.Get(quota.TenantID); err == nil {
+		return s.quotaRepository.Update(quota)
+	}
+	return s.quotaRepository.Create(quota)
+}
+
+func (s *QuotaService) GetQuota(ctx context.Context, tenantID domain.TenantID) (*domain.Quota, error) {
+	if err := authorize(ctx, domain.RoleViewer, tenantID); err != nil {
+		return nil, err
+	}
+	return s.quotaRepository.Get(tenantID)
+}
+
+func (s *QuotaService) ListQuotas(ctx context.Context) ([]*domain.Quota, error) {
+	quotas, err := s.quotaRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, quotas, false, func(q *domain.Quota) domain.TenantID { return q.TenantID })
+}
+
+func (s *QuotaService) DeleteQuota(ctx context.Context, tenantID domain.TenantID) error {
+	if err := authorize(ctx, domain.RoleAdmin, ""); err != nil {
+		return err
+	}
+	return s.quotaRepository.Delete(tenantID)
+}
+
+func (s *QuotaService) TenantUsage(ctx context.Context, tenantID domain.TenantID) (*domain.Usage, error) {
+	if err := authorize(ctx, domain.RoleViewer, tenantID); err != nil {
+		return nil, err
+	}
+	return s.tenantUsage(tenantID)
+}
+
+func (s *QuotaService) tenantUsage(tenantID domain.TenantID) (*domain.Usage, error) {
+	used, err := s.usage(func(n *domain.Node) bool {
+		// idle pooled nodes belong to nobody until they are handed out
+		return n.TenantID == tenantID && n.PoolID == ""
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &domain.Usage{Used: used}
+	if quota, err := s.quotaRepository.Get(tenantID); err == nil {
+		usage.Limits = quota.Limits
+	}
+	return usage, nil
+}
+
+func (s *QuotaService) ProviderUsage(ctx context.Context, providerID domain.ProviderID) (*domain.Usage, error) {
+	if err := authorize(ctx, domain.RoleViewer, ""); err != nil {
+		return nil, err
+	}
+	return s.providerUsage(providerID)
+}
+
+func (s *QuotaService) providerUsage(providerID domain.ProviderID) (*domain.Usage, error) {
+	used, err := s.usage(func(n *domain.Node) bool { return n.ProviderID == providerID })
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &domain.Usage{Used: used}
+	if profile, err := s.profileRepository.Get(providerID); err == nil {
+		usage.Limits = profile.Capacity
+	}
+	return usage, nil
+}
+
+func (s *QuotaService) Admit(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tenantID != "" {
+		usage, err := s.tenantUsage(tenantID)
+		if err != nil {
+			return err
+		}
+		if err := req.Exceeds(usage.Used, usage.Limits, domain.QuotaScopeTenant, string(tenantID)); err != nil {
+			return err
+		}
+	}
+
+	if providerID != "" {
+		usage, err := s.providerUsage(providerID)
+		if err != nil {
+			return err
+		}
+		if err := req.Exceeds(usage.Used, usage.Limits, domain.QuotaScopeProvider, string(providerID)); err != nil {
+			return err
+		}
+	}
+
+	return fn()
+}
+
+func (s *QuotaService) Fits(tenantID domain.TenantID, providerID domain.ProviderID, req domain.Resources) error {
+	if quota, err := s.quotaRepository.Get(tenantID); tenantID != "" && err == nil {
+		if err := req.Exceeds(domain.Resources{}, quota.Limits, domain.QuotaScopeTenant, string(tenantID)); err != nil {
+			return err
+		}
+	}
+	if profile, err := s.profileRepository.Get(providerID); providerID != "" && err == nil {
+		if err := req.Exceeds(domain.Resources{}, profile.Capacity, domain.QuotaScopeProvider, string(providerID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usage sums resources of every node that still holds them.
+func (s *QuotaService) usage(match func(n *domain.Node) bool) (domain.Resources, error) {
+	nodes, err := s.nodeRepository.List()
+	if err != nil {
+		return domain.Resources{}, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	var used domain.Resources
+	for _, node := range nodes {
+		if node.State != domain.NodeStateTerminated && match(node) {
+			used = used.Add(node.Resources)
+		}
+	}
+	return used, nil
+}
+
+var _ port.QuotaService = (*QuotaService)(nil)
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+
+	"github.com/google/uuid"
+)
+
+type quotaFixture struct {
+	nodes    port.NodeRepository
+	quotas   port.QuotaRepository
+	profiles port.ProviderProfileRepository
+	service  *QuotaService
+}
+
+func newQuotaFixture() *quotaFixture {
+	f := &quotaFixture{
+		nodes:    util.NewRepository[domain.NodeID, domain.Node](),
+		quotas:   util.NewRepository[domain.TenantID, domain.Quota](),
+		profiles: util.NewRepository[domain.ProviderID, domain.ProviderProfile](),
+	}
+	f.service = NewQuotaService(f.nodes, f.quotas, f.profiles)
+	return f
+}
+
+func (f *quotaFixture) addNode(t *testing.T, node domain.Node) {
+	t.Helper()
+	if node.NodeID == "" {
+		nodes, _ := f.nodes.List()
+		node.NodeID = domain.NodeID(fmt.Sprintf("node-%d", len(nodes)))
+	}
+	if node.State == "" {
+		node.State = domain.NodeStateRunning
+	}
+	if err := f.nodes.Create(node); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdmitTenantQuota(t *testing.T) {
+	f := newQuotaFixture()
+	f.quotas.Create(domain.Quota{TenantID: "tenant-a", Limits: domain.Resources{Nodes: 2, CPUs: 4}})
+	f.addNode(t, domain.Node{TenantID: "tenant-a", Resources: domain.Resources{Nodes: 1, CPUs: 2}})
+
+	called := false
+	err := f.service.Admit("tenant-a", "", domain.Resources{Nodes: 1, CPUs: 2}, func() error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatalf("Admit() = %v, called = %v, want admitted", err, called)
+	}
+
+	f.addNode(t, domain.Node{TenantID: "tenant-a", Resources: domain.Resources{Nodes: 1, CPUs: 2}})
+	err = f.service.Admit("tenant-a", "", domain.Resources{Nodes: 1}, func() error {
+		t.Error("over quota request was admitted")
+		return nil
+	})
+	var exceeded *domain.QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Admit() = %v, want *QuotaExceededError", err)
+	}
+	if exceeded.Scope != domain.QuotaScopeTenant || exceeded.Resource != "nodes" || exceeded.Used != 2 {
+		t.Errorf("exceeded = %+v, want tenant nodes with 2 used", exceeded)
+	}
+
+	// other tenants are not affected
+	if err := f.service.Admit("tenant-b", "", domain.Resources{Nodes: 1}, func() error { return nil }); err != nil {
+		t.Errorf("Admit() for another tenant = %v", err)
+	}
+}
+
+func TestAdmitProviderCapacity(t *testing.T) {
+	f := newQuotaFixture()
+	f.profiles.Create(domain.ProviderProfile{ProviderID: "docker", Capacity: domain.Resources{MemoryMB: 1024}})
+	f.addNode(t, domain.Node{ProviderID: "docker", TenantID: "tenant-a", Resources: domain.Resources{Nodes: 1, MemoryMB: 768}})
+
+	err := f.service.Admit("tenant-b", "docker", domain.Resources{Nodes: 1, MemoryMB: 512}, func() error {
+		t.Error("request over capacity was admitted")
+		return nil
+	})
+	var exceeded *domain.QuotaExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Admit() = %v, want *QuotaExceededError", err)
+	}
+	if exceeded.Scope != domain.QuotaScopeProvider || exceeded.Name != "docker" || exceeded.Resource != "memory_mb" {
+		t.Errorf("exceeded = %+v, want provider docker memory_mb", exceeded)
+	}
+
+	if err := f.service.Admit("tenant-b", "docker", domain.Resources{Nodes: 1, MemoryMB: 256}, func() error { return nil }); err != nil {
+		t.Errorf("Admit() within capacity = %v", err)
+	}
+}
+
+func TestAdmitUsage(t *testing.T) {
+	f := newQuotaFixture()
+	f.quotas.Create(domain.Quota{TenantID: "tenant-a", Limits: domain.Resources{Nodes: 1}})
+	f.profiles.Create(domain.ProviderProfile{ProviderID: "docker", Capacity: domain.Resources{Nodes: 2}})
+
+	// terminated nodes hold nothing, idle pooled nodes only hold provider capacity
+	f.addNode(t, domain.Node{ProviderID: "docker", TenantID: "tenant-a", State: domain.NodeStateTerminated, Resources: domain.Resources{Nodes: 1}})
+	f.addNode(t, domain.Node{ProviderID: "docker", TenantID: "tenant-a", PoolID: "pool", Resources: domain.Resources{Nodes: 1}})
+
+	if err := f.service.Admit("tenant-a", "docker", domain.Resources{Nodes: 1}, func() error { return nil }); err != nil {
+		t.Fatalf("Admit() = %v, want admitted", err)
+	}
+
+	f.addNode(t, domain.Node{ProviderID: "docker", TenantID: "tenant-b", Resources: domain.Resources{Nodes: 1}})
+	err := f.service.Admit("tenant-c", "docker", domain.Resources{Nodes: 1}, func() error { return nil })
+	if !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Errorf("Admit() on a full provider = %v, want quota exceeded", err)
+	}
+}
+
+func TestAdmitSerialized(t *testing.T) {
+	f := newQuotaFixture()
+	f.quotas.Create(domain.Quota{TenantID: "tenant-a", Limits: domain.Resources{Nodes: 3}})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.service.Admit("tenant-a", "", domain.Resources{Nodes: 1}, func() error {
+				return f.nodes.Create(domain.Node{
+					NodeID:    domain.NodeID(uuid.New().String()),
+					TenantID:  "tenant-a",
+					State:     domain.NodeStateRunning,
+					Resources: domain.Resources{Nodes: 1},
+				})
+			})
+		}()
+	}
+	wg.Wait()
+
+	viewer := util.WithIdentity(context.Background(), domain.Identity{Subject: "bob", TenantID: "tenant-a", Role: domain.RoleViewer})
+	usage, err := f.service.TenantUsage(viewer, "tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Used.Nodes != 3 {
+		t.Errorf("used nodes = %d, want exactly the quota of 3", usage.Used.Nodes)
+	}
+}
+
+type sizedProvider struct {
+	port.NodeProvider
+	res domain.Resources
+}
+
+func (p sizedProvider) SpecResources(spec domain.NodeSpec) (domain.Resources, error) {
+	return p.res, nil
+}
+
+func TestSpecResources(t *testing.T) {
+	spec := domain.NodeSpec{Extra: map[string]any{"cpus": 1.5, "memory_mb": "512"}}
+
+	res, err := specResources(sizedProvider{}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.Resources{Nodes: 1}); res != want {
+		t.Errorf("estimated resources = %+v, want %+v", res, want)
+	}
+
+	res, err = specResources(sizedProvider{res: domain.Resources{CPUs: 2, MemoryMB: 2048}}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.Resources{Nodes: 1, CPUs: 2, MemoryMB: 2048}); res != want {
+		t.Errorf("estimated resources = %+v, want %+v", res, want)
+	}
+
+	// providers without an estimate are sized from the common fields
+	var provider struct{ port.NodeProvider }
+	res, err = specResources(provider, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (domain.Resources{Nodes: 1, CPUs: 2, MemoryMB: 512}); res != want {
+		t.Errorf("fallback resources = %+v, want %+v", res, want)
+	}
+}
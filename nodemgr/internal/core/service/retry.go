@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"syscall"
+	"time"
+)
+
+// retryPolicies returns the policies of a provider with defaults filled in.
+func retryPolicies(repository port.RetryPolicyRepository, providerID domain.ProviderID) domain.RetryPolicies {
+	policies := domain.RetryPolicies{ProviderID: providerID}
+	if stored, err := repository.Get(providerID); err == nil {
+		policies = *stored
+	}
+
+	policies.Provision = policies.Provision.OrDefault()
+	policies.Destroy = policies.Destroy.OrDefault()
+	policies.ExecOpen = policies.ExecOpen.OrDefault()
+	return policies
+}
+
+// retry calls fn until it succeeds, fails with a permanent error or runs out
+// of attempts. Every attempt is passed to record.
+func retry(ctx context.Context, policy domain.RetryPolicy, fn func(ctx context.Context) error, record func(attempt domain.OperationAttempt)) error {
+	for n := 1; ; n++ {
+		attempt := domain.OperationAttempt{Attempt: n, StartedAt: time.Now()}
+		err := fn(ctx)
+		attempt.FinishedAt = time.Now()
+		if err != nil {
+			attempt.Error = err.Error()
+			attempt.Retryable = retryable(err)
+		}
+		record(attempt)
+
+		if err == nil || !attempt.Retryable || n >= policy.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(policy.Backoff(n))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// retryable classifies errors, adapters mark errors they know to be transient
+// as caused by an unavailable provider and network errors are always worth
+// another try.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, domain.ErrInvalidSpec) {
+		return false
+	}
+
+	if errors.Is(err, domain.ErrProviderUnavailable) {
+		return true
+	}
+
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"provider unavailable", domain.ProviderUnavailable(errors.New("daemon down")), true},
+		{"wrapped provider unavailable", fmt.Errorf("provisioning: %w", domain.ProviderUnavailable(errors.New("stack is locked"))), true},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, true},
+		{"connection refused", fmt.Errorf("connecting: %w", syscall.ECONNREFUSED), true},
+		{"connection reset", syscall.ECONNRESET, true},
+		{"invalid spec", domain.InvalidSpec("image is required"), false},
+		{"invalid spec marked unavailable", domain.ProviderUnavailable(domain.InvalidSpec("image is required")), false},
+		{"conflict", domain.Conflict("request already admitted"), false},
+		{"cancelled", fmt.Errorf("waiting: %w", context.Canceled), false},
+		{"cancelled while unavailable", &domain.ProviderUnavailableError{Err: context.Canceled}, false},
+		{"quota exceeded", &domain.QuotaExceededError{Scope: domain.QuotaScopeTenant}, false},
+		{"unknown", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := domain.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	unavailable := &domain.ProviderUnavailableError{Err: errors.New("daemon down")}
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		wantErr  bool
+	}{
+		{"succeeds at once", []error{nil}, 1, false},
+		{"succeeds after retries", []error{unavailable, unavailable, nil}, 3, false},
+		{"runs out of attempts", []error{unavailable, unavailable, unavailable, nil}, 3, true},
+		{"permanent error", []error{domain.InvalidSpec("bad"), nil}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorded []domain.OperationAttempt
+			calls := 0
+			err := retry(context.Background(), policy, func(ctx context.Context) error {
+				calls++
+				return tt.errs[calls-1]
+			}, func(attempt domain.OperationAttempt) {
+				recorded = append(recorded, attempt)
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retry() = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.attempts || len(recorded) != tt.attempts {
+				t.Errorf("calls = %d, recorded = %d, want %d attempts", calls, len(recorded), tt.attempts)
+			}
+			for i, attempt := range recorded {
+				if attempt.Attempt != i+1 {
+					t.Errorf("attempt %d is numbered %d", i+1, attempt.Attempt)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := domain.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}
+
+	calls := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- retry(ctx, policy, func(ctx context.Context) error {
+			calls++
+			return &domain.ProviderUnavailableError{Err: errors.New("daemon down")}
+		}, func(domain.OperationAttempt) {})
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, domain.ErrProviderUnavailable) {
+			t.Errorf("retry() = %v, want the last attempt error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("retry kept waiting after cancellation")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
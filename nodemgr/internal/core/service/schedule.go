@@ -0,0 +1,263 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type SchedulerService struct {
+	templateService    port.TemplateService
+	mappingService     port.MappingService
+	providerRepository port.NodeProviderRepository
+	profileRepository  port.ProviderProfileRepository
+	nodeRepository     port.NodeRepository
+	provisionService   port.NodeProvisionService
+}
+
+func NewSchedulerService(
+	templateService port.TemplateService,
+	mappingService port.MappingService,
+	providerRepository port.NodeProviderRepository,
+	profileRepository port.ProviderProfileRepository,
+	nodeRepository port.NodeRepository,
+	provisionService port.NodeProvisionService,
+) *SchedulerService {
+	return &SchedulerService{
+		templateService:    templateService,
+		mappingService:     mappingService,
+		providerRepository: providerRepository,
+		profileRepository:  profileRepository,
+		nodeRepository:     nodeRepository,
+		provisionService:   provisionService,
+	}
+}
+
+func (s *SchedulerService) Schedule(ctx context.Context, req domain.Requirement) (_ *domain.SchedulingDecision, err error) {
+	_, span := util.StartSpan(ctx, "SchedulerService.Schedule",
+		attribute.String("tenant_id", string(req.TenantID)),
+		attribute.String("policy", string(req.Policy)))
+	defer util.EndSpan(span, &err)
+
+	if err := authorize(ctx, domain.RoleViewer, req.TenantID); err != nil {
+		return nil, err
+	}
+	if req.Policy == "" {
+		req.Policy = domain.SchedulingPolicyCheapest
+	}
+
+	score, err := s.policy(req.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := s.templateService.ListTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing templates: %w", err)
+	}
+	// global callers see the templates of every tenant
+	templates = slices.DeleteFunc(templates, func(t *domain.NodeTemplate) bool {
+		return t.TenantID != "" && t.TenantID != req.TenantID
+	})
+	providers, err := s.providerRepository.List()
+	if err != nil {
+		return nil, fmt.Errorf("listing providers: %w", err)
+	}
+	mappings, err := s.mappingService.ListMappings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	mappings = slices.DeleteFunc(mappings, func(m *domain.NodeSpecMapping) bool {
+		return m.TenantID != "" && m.TenantID != req.TenantID
+	})
+
+	type candidate struct {
+		domain.SchedulingCandidate
+		tmpl *domain.NodeTemplate
+	}
+
+	var viable, rejected []candidate
+	for _, provider := range providers {
+		// providers without a profile only fit requirements they cannot violate
+		profile := domain.ProviderProfile{ProviderID: (*provider).ID()}
+		if p, err := s.profileRepository.Get(profile.ProviderID); err == nil {
+			profile = *p
+		}
+		denied := authorizeProvider(ctx, *provider) != nil
+
+		for _, tmpl := range templates {
+			c := candidate{
+				SchedulingCandidate: domain.SchedulingCandidate{
+					TemplateID: tmpl.ID(),
+					ProviderID: profile.ProviderID,
+					Rejections: reject(req, tmpl, profile),
+				},
+				tmpl: tmpl,
+			}
+			if denied {
+				c.Rejections = append(c.Rejections, "provider is limited to global admins")
+			}
+			supported, err := s.supports(ctx, tmpl, profile.ProviderID, mappings)
+			if err != nil {
+				return nil, err
+			}
+			if !supported {
+				c.Rejections = append(c.Rejections, "template has no overrides or mapping for provider")
+			}
+			if len(c.Rejections) > 0 {
+				rejected = append(rejected, c)
+				continue
+			}
+
+			c.Score = score(profile)
+			viable = append(viable, c)
+		}
+	}
+
+	// the smallest template that fits wins between equally scored candidates
+	slices.SortFunc(viable, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(a.Score, b.Score),
+			cmp.Compare(a.tmpl.CPUs, b.tmpl.CPUs),
+			cmp.Compare(a.tmpl.MemoryMB, b.tmpl.MemoryMB),
+			cmp.Compare(a.ProviderID, b.ProviderID),
+			cmp.Compare(a.TemplateID, b.TemplateID),
+		)
+	})
+	slices.SortFunc(rejected, func(a, b candidate) int {
+		return cmp.Or(cmp.Compare(a.ProviderID, b.ProviderID), cmp.Compare(a.TemplateID, b.TemplateID))
+	})
+
+	decision := &domain.SchedulingDecision{Policy: req.Policy}
+	for _, c := range append(viable, rejected...) {
+		decision.Candidates = append(decision.Candidates, c.SchedulingCandidate)
+	}
+
+	if len(viable) == 0 {
+		return decision, domain.ErrUnschedulable
+	}
+	decision.TemplateID = viable[0].TemplateID
+	decision.ProviderID = viable[0].ProviderID
+	return decision, nil
+}
+
+func (s *SchedulerService) ProvisionFor(ctx context.Context, req domain.Requirement) (*domain.Operation, *domain.SchedulingDecision, error) {
+	decision, err := s.Schedule(ctx, req)
+	if err != nil {
+		return nil, decision, err
+	}
+
+	op, err := s.provisionService.ProvisionFromTemplate(ctx, decision.TemplateID, decision.ProviderID, req.TenantID)
+	if err != nil {
+		return nil, decision, err
+	}
+	return op, decision, nil
+}
+
+// supports reports whether the template was prepared for the provider, either
+// by its own overrides or by a mapping matching the rendered template. Values
+// under "all" apply to every provider and do not count.
+func (s *SchedulerService) supports(ctx context.Context, tmpl *domain.NodeTemplate, providerID domain.ProviderID, mappings []*domain.NodeSpecMapping) (bool, error) {
+	if _, ok := tmpl.ProviderOverrides[providerID]; ok {
+		return true, nil
+	}
+
+	spec, err := s.templateService.RenderTemplate(ctx, tmpl.ID(), providerID)
+	if err != nil {
+		return false, fmt.Errorf("rendering template %s: %w", tmpl.ID(), err)
+	}
+	for _, m := range mappings {
+		if _, ok := m.ProviderOverrides[providerID]; ok && matchMapping(spec, *m) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *SchedulerService) policy(policy domain.SchedulingPolicy) (func(domain.ProviderProfile) float64, error) {
+	switch policy {
+	case domain.SchedulingPolicyCheapest:
+		return func(p domain.ProviderProfile) float64 { return p.CostPerHour }, nil
+
+	case domain.SchedulingPolicyFastestStart:
+		return func(p domain.ProviderProfile) float64 { return p.StartupTime.Seconds() }, nil
+
+	case domain.SchedulingPolicyPreferLocal:
+		// local providers first, the cheapest one among each group
+		return func(p domain.ProviderProfile) float64 {
+			if p.Local {
+				return p.CostPerHour
+			}
+			return 1e9 + p.CostPerHour
+		}, nil
+
+	case domain.SchedulingPolicySpread:
+		nodes, err := s.nodeRepository.List()
+		if err != nil {
+			return nil, fmt.Errorf("listing nodes: %w", err)
+		}
+		active := make(map[domain.ProviderID]int)
+		for _, node := range nodes {
+			if node.State != domain.NodeStateTerminated {
+				active[node.ProviderID]++
+			}
+		}
+		return func(p domain.ProviderProfile) float64 { return float64(active[p.ProviderID]) }, nil
+
+	default:
+		return nil, domain.InvalidSpec("unknown scheduling policy %q", policy)
+	}
+}
+
+func reject(req domain.Requirement, tmpl *domain.NodeTemplate, profile domain.ProviderProfile) []string {
+	var reasons []string
+
+	if tmpl.CPUs < req.MinCPUs {
+		reasons = append(reasons, fmt.Sprintf("template has %d cpus, %d required", tmpl.CPUs, req.MinCPUs))
+	}
+	if tmpl.MemoryMB < req.MinMemoryMB {
+		reasons = append(reasons, fmt.Sprintf("template has %d MB memory, %d MB required", tmpl.MemoryMB, req.MinMemoryMB))
+	}
+	if profile.MaxCPUs > 0 && tmpl.CPUs > profile.MaxCPUs {
+		reasons = append(reasons, fmt.Sprintf("provider offers at most %d cpus, template needs %d", profile.MaxCPUs, tmpl.CPUs))
+	}
+	if profile.MaxMemoryMB > 0 && tmpl.MemoryMB > profile.MaxMemoryMB {
+		reasons = append(reasons, fmt.Sprintf("provider offers at most %d MB memory, template needs %d MB", profile.MaxMemoryMB, tmpl.MemoryMB))
+	}
+
+	for _, c := range req.Caps {
+		if !slices.Contains(profile.Caps, c) {
+			reasons = append(reasons, fmt.Sprintf("provider lacks capability %s", c))
+		}
+	}
+
+	if req.Arch != "" {
+		if tmpl.Arch != "" && tmpl.Arch != req.Arch {
+			reasons = append(reasons, fmt.Sprintf("template is %s, %s required", tmpl.Arch, req.Arch))
+		}
+		if !slices.Contains(profile.Arch, req.Arch) {
+			reasons = append(reasons, fmt.Sprintf("provider does not offer %s", req.Arch))
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(req.Labels)) {
+		v, ok := tmpl.Labels[k]
+		if !ok {
+			v, ok = profile.Labels[k]
+		}
+		if !ok || v != req.Labels[k] {
+			reasons = append(reasons, fmt.Sprintf("label %s=%s not matched", k, req.Labels[k]))
+		}
+	}
+
+	return reasons
+}
+
+var _ port.SchedulerService = (*SchedulerService)(nil)
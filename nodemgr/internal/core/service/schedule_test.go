@@ -0,0 +1,298 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"nodemgr/internal/core/domain"
+	"nodemgr/internal/core/port"
+	"nodemgr/internal/core/util"
+)
+
+// every template is prepared for every provider of the fixture unless a test
+// says otherwise
+var testSchedulerOverrides = map[domain.ProviderID]map[string]any{"docker": {}, "cloud": {}, "libvirt": {}}
+
+type schedulerFixture struct {
+	templates []domain.NodeTemplate
+	mappings  []domain.NodeSpecMapping
+	profiles  []domain.ProviderProfile
+	nodes     []domain.Node
+}
+
+func (f schedulerFixture) service(t *testing.T) *SchedulerService {
+	t.Helper()
+
+	templates := util.NewRepository[domain.TemplateID, domain.NodeTemplate]()
+	for _, tmpl := range f.templates {
+		templates.Create(tmpl)
+	}
+	mappings := util.NewRepository[domain.MappingID, domain.NodeSpecMapping]()
+	for _, m := range f.mappings {
+		mappings.Create(m)
+	}
+	providers := util.NewRepository[domain.ProviderID, port.NodeProvider]()
+	profiles := util.NewRepository[domain.ProviderID, domain.ProviderProfile]()
+	for _, profile := range f.profiles {
+		providers.Create(&fakeNodeProvider{id: profile.ProviderID, unisolated: profile.ProviderID == "local"})
+		profiles.Create(profile)
+	}
+	nodes := util.NewRepository[domain.NodeID, domain.Node]()
+	for _, node := range f.nodes {
+		nodes.Create(node)
+	}
+
+	return NewSchedulerService(NewTemplateService(templates), NewMappingService(mappings), providers, profiles, nodes, nil)
+}
+
+func TestSchedulePolicies(t *testing.T) {
+	f := schedulerFixture{
+		templates: []domain.NodeTemplate{
+			{TemplateID: "large", CPUs: 8, MemoryMB: 16384, ProviderOverrides: testSchedulerOverrides},
+			{TemplateID: "small", CPUs: 2, MemoryMB: 2048, ProviderOverrides: testSchedulerOverrides},
+		},
+		profiles: []domain.ProviderProfile{
+			{ProviderID: "cloud", CostPerHour: 0.1, StartupTime: 30 * time.Second},
+			{ProviderID: "docker", CostPerHour: 0.5, StartupTime: 5 * time.Second, Local: true},
+			{ProviderID: "libvirt", CostPerHour: 0.3, StartupTime: time.Minute, Local: true},
+		},
+		nodes: []domain.Node{
+			{NodeID: "n1", ProviderID: "libvirt", State: domain.NodeStateRunning},
+			{NodeID: "n2", ProviderID: "docker", State: domain.NodeStateRunning},
+			{NodeID: "n3", ProviderID: "docker", State: domain.NodeStateStopped},
+			{NodeID: "n4", ProviderID: "cloud", State: domain.NodeStateRunning},
+			{NodeID: "n5", ProviderID: "cloud", State: domain.NodeStateRunning},
+			{NodeID: "n6", ProviderID: "libvirt", State: domain.NodeStateTerminated},
+			{NodeID: "n7", ProviderID: "docker", State: domain.NodeStatePending},
+		},
+	}
+	s := f.service(t)
+
+	tests := []struct {
+		policy       domain.SchedulingPolicy
+		wantProvider domain.ProviderID
+		wantOrder    []domain.ProviderID
+	}{
+		{"", "cloud", []domain.ProviderID{"cloud", "cloud", "libvirt", "libvirt", "docker", "docker"}},
+		{domain.SchedulingPolicyCheapest, "cloud", []domain.ProviderID{"cloud", "cloud", "libvirt", "libvirt", "docker", "docker"}},
+		{domain.SchedulingPolicyFastestStart, "docker", []domain.ProviderID{"docker", "docker", "cloud", "cloud", "libvirt", "libvirt"}},
+		{domain.SchedulingPolicyPreferLocal, "libvirt", []domain.ProviderID{"libvirt", "libvirt", "docker", "docker", "cloud", "cloud"}},
+		// terminated nodes do not count, stopped ones still hold resources
+		{domain.SchedulingPolicySpread, "libvirt", []domain.ProviderID{"libvirt", "libvirt", "cloud", "cloud", "docker", "docker"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			decision, err := s.Schedule(userA, domain.Requirement{TenantID: "project-a", Policy: tt.policy})
+			if err != nil {
+				t.Fatalf("schedule: %v", err)
+			}
+
+			// the smaller template wins between equally scored candidates
+			if decision.ProviderID != tt.wantProvider || decision.TemplateID != "small" {
+				t.Errorf("picked %s on %s, want small on %s", decision.TemplateID, decision.ProviderID, tt.wantProvider)
+			}
+			if tt.policy == "" && decision.Policy != domain.SchedulingPolicyCheapest {
+				t.Errorf("policy = %q, want cheapest by default", decision.Policy)
+			}
+
+			var order []domain.ProviderID
+			for _, c := range decision.Candidates {
+				order = append(order, c.ProviderID)
+				if len(c.Rejections) > 0 {
+					t.Errorf("candidate %s on %s rejected: %v", c.TemplateID, c.ProviderID, c.Rejections)
+				}
+			}
+			if !slices.Equal(order, tt.wantOrder) {
+				t.Errorf("candidates = %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestScheduleUnknownPolicy(t *testing.T) {
+	s := schedulerFixture{}.service(t)
+
+	_, err := s.Schedule(userA, domain.Requirement{TenantID: "project-a", Policy: "random"})
+	if domain.Code(err) != domain.ErrorCodeInvalidSpec {
+		t.Errorf("code = %q, want invalid_spec", domain.Code(err))
+	}
+}
+
+func TestScheduleRejections(t *testing.T) {
+	base := domain.NodeTemplate{TemplateID: "tmpl", CPUs: 4, MemoryMB: 4096, Arch: "amd64", ProviderOverrides: testSchedulerOverrides}
+	profile := domain.ProviderProfile{ProviderID: "docker", Arch: []string{"amd64"}}
+
+	tests := []struct {
+		name    string
+		tmpl    func(tmpl *domain.NodeTemplate)
+		profile func(profile *domain.ProviderProfile)
+		req     domain.Requirement
+		want    []string
+	}{
+		{name: "fits"},
+		{
+			name: "too few cpus",
+			req:  domain.Requirement{MinCPUs: 8},
+			want: []string{"template has 4 cpus, 8 required"},
+		},
+		{
+			name: "too little memory",
+			req:  domain.Requirement{MinMemoryMB: 8192},
+			want: []string{"template has 4096 MB memory, 8192 MB required"},
+		},
+		{
+			name:    "provider too small",
+			profile: func(p *domain.ProviderProfile) { p.MaxCPUs, p.MaxMemoryMB = 2, 2048 },
+			want:    []string{"provider offers at most 2 cpus, template needs 4", "provider offers at most 2048 MB memory, template needs 4096 MB"},
+		},
+		{
+			name:    "missing capability",
+			profile: func(p *domain.ProviderProfile) { p.Caps = []domain.Cap{"exec:docker"} },
+			req:     domain.Requirement{Caps: []domain.Cap{"exec:docker", "lifecycle:docker"}},
+			want:    []string{"provider lacks capability lifecycle:docker"},
+		},
+		{
+			name: "architecture",
+			req:  domain.Requirement{Arch: "arm64"},
+			want: []string{"template is amd64, arm64 required", "provider does not offer arm64"},
+		},
+		{
+			name:    "labels of template and provider",
+			tmpl:    func(tmpl *domain.NodeTemplate) { tmpl.Labels = map[string]string{"gpu": "none"} },
+			profile: func(p *domain.ProviderProfile) { p.Labels = map[string]string{"region": "eu", "gpu": "a100"} },
+			req:     domain.Requirement{Labels: map[string]string{"gpu": "a100", "region": "eu", "zone": "1"}},
+			// labels of the template take precedence over those of the provider
+			want: []string{"label gpu=a100 not matched", "label zone=1 not matched"},
+		},
+		{
+			name: "unisolated provider",
+			tmpl: func(tmpl *domain.NodeTemplate) {
+				tmpl.ProviderOverrides = map[domain.ProviderID]map[string]any{"local": {}}
+			},
+			profile: func(p *domain.ProviderProfile) { p.ProviderID = "local" },
+			want:    []string{"provider is limited to global admins"},
+		},
+		{
+			name: "no overrides for the provider",
+			tmpl: func(tmpl *domain.NodeTemplate) {
+				tmpl.ProviderOverrides = map[domain.ProviderID]map[string]any{"all": {"user": "ubuntu"}, "libvirt": {}}
+			},
+			want: []string{"template has no overrides or mapping for provider"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, p := base, profile
+			if tt.tmpl != nil {
+				tt.tmpl(&tmpl)
+			}
+			if tt.profile != nil {
+				tt.profile(&p)
+			}
+			s := schedulerFixture{templates: []domain.NodeTemplate{tmpl}, profiles: []domain.ProviderProfile{p}}.service(t)
+
+			req := tt.req
+			req.TenantID = "project-a"
+			decision, err := s.Schedule(userA, req)
+			if len(decision.Candidates) != 1 {
+				t.Fatalf("candidates = %+v, want one", decision.Candidates)
+			}
+			if got := decision.Candidates[0].Rejections; !slices.Equal(got, tt.want) {
+				t.Errorf("rejections = %q, want %q", got, tt.want)
+			}
+
+			if tt.want == nil {
+				if err != nil || decision.TemplateID != "tmpl" || decision.ProviderID != p.ProviderID {
+					t.Errorf("decision = %s on %s, err = %v, want tmpl on %s", decision.TemplateID, decision.ProviderID, err, p.ProviderID)
+				}
+			} else if !errors.Is(err, domain.ErrUnschedulable) || decision.TemplateID != "" {
+				t.Errorf("decision = %s on %s, err = %v, want unschedulable", decision.TemplateID, decision.ProviderID, err)
+			}
+		})
+	}
+}
+
+func TestScheduleMappedTemplates(t *testing.T) {
+	f := schedulerFixture{
+		templates: []domain.NodeTemplate{
+			{TemplateID: "ubuntu", Image: "ubuntu"},
+			{TemplateID: "alpine", Image: "alpine"},
+		},
+		mappings: []domain.NodeSpecMapping{
+			{
+				MappingID:         "ubuntu",
+				Match:             map[string]string{"image": "ubuntu*"},
+				MatchType:         domain.MatchTypeGlob,
+				ProviderOverrides: map[domain.ProviderID]map[string]any{"docker": {"image": "ubuntu:24.04"}},
+			},
+			{
+				MappingID:         "alpine-of-b",
+				TenantID:          "project-b",
+				Match:             map[string]string{"image": "alpine"},
+				ProviderOverrides: map[domain.ProviderID]map[string]any{"docker": {"image": "alpine:3"}},
+			},
+		},
+		profiles: []domain.ProviderProfile{{ProviderID: "docker"}, {ProviderID: "libvirt", CostPerHour: 1}},
+	}
+	s := f.service(t)
+
+	decision, err := s.Schedule(userA, domain.Requirement{TenantID: "project-a"})
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if decision.TemplateID != "ubuntu" || decision.ProviderID != "docker" {
+		t.Errorf("picked %s on %s, want ubuntu on docker", decision.TemplateID, decision.ProviderID)
+	}
+
+	// mappings of other tenants do not prepare templates for project-a
+	rejected := map[domain.ProviderID][]domain.TemplateID{}
+	for _, c := range decision.Candidates[1:] {
+		if !slices.Equal(c.Rejections, []string{"template has no overrides or mapping for provider"}) {
+			t.Errorf("rejections of %s on %s = %q", c.TemplateID, c.ProviderID, c.Rejections)
+		}
+		rejected[c.ProviderID] = append(rejected[c.ProviderID], c.TemplateID)
+	}
+	if !slices.Equal(rejected["docker"], []domain.TemplateID{"alpine"}) || !slices.Equal(rejected["libvirt"], []domain.TemplateID{"alpine", "ubuntu"}) {
+		t.Errorf("rejected = %v, want alpine on docker and both on libvirt", rejected)
+	}
+}
+
+func TestScheduleUnschedulableListsRejections(t *testing.T) {
+	f := schedulerFixture{
+		templates: []domain.NodeTemplate{
+			{TemplateID: "b", CPUs: 2, ProviderOverrides: testSchedulerOverrides},
+			{TemplateID: "a", CPUs: 1, ProviderOverrides: testSchedulerOverrides},
+			{TemplateID: "other", TenantID: "project-b", CPUs: 16, ProviderOverrides: testSchedulerOverrides},
+		},
+		profiles: []domain.ProviderProfile{{ProviderID: "libvirt"}, {ProviderID: "docker"}},
+	}
+
+	decision, err := f.service(t).Schedule(userA, domain.Requirement{TenantID: "project-a", MinCPUs: 4})
+	if !errors.Is(err, domain.ErrUnschedulable) || domain.Code(err) != domain.ErrorCodeUnschedulable {
+		t.Fatalf("err = %v, want unschedulable", err)
+	}
+
+	// templates of other tenants are not considered at all
+	var got []string
+	for _, c := range decision.Candidates {
+		got = append(got, string(c.ProviderID)+"/"+string(c.TemplateID))
+		if len(c.Rejections) == 0 {
+			t.Errorf("candidate %s on %s has no rejection", c.TemplateID, c.ProviderID)
+		}
+	}
+	if want := []string{"docker/a", "docker/b", "libvirt/a", "libvirt/b"}; !slices.Equal(got, want) {
+		t.Errorf("candidates = %v, want %v", got, want)
+	}
+}
+
+func TestScheduleRequiresViewer(t *testing.T) {
+	s := schedulerFixture{}.service(t)
+
+	if _, err := s.Schedule(userA, domain.Requirement{TenantID: "project-b"}); domain.Code(err) != domain.ErrorCodePermissionDenied {
+		t.Errorf("code = %q, want permission_denied", domain.Code(err))
+	}
+}
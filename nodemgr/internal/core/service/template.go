@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"maps"
 	"nodemgr/internal/core/domain"
 	"nodemgr/internal/core/port"
 	"nodemgr/internal/core/util"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type TemplateService struct {
@@ -16,31 +19,67 @@ func NewTemplateService(templateRepository port.TemplateRepository) *TemplateSer
 	return &TemplateService{templateRepository: templateRepository}
 }
 
-func (s *TemplateService) CreateTemplate(tmpl domain.NodeTemplate) (domain.TemplateID, error) {
-	return "", fmt.Errorf("not implemented")
+func (s *TemplateService) CreateTemplate(ctx context.Context, tmpl domain.NodeTemplate) (domain.TemplateID, error) {
+	if err := authorize(ctx, domain.RoleAdmin, tmpl.TenantID); err != nil {
+		return "", err
+	}
+	if tmpl.TemplateID == "" {
+		return "", domain.InvalidSpec("template ID is required")
+	}
+
+	if err := s.templateRepository.Create(tmpl); err != nil {
+		return "", fmt.Errorf("storing template: %w", err)
+	}
+	return tmpl.TemplateID, nil
 }
 
-func (s *TemplateService) GetTemplate(tmplID domain.TemplateID) (*domain.NodeTemplate, error) {
-	return nil, fmt.Errorf("not implemented")
+func (s *TemplateService) GetTemplate(ctx context.Context, tmplID domain.TemplateID) (*domain.NodeTemplate, error) {
+	tmpl, err := s.templateRepository.Get(tmplID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeView(ctx, tmpl.TenantID); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
 }
 
-func (s *TemplateService) ListTemplates() ([]*domain.NodeTemplate, error) {
-	return nil, fmt.Errorf("not implemented")
+func (s *TemplateService) ListTemplates(ctx context.Context) ([]*domain.NodeTemplate, error) {
+	templates, err := s.templateRepository.List()
+	if err != nil {
+		return nil, err
+	}
+	return visible(ctx, templates, true, func(t *domain.NodeTemplate) domain.TenantID { return t.TenantID })
 }
 
-func (s *TemplateService) DeleteTemplate(tmplID domain.TemplateID) error {
-	return fmt.Errorf("not implemented")
+func (s *TemplateService) DeleteTemplate(ctx context.Context, tmplID domain.TemplateID) error {
+	tmpl, err := s.templateRepository.Get(tmplID)
+	if err != nil {
+		return fmt.Errorf("loading template: %w", err)
+	}
+	if err := authorize(ctx, domain.RoleAdmin, tmpl.TenantID); err != nil {
+		return err
+	}
+	return s.templateRepository.Delete(tmplID)
 }
 
-func (s *TemplateService) RenderTemplate(templateID domain.TemplateID, providerID domain.ProviderID) (domain.NodeSpec, error) {
-	tmpl, err := s.templateRepository.Get(templateID)
+func (s *TemplateService) RenderTemplate(ctx context.Context, templateID domain.TemplateID, providerID domain.ProviderID) (_ domain.NodeSpec, err error) {
+	_, span := util.StartSpan(ctx, "TemplateService.RenderTemplate",
+		attribute.String("template_id", string(templateID)),
+		attribute.String("provider_id", string(providerID)))
+	defer util.EndSpan(span, &err)
+
+	tmpl, err := s.GetTemplate(ctx, templateID)
 	if err != nil {
 		return domain.NodeSpec{}, fmt.Errorf("loading template: %w", err)
 	}
 
 	out := domain.NodeSpec{
+		TemplateID: templateID,
 		ProviderID: providerID,
+		TenantID:   tmpl.TenantID,
 		Extra:      map[string]any{},
+		Lease:      tmpl.Lease,
 	}
 	maps.Copy(out.Extra, tmpl.Extra)
 
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"nodemgr/internal/core/domain"
+	"strings"
+)
+
+// RenderBootstrapScript turns the bootstrap section into a POSIX shell script
+// meant to be run as root, for providers without cloud-init.
+func RenderBootstrapScript(b *domain.NodeBootstrap, user string) string {
+	var sb strings.Builder
+	sb.WriteString("set -e\n")
+
+	if len(b.Packages) > 0 {
+		pkgs := quoteAll(b.Packages)
+		fmt.Fprintf(&sb, `if command -v apt-get >/dev/null 2>&1; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update && apt-get install -y %[1]s
+elif command -v apk >/dev/null 2>&1; then
+  apk add --no-cache %[1]s
+elif command -v dnf >/dev/null 2>&1; then
+  dnf install -y %[1]s
+elif command -v yum >/dev/null 2>&1; then
+  yum install -y %[1]s
+else
+  echo "no supported package manager found" >&2
+  exit 1
+fi
+`, pkgs)
+	}
+
+	for _, f := range b.Files {
+		path := ShellQuote(f.Path)
+		fmt.Fprintf(&sb, "mkdir -p \"$(dirname %s)\"\n", path)
+		fmt.Fprintf(&sb, "echo %s | base64 -d > %s\n", base64.StdEncoding.EncodeToString([]byte(f.Content)), path)
+		if f.Permissions != "" {
+			fmt.Fprintf(&sb, "chmod %s %s\n", ShellQuote(f.Permissions), path)
+		}
+		if f.Owner != "" {
+			fmt.Fprintf(&sb, "chown %s %s\n", ShellQuote(f.Owner), path)
+		}
+	}
+
+	if len(b.AuthorizedKeys) > 0 && user != "" {
+		fmt.Fprintf(&sb, "home=$(awk -F: -v u=%s '$1 == u { print $6 }' /etc/passwd)\n", ShellQuote(user))
+		sb.WriteString("mkdir -p \"$home/.ssh\"\n")
+		for _, key := range b.AuthorizedKeys {
+			fmt.Fprintf(&sb, "printf '%%s\\n' %s >> \"$home/.ssh/authorized_keys\"\n", ShellQuote(key))
+		}
+		sb.WriteString("chmod 700 \"$home/.ssh\" && chmod 600 \"$home/.ssh/authorized_keys\"\n")
+		fmt.Fprintf(&sb, "chown -R %s \"$home/.ssh\"\n", ShellQuote(user))
+	}
+
+	for _, cmd := range b.RunCmd {
+		fmt.Fprintf(&sb, "sh -c %s\n", ShellQuote(cmd))
+	}
+
+	return sb.String()
+}
+
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+func quoteAll(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = ShellQuote(item)
+	}
+	return strings.Join(quoted, " ")
+}
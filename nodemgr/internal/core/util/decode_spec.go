@@ -1,7 +1,7 @@
 package util
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/go-viper/mapstructure/v2"
 )
@@ -19,6 +19,7 @@ func DecodeExtraTo[T any](m map[string]any) (T, error) {
 		ErrorUnset:       false,
 		WeaklyTypedInput: true,
 		ZeroFields:       true,
+		DecodeHook:       mapstructure.TextUnmarshallerHookFunc(),
 	})
 	if err != nil {
 		return out, err
@@ -28,7 +29,7 @@ func DecodeExtraTo[T any](m map[string]any) (T, error) {
 		return out, err
 	}
 	if len(meta.Unused) > 0 {
-		log.Printf("unused fields: %v", meta.Unused)
+		slog.Debug("ignoring unused spec fields", "fields", meta.Unused)
 	}
 
 	return out, nil
@@ -2,6 +2,9 @@ package util
 
 import (
 	"fmt"
+	"nodemgr/internal/core/domain"
+	"reflect"
+	"sync"
 )
 
 type WithID[K comparable] interface {
@@ -9,6 +12,7 @@ type WithID[K comparable] interface {
 }
 
 type Repository[K comparable, T WithID[K]] struct {
+	mu    sync.RWMutex
 	inmem map[K]T
 }
 
@@ -17,19 +21,60 @@ func NewRepository[K comparable, T WithID[K]]() *Repository[K, T] {
 }
 
 func (r *Repository[K, T]) Create(item T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.inmem[item.ID()]; exists {
+		return &domain.AlreadyExistsError{Kind: r.kind(), ID: fmt.Sprint(item.ID())}
+	}
 	r.inmem[item.ID()] = item
 	return nil
 }
 
+func (r *Repository[K, T]) Update(item T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.inmem[item.ID()]; !exists {
+		return &domain.NotFoundError{Kind: r.kind(), ID: fmt.Sprint(item.ID())}
+	}
+	r.inmem[item.ID()] = item
+	return nil
+}
+
+// UpdateFunc applies fn to the stored item and stores the result under the
+// repository lock, concurrent read-modify-write cycles can not overwrite each
+// other. Nothing is stored when fn fails.
+func (r *Repository[K, T]) UpdateFunc(id K, fn func(item *T) error) (*T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, exists := r.inmem[id]
+	if !exists {
+		return nil, &domain.NotFoundError{Kind: r.kind(), ID: fmt.Sprint(id)}
+	}
+	if err := fn(&item); err != nil {
+		return nil, err
+	}
+	r.inmem[id] = item
+	return &item, nil
+}
+
 func (r *Repository[K, T]) Get(id K) (*T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	item, exists := r.inmem[id]
 	if !exists {
-		return nil, fmt.Errorf("item not found")
+		return nil, &domain.NotFoundError{Kind: r.kind(), ID: fmt.Sprint(id)}
 	}
 	return &item, nil
 }
 
 func (r *Repository[K, T]) List() ([]*T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var items []*T
 	for _, item := range r.inmem {
 		items = append(items, &item)
@@ -38,6 +83,14 @@ func (r *Repository[K, T]) List() ([]*T, error) {
 }
 
 func (r *Repository[K, T]) Delete(id K) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	delete(r.inmem, id)
 	return nil
 }
+
+// kind names the stored type in errors, like "NodeTemplate".
+func (r *Repository[K, T]) kind() string {
+	return reflect.TypeFor[T]().Name()
+}
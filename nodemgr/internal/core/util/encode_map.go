@@ -2,7 +2,7 @@ package util
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 )
 
 func StructToMapJSON(v any) (map[string]any, error) {
@@ -15,6 +15,6 @@ func StructToMapJSON(v any) (map[string]any, error) {
 		return nil, err
 	}
 
-	log.Printf("StructToMapJSON: %+v", m)
+	slog.Debug("encoded struct to map", "map", m)
 	return m, nil
 }
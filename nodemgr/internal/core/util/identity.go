@@ -0,0 +1,34 @@
+package util
+
+import (
+	"context"
+	"nodemgr/internal/core/domain"
+)
+
+// AnonymousActor is recorded for calls without an identity, they are always
+// denied.
+const AnonymousActor = "anonymous"
+
+type identityKey struct{}
+
+// WithIdentity returns ctx acting on behalf of identity, transports set it
+// once the caller is authenticated.
+func WithIdentity(ctx context.Context, identity domain.Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the identity of ctx, contexts without one are not
+// authenticated.
+func IdentityFrom(ctx context.Context) (domain.Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(domain.Identity)
+	return identity, ok
+}
+
+// Actor returns the subject of the identity of ctx or AnonymousActor when
+// there is none.
+func Actor(ctx context.Context) string {
+	if identity, ok := IdentityFrom(ctx); ok && identity.Subject != "" {
+		return identity.Subject
+	}
+	return AnonymousActor
+}
@@ -0,0 +1,113 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// NewLogger creates a logger writing format, json or text, to w. Records
+// logged with a context also carry the attributes of WithLogAttrs.
+func NewLogger(w io.Writer, format string, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("parsing log level: %w", err)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected json or text", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns ctx carrying attrs, like node or operation ID, which
+// are added to every record logged with it.
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, logAttrsKey{}, append(slices.Clip(prev), attrs...))
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// LogWriter logs every line written to it, it is meant for output of tools
+// like the pulumi engine which would otherwise be lost.
+type LogWriter struct {
+	ctx    context.Context
+	logger *slog.Logger
+	level  slog.Level
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func NewLogWriter(ctx context.Context, logger *slog.Logger, level slog.Level) *LogWriter {
+	return &LogWriter{ctx: ctx, logger: logger, level: level}
+}
+
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Close logs what is left of an unterminated last line.
+func (w *LogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.log(w.buf)
+	w.buf = nil
+	return nil
+}
+
+func (w *LogWriter) log(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	w.logger.Log(w.ctx, w.level, string(line))
+}
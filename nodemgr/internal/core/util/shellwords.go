@@ -0,0 +1,66 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// ShellWords is an argv decoded either from a list or from a single string
+// split with POSIX shell-words rules, so `["echo", "a b"]` and `echo 'a b'`
+// are equivalent. Variables are not expanded and shell operators are rejected.
+type ShellWords []string
+
+func (w *ShellWords) UnmarshalText(text []byte) error {
+	args, err := splitShellWords(string(text))
+	if err != nil {
+		return err
+	}
+
+	*w = args
+	return w.Validate()
+}
+
+// Normalize returns the validated argv. A list with a single element holding
+// whitespace, like `["sleep 60"]`, is the form older versions split on spaces,
+// it is split like the string form so such specs keep working.
+func (w ShellWords) Normalize() (ShellWords, error) {
+	if len(w) == 1 && strings.ContainsAny(w[0], " \t\n") {
+		args, err := splitShellWords(w[0])
+		if err != nil {
+			return nil, err
+		}
+		w = args
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func splitShellWords(s string) (ShellWords, error) {
+	parser := shellwords.NewParser()
+	args, err := parser.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", s, err)
+	}
+	if parser.Position != -1 {
+		return nil, fmt.Errorf("parsing %q: shell operators are not supported", s)
+	}
+	return args, nil
+}
+
+// Validate applies the same rules to the list form that parsing applies to
+// the string form.
+func (w ShellWords) Validate() error {
+	if len(w) == 0 || strings.TrimSpace(w[0]) == "" {
+		return fmt.Errorf("empty command")
+	}
+	for _, arg := range w {
+		if strings.ContainsRune(arg, 0) {
+			return fmt.Errorf("argument %q contains NUL byte", arg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShellWordsUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want ShellWords
+	}{
+		{"plain", "sleep infinity", ShellWords{"sleep", "infinity"}},
+		{"extra whitespace", "  make\t-j8   test ", ShellWords{"make", "-j8", "test"}},
+		{"single quotes", `sh -c 'make -j8 test'`, ShellWords{"sh", "-c", "make -j8 test"}},
+		{"double quotes", `echo "a b" c`, ShellWords{"echo", "a b", "c"}},
+		{"quoted operators", `sh -c "make | tee log && echo done"`, ShellWords{"sh", "-c", "make | tee log && echo done"}},
+		{"escaped space", `ls my\ dir`, ShellWords{"ls", "my dir"}},
+		{"escaped quote", `echo it\'s`, ShellWords{"echo", "it's"}},
+		{"empty quoted argument", `printf '%s' ''`, ShellWords{"printf", "%s", ""}},
+		{"variables are not expanded", `echo $HOME ${USER}`, ShellWords{"echo", "$HOME", "${USER}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ShellWords
+			if err := got.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatalf("UnmarshalText(%q): %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellWordsRejected(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"   ",
+		"make | tee log",
+		"make && echo done",
+		"make; echo done",
+		"sleep 60 &",
+		"echo 'unterminated",
+	} {
+		var w ShellWords
+		if err := w.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) = %q, want an error", text, w)
+		}
+	}
+
+	for _, w := range []ShellWords{{}, {""}, {"echo", "a\x00b"}} {
+		if _, err := w.Normalize(); err == nil {
+			t.Errorf("Normalize(%q) succeeded, want an error", w)
+		}
+	}
+}
+
+func TestShellWordsListAndStringEquivalent(t *testing.T) {
+	type args struct {
+		Command ShellWords `mapstructure:"command"`
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"string", `sh -c 'make -j8 test'`},
+		{"list", []any{"sh", "-c", "make -j8 test"}},
+		{"single element list", []any{`sh -c 'make -j8 test'`}},
+	}
+
+	want := ShellWords{"sh", "-c", "make -j8 test"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeExtraTo[args](map[string]any{"command": tt.value})
+			if err != nil {
+				t.Fatalf("decoding: %v", err)
+			}
+			got, err := decoded.Command.Normalize()
+			if err != nil {
+				t.Fatalf("normalize: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("command = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestShellWordsNormalizeKeepsLists(t *testing.T) {
+	// only a lone element is split, arguments of longer lists are kept whole
+	w := ShellWords{"echo", "a b"}
+	got, err := w.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, w) {
+		t.Errorf("Normalize(%q) = %q", w, got)
+	}
+
+	if got, err := (ShellWords{"true"}).Normalize(); err != nil || !reflect.DeepEqual(got, ShellWords{"true"}) {
+		t.Errorf("Normalize([true]) = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"nodemgr/internal/core/domain"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "nodemgr"
+
+// SetupTracing installs the W3C trace context propagator and, when an OTLP
+// endpoint is configured through the standard OTEL_EXPORTER_OTLP_* variables,
+// a tracer provider exporting spans to it. Without an endpoint spans are not
+// recorded but trace context is still passed on.
+func SetupTracing(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("nodemgr")))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span as child of the one in ctx, the span must be ended
+// with EndSpan.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan marks span as failed when *err is set and ends it, it is meant to be
+// deferred with a pointer to the named error result.
+func EndSpan(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+		span.SetAttributes(attribute.String("error.code", string(domain.Code(*err))))
+	}
+	span.End()
+}
+
+// TraceEnv returns the trace context of ctx as environment variables, like
+// TRACEPARENT, so processes started on nodes can continue the trace.
+func TraceEnv(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	env := make(map[string]string, len(carrier))
+	for k, v := range carrier {
+		env[strings.ToUpper(k)] = v
+	}
+	return env
+}
+
+// InjectTraceHeaders adds the trace context of ctx to message headers, nats
+// headers can be passed as they are.
+func InjectTraceHeaders(ctx context.Context, headers map[string][]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(http.Header(headers)))
+}
+
+// ExtractTraceHeaders returns ctx continuing the trace carried in headers.
+func ExtractTraceHeaders(ctx context.Context, headers map[string][]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(http.Header(headers)))
+}